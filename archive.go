@@ -0,0 +1,147 @@
+package httpmock
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// archiveModTime is the modification time of every file in the archives built by
+// NewZipResponder and NewTarGzResponder, so that they are byte for byte the same from run to run.
+var archiveModTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ArchiveOption configures NewZipResponder and NewTarGzResponder.
+type ArchiveOption func(*archiveConfig)
+
+type archiveConfig struct {
+	filename  string
+	streaming bool
+}
+
+// ArchiveFilename sets the file name the Content-Disposition header suggests for the archive,
+// "archive.zip" or "archive.tar.gz" by default.
+func ArchiveFilename(name string) ArchiveOption {
+	return func(c *archiveConfig) {
+		c.filename = name
+	}
+}
+
+// ArchiveStreaming makes the archive be built anew for each call and streamed to the client as it
+// is written, through a pipe, rather than built once and kept in memory, for large files.  The
+// responses then have no Content-Length.
+func ArchiveStreaming() ArchiveOption {
+	return func(c *archiveConfig) {
+		c.streaming = true
+	}
+}
+
+// NewZipResponder creates a Responder serving files, keyed by path, as a zip archive, with a
+// Content-Type of application/zip, a Content-Disposition offering it as an attachment and a
+// Content-Length.  The files are stored in path order with a fixed modification time, so that the
+// archive is the same on every call and every run.
+func NewZipResponder(status int, files map[string][]byte, opts ...ArchiveOption) Responder {
+	return newArchiveResponder(status, files, "application/zip", "archive.zip", writeZip, opts)
+}
+
+// NewTarGzResponder is like NewZipResponder, but serves a gzip compressed tar archive with a
+// Content-Type of application/gzip.
+func NewTarGzResponder(status int, files map[string][]byte, opts ...ArchiveOption) Responder {
+	return newArchiveResponder(status, files, "application/gzip", "archive.tar.gz", writeTarGz, opts)
+}
+
+func newArchiveResponder(status int, files map[string][]byte, contentType, filename string,
+	write func(io.Writer, map[string][]byte) error, opts []ArchiveOption) Responder {
+	cfg := archiveConfig{filename: filename}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": cfg.filename}))
+
+	if cfg.streaming {
+		return func(req *http.Request) (*http.Response, error) {
+			pr, pw := io.Pipe()
+			go func() {
+				pw.CloseWithError(write(pw, files))
+			}()
+			return &http.Response{
+				Status:        statusLine(status),
+				StatusCode:    status,
+				Header:        header.Clone(),
+				Body:          pr,
+				ContentLength: -1,
+			}, nil
+		}
+	}
+
+	var buf bytes.Buffer
+	err := write(&buf, files)
+	data := buf.Bytes()
+	return func(req *http.Request) (*http.Response, error) {
+		if err != nil {
+			return nil, err
+		}
+		resp := NewBytesResponse(status, data)
+		resp.Header = header.Clone()
+		resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+		resp.ContentLength = int64(len(data))
+		return resp, nil
+	}
+}
+
+// sortedFileNames returns the paths of files in order.
+func sortedFileNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeZip(w io.Writer, files map[string][]byte) error {
+	zw := zip.NewWriter(w)
+	for _, name := range sortedFileNames(files) {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: archiveModTime})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeTarGz(w io.Writer, files map[string][]byte) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, name := range sortedFileNames(files) {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Size:     int64(len(files[name])),
+			Mode:     0644,
+			ModTime:  archiveModTime,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
@@ -0,0 +1,123 @@
+package httpmock
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+var archiveFiles = map[string][]byte{
+	"report.csv":       []byte("id,name\n1,gadget\n"),
+	"images/logo.png":  {0x89, 'P', 'N', 'G'},
+	"README.txt":       []byte("exported data"),
+	"empty/nothing.md": {},
+}
+
+// getArchive fetches an archive served by responder.
+func getArchive(t *testing.T, responder Responder) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := responder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, data
+}
+
+func TestZipResponder(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		var opts []ArchiveOption
+		if streaming {
+			opts = append(opts, ArchiveStreaming())
+		}
+		responder := NewZipResponder(200, archiveFiles, append(opts, ArchiveFilename("export.zip"))...)
+		resp, data := getArchive(t, responder)
+
+		if resp.Header.Get("Content-Type") != "application/zip" ||
+			resp.Header.Get("Content-Disposition") != "attachment; filename=export.zip" {
+			t.Errorf("streaming=%v: unexpected headers %v", streaming, resp.Header)
+		}
+		if expected := int64(len(data)); streaming && resp.ContentLength != -1 || !streaming && resp.ContentLength != expected {
+			t.Errorf("streaming=%v: unexpected ContentLength %d for %d bytes", streaming, resp.ContentLength, len(data))
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(content, archiveFiles[f.Name]) {
+				t.Errorf("streaming=%v: %s: expected %q, got %q", streaming, f.Name, archiveFiles[f.Name], content)
+			}
+		}
+		expected := []string{"README.txt", "empty/nothing.md", "images/logo.png", "report.csv"}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("streaming=%v: expected files %q, got %q", streaming, expected, names)
+		}
+
+		if _, again := getArchive(t, responder); !bytes.Equal(again, data) {
+			t.Errorf("streaming=%v: expected the archive to be byte stable", streaming)
+		}
+	}
+}
+
+func TestTarGzResponder(t *testing.T) {
+	resp, data := getArchive(t, NewTarGzResponder(200, archiveFiles))
+	if resp.Header.Get("Content-Type") != "application/gzip" ||
+		resp.Header.Get("Content-Disposition") != "attachment; filename=archive.tar.gz" {
+		t.Errorf("unexpected headers %v", resp.Header)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(zr)
+	found := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found[hdr.Name] = content
+	}
+	if !reflect.DeepEqual(found, archiveFiles) {
+		t.Errorf("expected the archive to hold %q, got %q", archiveFiles, found)
+	}
+
+	_, streamed := getArchive(t, NewTarGzResponder(200, archiveFiles, ArchiveStreaming()))
+	if !bytes.Equal(streamed, data) {
+		t.Error("expected the streamed archive to be the same as the buffered one")
+	}
+}
@@ -0,0 +1,64 @@
+package httpmock
+
+import (
+	"context"
+	"net/http"
+)
+
+type realTransportKey struct{}
+
+type forcedMockKey struct{}
+
+// WithRealTransport returns a copy of ctx making the requests using it bypass the mock: a
+// MockTransport forwards them to InitialTransport, whatever the registrations.  Bypassed requests
+// still show up in the call history, with Bypassed set.
+//
+//	req, _ := http.NewRequestWithContext(httpmock.WithRealTransport(ctx), "GET", healthURL, nil)
+func WithRealTransport(ctx context.Context) context.Context {
+	return context.WithValue(ctx, realTransportKey{}, true)
+}
+
+// WithForcedMock returns a copy of ctx making the requests using it go through the registrations
+// of a MockTransport even when their host is allowed through with AllowPassThrough.  It takes
+// precedence over WithRealTransport.
+func WithForcedMock(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcedMockKey{}, true)
+}
+
+// AllowPassThrough makes the MockTransport forward requests to the given hosts to
+// InitialTransport instead of the registrations, as WithRealTransport does for a single request.
+// hosts follow the same rules as the host of SimulateHostError.  Reset clears the list.
+func (m *MockTransport) AllowPassThrough(hosts ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.passThrough = append(m.passThrough, hosts...)
+}
+
+// bypasses reports whether req has to be forwarded to the real transport.
+func (m *MockTransport) bypasses(req *http.Request) bool {
+	ctx := req.Context()
+	if forced, _ := ctx.Value(forcedMockKey{}).(bool); forced {
+		return false
+	}
+	if bypassed, _ := ctx.Value(realTransportKey{}).(bool); bypassed {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, pattern := range m.passThrough {
+		if hostMatches(pattern, req.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// bypass forwards req to InitialTransport, marking its call in the history as bypassed.
+func (m *MockTransport) bypass(req *http.Request, call *Call) (*http.Response, error) {
+	m.mu.Lock()
+	call.Bypassed = true
+	m.mu.Unlock()
+
+	return InitialTransport.RoundTrip(req)
+}
@@ -0,0 +1,66 @@
+package httpmock
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithRealTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("real"))
+	}))
+	defer server.Close()
+
+	// other tests may leave a fake InitialTransport behind
+	saved := InitialTransport
+	InitialTransport = &http.Transport{}
+	defer func() { InitialTransport = saved }()
+
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", server.URL+"/health", NewStringResponder(200, "mocked"))
+	client := &http.Client{Transport: tr}
+
+	get := func(ctx context.Context) string {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/health", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(resp.Body)
+		return string(data)
+	}
+
+	if body := get(context.Background()); body != "mocked" {
+		t.Fatalf("expected the request to be mocked, got %q", body)
+	}
+	if body := get(WithRealTransport(context.Background())); body != "real" {
+		t.Fatalf("expected the request to reach the server, got %q", body)
+	}
+
+	tr.AllowPassThrough("127.0.0.1")
+	if body := get(context.Background()); body != "real" {
+		t.Fatalf("expected the allowed host to reach the server, got %q", body)
+	}
+	if body := get(WithForcedMock(context.Background())); body != "mocked" {
+		t.Fatalf("expected the forced request to be mocked, got %q", body)
+	}
+
+	history := tr.History()
+	bypassed := []bool{false, true, true, false}
+	for i, call := range history {
+		if call.Bypassed != bypassed[i] {
+			t.Errorf("call %d: expected Bypassed %v, got %v", i, bypassed[i], call.Bypassed)
+		}
+	}
+	if n := tr.CallCounts()["GET "+server.URL+"/health"]; n != 2 {
+		t.Fatalf("expected only the mocked calls to count against the registration, got %d", n)
+	}
+}
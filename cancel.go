@@ -0,0 +1,114 @@
+package httpmock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// errRequestCanceled is the error net/http gives for a request canceled through its deprecated
+// Cancel channel.
+var errRequestCanceled = errors.New("net/http: request canceled")
+
+// canceledError returns the error a real transport gives once req is canceled: the error of its
+// context, so that a deadline is reported as context.DeadlineExceeded, a net.Error whose Timeout
+// is true.  http.Client closes the Cancel channel of the requests to a custom RoundTripper when its
+// Timeout expires, at the deadline of their context, which is then reported too.
+func canceledError(req *http.Request) error {
+	ctx := req.Context()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return errRequestCanceled
+}
+
+// requestCanceled reports whether req has been canceled, through its context or its Cancel
+// channel.
+func requestCanceled(req *http.Request) bool {
+	if req.Context().Err() != nil {
+		return true
+	}
+	select {
+	case <-req.Cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// cancelOnDone makes the body of resp fail with the error of a real connection once req is
+// canceled, for requests that can be.  The body of a 101 is a connection of its own, and is left
+// alone.
+func cancelOnDone(req *http.Request, resp *http.Response) {
+	if (req.Context().Done() == nil && req.Cancel == nil) || resp.Body == nil || resp.Body == http.NoBody ||
+		resp.StatusCode == http.StatusSwitchingProtocols {
+		return
+	}
+	body := &cancelableBody{ReadCloser: resp.Body, req: req}
+	if _, ok := resp.Body.(io.WriterTo); ok {
+		resp.Body = cancelableWriterToBody{body}
+		return
+	}
+	resp.Body = body
+}
+
+// cancelableBody is a response body whose reads fail once its request is canceled, whether or not
+// the body has more to give.
+type cancelableBody struct {
+	io.ReadCloser
+	req *http.Request
+}
+
+func (b *cancelableBody) Read(p []byte) (int, error) {
+	if err := b.canceled(); err != nil {
+		return 0, err
+	}
+	return b.ReadCloser.Read(p)
+}
+
+// canceled returns the error reads fail with once the request is canceled, or nil.
+func (b *cancelableBody) canceled() error {
+	select {
+	case <-b.req.Context().Done():
+		return canceledError(b.req)
+	case <-b.req.Cancel:
+		return canceledError(b.req)
+	default:
+		return nil
+	}
+}
+
+func (b *cancelableBody) unwrapBody() io.ReadCloser {
+	return b.ReadCloser
+}
+
+// cancelableWriterToBody is a cancelableBody keeping the io.WriterTo of the body it wraps, for
+// io.Copy to still take the fast path.  The copy fails once the request is canceled, as reads do.
+type cancelableWriterToBody struct {
+	*cancelableBody
+}
+
+func (b cancelableWriterToBody) WriteTo(w io.Writer) (int64, error) {
+	if err := b.canceled(); err != nil {
+		return 0, err
+	}
+	return b.ReadCloser.(io.WriterTo).WriteTo(cancelableWriter{w: w, body: b.cancelableBody})
+}
+
+// cancelableWriter passes writes on to w until the request of body is canceled.
+type cancelableWriter struct {
+	w    io.Writer
+	body *cancelableBody
+}
+
+func (c cancelableWriter) Write(p []byte) (int, error) {
+	if err := c.body.canceled(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
@@ -0,0 +1,95 @@
+package httpmock
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// assertTimeout checks that err is reported as a timeout the way net/http reports it.
+func assertTimeout(t *testing.T, phase string, err error) {
+	t.Helper()
+	var netErr net.Error
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("%s: expected a timeout wrapping context.DeadlineExceeded, got %T %v", phase, err, err)
+	}
+	if strings.Contains(err.Error(), "request canceled") {
+		t.Errorf("%s: expected the deadline to be reported rather than the cancellation, got %v", phase, err)
+	}
+}
+
+func TestClientTimeoutErrors(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl+"headers", NewHangingResponder())
+	tr.RegisterResponder("GET", testUrl+"body", NewHangAfterHeadersResponder())
+	tr.RegisterResponder("GET", testUrl+"buffered", NewStringResponder(200, "hello"))
+	client := &http.Client{Transport: tr, Timeout: 50 * time.Millisecond}
+
+	_, err := client.Get(testUrl + "headers")
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		t.Errorf("expected a *url.Error, got %T %v", err, err)
+	}
+	assertTimeout(t, "headers", err)
+
+	for _, path := range []string{"body", "buffered"} {
+		resp, err := client.Get(testUrl + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assertTimeout(t, path, err)
+	}
+}
+
+func TestContextDeadlineErrors(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl+"headers", NewHangingResponder())
+	tr.RegisterResponder("GET", testUrl+"body", NewStringResponder(200, "hello"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl+"headers", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tr.RoundTrip(req)
+	assertTimeout(t, "headers", err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if req, err = http.NewRequestWithContext(ctx, "GET", testUrl+"body", nil); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2)
+	if _, err := resp.Body.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+	_, err = resp.Body.Read(buf)
+	assertTimeout(t, "body", err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	if req, err = http.NewRequestWithContext(ctx, "GET", testUrl+"body", nil); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err = tr.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err = resp.Body.Read(buf); err != context.Canceled {
+		t.Errorf("expected a canceled body to fail with context.Canceled, got %v", err)
+	}
+}
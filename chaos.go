@@ -0,0 +1,125 @@
+package httpmock
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// ChaosConfig describes the faults a MockTransport injects in chaos mode, see EnableChaos.
+type ChaosConfig struct {
+	// Seed seeds the source of randomness of the transport.  Zero picks a seed from the current
+	// time.
+	Seed int64
+
+	// FailureRate is the probability, from 0 to 1, that a request fails instead of reaching its
+	// responder.  Failure answers the failing requests, and defaults to NewConnRefusedResponder().
+	FailureRate float64
+	Failure     Responder
+
+	// Latency is the average delay added before every response, and Jitter how far it may stray
+	// from it: each delay is drawn from [Latency-Jitter, Latency+Jitter], and never less than zero.
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// Reorder is how far the completion of a request may be pushed back, after its responder
+	// returned: each is delayed by a duration drawn from [0, Reorder], so that concurrent calls
+	// complete out of order.
+	Reorder time.Duration
+}
+
+// EnableChaos turns chaos mode on: every request that reaches a registration, or the no responder,
+// may be delayed, fail or complete out of order, as described by cfg, on top of any host profile
+// (see SetHostProfile).  Every random draw made for the requests of the transport, including the
+// ones of WithJitter, of host profiles, and of NewJitteredSlowResponder and NewWeightedResponder
+// without a seed of their own, comes from a source of the transport seeded with cfg.Seed, which is
+// logged to l and returned, so that a failing test can be rerun with the same seed to get the same
+// outcomes for the same sequence of requests.  Other transports, and the package source of
+// randomness (see SetRandSource), are left alone.  Calls record the chaos they went through in
+// Call.ChaosLatency and Call.ChaosFailed.  Chaos mode lasts until DisableChaos, Reset leaving it in
+// place.
+func (m *MockTransport) EnableChaos(l Logger, cfg ChaosConfig) int64 {
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	if l != nil {
+		l.Logf("httpmock: chaos mode on, seed %d", cfg.Seed)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chaos = &cfg
+	m.chaosRand = newLockedRand(rand.NewSource(cfg.Seed))
+	return cfg.Seed
+}
+
+// DisableChaos turns chaos mode off, the requests of the transport drawing from the package source
+// of randomness again.
+func (m *MockTransport) DisableChaos() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chaos = nil
+	m.chaosRand = nil
+}
+
+// chaosRequest returns req drawing from the source of randomness of the transport when chaos mode
+// is on, and req itself otherwise.
+func (m *MockTransport) chaosRequest(req *http.Request) *http.Request {
+	m.mu.Lock()
+	r := m.chaosRand
+	m.mu.Unlock()
+	if r == nil {
+		return req
+	}
+	return withRand(req, r)
+}
+
+// applyChaos draws what chaos mode, if on, does to req and returns the responder to run in place
+// of responder, recording the outcome in call.
+func (m *MockTransport) applyChaos(responder Responder, req *http.Request, call *Call) Responder {
+	m.mu.Lock()
+	cfg := m.chaos
+	m.mu.Unlock()
+	if cfg == nil {
+		return responder
+	}
+
+	latency := cfg.Latency
+	if cfg.Jitter > 0 {
+		latency += time.Duration(randInt63n(req, int64(2*cfg.Jitter+1))) - cfg.Jitter
+	}
+	if latency < 0 {
+		latency = 0
+	}
+	var delay time.Duration
+	if cfg.Reorder > 0 {
+		delay = time.Duration(randInt63n(req, int64(cfg.Reorder+1)))
+	}
+	failed := cfg.FailureRate > 0 && randFloat64(req) < cfg.FailureRate
+	if failed {
+		responder = cfg.Failure
+		if responder == nil {
+			responder = NewConnRefusedResponder()
+		}
+	}
+
+	m.mu.Lock()
+	call.ChaosLatency = latency + delay
+	call.ChaosFailed = failed
+	m.mu.Unlock()
+
+	inner := responder
+	return func(req *http.Request) (*http.Response, error) {
+		if err := sleepContext(req, latency); err != nil {
+			return nil, err
+		}
+		resp, err := inner(req)
+		if err := sleepContext(req, delay); err != nil {
+			if resp != nil && resp.Body != nil {
+				resp.Body.Close()
+			}
+			return nil, err
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,206 @@
+package httpmock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChaosReproducible(t *testing.T) {
+	// outcomes runs a scenario under chaos mode, and returns the outcome of every call
+	outcomes := func(seed int64) []string {
+		var logged []string
+		tr := NewMockTransport()
+		got := tr.EnableChaos(LoggerFunc(func(format string, args ...interface{}) {
+			logged = append(logged, fmt.Sprintf(format, args...))
+		}), ChaosConfig{
+			Seed:        seed,
+			FailureRate: 0.2,
+			Jitter:      time.Millisecond,
+			Reorder:     time.Millisecond,
+		})
+		defer tr.DisableChaos()
+		if got != seed || len(logged) != 1 || logged[0] != fmt.Sprintf("httpmock: chaos mode on, seed %d", seed) {
+			t.Fatalf("expected the seed %d returned and logged, got %d and %q", seed, got, logged)
+		}
+
+		flaky, err := NewErrorRateResponder(0.3, NewStringResponder(200, "ok"), 503)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.RegisterResponder("GET", testUrl, flaky)
+		client := &http.Client{Transport: tr}
+
+		var outcomes []string
+		for i := 0; i < 40; i++ {
+			resp, err := client.Get(testUrl)
+			if err != nil {
+				outcomes = append(outcomes, "error")
+				continue
+			}
+			resp.Body.Close()
+			outcomes = append(outcomes, resp.Status)
+		}
+		for _, call := range tr.History() {
+			outcomes = append(outcomes, fmt.Sprintf("%s %v", call.ChaosLatency, call.ChaosFailed))
+		}
+		return outcomes
+	}
+
+	first := outcomes(42)
+	if second := outcomes(42); !reflect.DeepEqual(first, second) {
+		t.Fatalf("expected the same outcomes with the same seed:\n%v\n%v", first, second)
+	}
+	if other := outcomes(7); reflect.DeepEqual(first, other) {
+		t.Fatal("expected other outcomes with another seed")
+	}
+
+	joined := strings.Join(first, " ")
+	for _, expected := range []string{"error", "200 OK", "503 Service Unavailable"} {
+		if !strings.Contains(joined, expected) {
+			t.Errorf("expected some %q outcomes, got %v", expected, first)
+		}
+	}
+}
+
+func TestChaosReorder(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	tr := NewMockTransport()
+	tr.EnableChaos(nil, ChaosConfig{Seed: 1, Reorder: time.Second})
+	defer tr.DisableChaos()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""))
+
+	// concurrent calls complete in the order of their delays, not the one they were made in
+	done := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		i := i
+		req, _ := http.NewRequest("GET", fmt.Sprintf("%s?i=%d", testUrl, i), nil)
+		go func() {
+			tr.RoundTrip(req)
+			done <- i
+		}()
+		waitForClockWaiters(t, clock, i+1)
+	}
+	delays := make(map[string]time.Duration)
+	for _, call := range tr.History() {
+		delays[call.Request.URL.Query().Get("i")] = call.ChaosLatency
+	}
+
+	sorted := make([]time.Duration, 0, len(delays))
+	for _, d := range delays {
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var elapsed time.Duration
+	for _, d := range sorted {
+		clock.Advance(d - elapsed)
+		elapsed = d
+		if i := <-done; delays[fmt.Sprint(i)] != d {
+			t.Errorf("expected the call delayed by %s to complete, got call %d delayed by %s", d, i, delays[fmt.Sprint(i)])
+		}
+	}
+}
+
+func TestChaosOwnRandSource(t *testing.T) {
+	defer SetRandSource(nil)
+	req, _ := http.NewRequest("GET", testUrl, nil)
+	newCoin := func() *WeightedResponder {
+		coin, err := NewWeightedResponder([]WeightedChoice{
+			{Responder: NewStringResponder(200, ""), Weight: 1},
+			{Responder: NewStringResponder(500, ""), Weight: 1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return coin
+	}
+
+	// flips flips a coin on the package source 100 times, with a call to tr, if any, before each
+	flips := func(tr *MockTransport) []int {
+		SetRandSource(rand.NewSource(7))
+		coin := newCoin()
+		for i := 0; i < 100; i++ {
+			if tr != nil {
+				tr.RoundTrip(req)
+			}
+			coin.Respond(req)
+		}
+		return coin.Counts()
+	}
+	// chaotic returns a transport in chaos mode flipping a coin of its own
+	chaotic := func() (*MockTransport, *WeightedResponder) {
+		tr, coin := NewMockTransport(), newCoin()
+		tr.EnableChaos(nil, ChaosConfig{Seed: 42})
+		tr.RegisterResponder("GET", testUrl, coin.Respond)
+		return tr, coin
+	}
+
+	// the draws of a transport in chaos mode come from its own source, and leave the package one
+	// alone
+	first, firstCoin := chaotic()
+	if alone, beside := flips(nil), flips(first); !reflect.DeepEqual(alone, beside) {
+		t.Errorf("expected chaos mode to leave the package source alone, got %v and %v", alone, beside)
+	}
+	SetRandSource(rand.NewSource(1))
+	second, secondCoin := chaotic()
+	for i := 0; i < 100; i++ {
+		second.RoundTrip(req)
+	}
+	if !reflect.DeepEqual(firstCoin.Counts(), secondCoin.Counts()) {
+		t.Errorf("expected the same flips from the same chaos seed, got %v and %v", firstCoin.Counts(), secondCoin.Counts())
+	}
+}
+
+// closeSignalingBody closes closed when it is closed.
+type closeSignalingBody struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (b *closeSignalingBody) Close() error {
+	close(b.closed)
+	return nil
+}
+
+func TestChaosReorderCanceled(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	body := &closeSignalingBody{Reader: strings.NewReader("ok"), closed: make(chan struct{})}
+	tr := NewMockTransport()
+	tr.EnableChaos(nil, ChaosConfig{Seed: 1, Reorder: time.Hour})
+	tr.RegisterResponder("GET", testUrl, func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Header: http.Header{}, Body: body}, nil
+	})
+
+	// the request is canceled while its completion is pushed back
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	errs := make(chan error)
+	go func() {
+		_, err := tr.RoundTrip(req)
+		errs <- err
+	}()
+	waitForClockWaiters(t, clock, 1)
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("expected the request canceled, got %v", err)
+	}
+	select {
+	case <-body.closed:
+	case <-time.After(5 * time.Second):
+		t.Error("expected the body the client never got closed")
+	}
+}
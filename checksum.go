@@ -0,0 +1,160 @@
+package httpmock
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ChecksumOption configures AutoChecksum and MatchBodyChecksumHeader.
+type ChecksumOption func(*checksumConfig)
+
+type checksumConfig struct {
+	hex     bool
+	decoded bool
+}
+
+// ChecksumHex encodes checksums in lowercase hexadecimal rather than base64.
+func ChecksumHex() ChecksumOption {
+	return func(c *checksumConfig) {
+		c.hex = true
+	}
+}
+
+// ChecksumDecoded computes checksums over the body once decoded, when its Content-Encoding is gzip,
+// rather than over the bytes on the wire.
+func ChecksumDecoded() ChecksumOption {
+	return func(c *checksumConfig) {
+		c.decoded = true
+	}
+}
+
+func newChecksumConfig(opts []ChecksumOption) checksumConfig {
+	var cfg checksumConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// newChecksumHash returns the hash computing the checksum alg, one of md5, sha1, sha256, sha512,
+// crc32 and crc32c, case insensitively.
+func newChecksumHash(alg string) (hash.Hash, error) {
+	switch strings.ToLower(alg) {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "crc32":
+		return crc32.NewIEEE(), nil
+	case "crc32c":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	}
+	return nil, fmt.Errorf("httpmock: unknown checksum algorithm %q", alg)
+}
+
+// checksum computes the checksum alg of body, sent with the given Content-Encoding.
+func (c checksumConfig) checksum(alg string, body []byte, encoding string) (string, error) {
+	h, err := newChecksumHash(alg)
+	if err != nil {
+		return "", err
+	}
+	if c.decoded && strings.EqualFold(encoding, "gzip") {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return "", fmt.Errorf("httpmock: decoding gzip body for its checksum: %s", err)
+		}
+		if body, err = ioutil.ReadAll(zr); err != nil {
+			return "", fmt.Errorf("httpmock: decoding gzip body for its checksum: %s", err)
+		}
+	}
+	h.Write(body)
+	if c.hex {
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// AutoChecksum sets the header headerName of the responses to the checksum alg of their body, as
+// storage APIs do with Content-MD5 or x-amz-checksum-sha256:
+//
+//	httpmock.NewResponder(200, content, httpmock.AutoChecksum("md5", "Content-MD5"))
+//
+// alg is one of md5, sha1, sha256, sha512, crc32 and crc32c, the checksum being base64 encoded
+// unless ChecksumHex is given.  The checksum covers the body as sent, that is compressed when the
+// inner responder, such as NewConditionalGzipResponder, gzip encodes it, unless ChecksumDecoded is
+// given.  Responses fail with an error if alg is unknown.
+func AutoChecksum(alg string, headerName string, opts ...ChecksumOption) ResponderOption {
+	cfg := newChecksumConfig(opts)
+	return func(inner Responder) Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := inner(req)
+			if err != nil || resp == nil {
+				return resp, err
+			}
+
+			var data []byte
+			if resp.Body != nil {
+				data, err = ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+			}
+			sum, err := cfg.checksum(alg, data, resp.Header.Get("Content-Encoding"))
+			if err != nil {
+				return nil, err
+			}
+
+			summed := *resp
+			summed.Header = resp.Header.Clone()
+			if summed.Header == nil {
+				summed.Header = http.Header{}
+			}
+			summed.Header.Set(headerName, sum)
+			summed.Body = NewRespBodyFromBytes(data)
+			return &summed, nil
+		}
+	}
+}
+
+// MatchBodyChecksumHeader matches requests whose header headerName holds the checksum alg of their
+// body, to verify that a client computes the checksum of its uploads right.  alg and opts work as
+// for AutoChecksum, ChecksumDecoded applying to gzip encoded uploads.
+func MatchBodyChecksumHeader(alg, headerName string, opts ...ChecksumOption) Matcher {
+	cfg := newChecksumConfig(opts)
+	return func(req *http.Request) error {
+		got := req.Header.Get(headerName)
+		if got == "" {
+			return fmt.Errorf("header %s missing", headerName)
+		}
+
+		body, err := ReplayableRequestBody(req)
+		if err != nil {
+			return err
+		}
+		expected, err := cfg.checksum(alg, body, req.Header.Get("Content-Encoding"))
+		if err != nil {
+			return err
+		}
+		if got != expected {
+			return fmt.Errorf("header %s is %q, the %s of the body is %q", headerName, got, alg, expected)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package httpmock
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAutoChecksum(t *testing.T) {
+	tests := []struct {
+		alg, header string
+		opts        []ChecksumOption
+		expected    string
+	}{
+		{"md5", "Content-MD5", nil, "XrY7u+Ae7tCTyyK7j1rNww=="},
+		{"SHA256", "ETag", []ChecksumOption{ChecksumHex()}, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"},
+		{"sha1", "X-Amz-Checksum-Sha1", nil, "Kq5sNclPz7QV2+lfQIuc6R7oRu0="},
+		{"crc32", "X-Amz-Checksum-Crc32", nil, "DUoRhQ=="},
+		{"crc32c", "X-Amz-Checksum-Crc32c", nil, "yZRlqg=="},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := NewResponder(200, "hello world", AutoChecksum(test.alg, test.header, test.opts...))(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get(test.header); got != test.expected {
+			t.Errorf("%s: expected %s %q, got %q", test.alg, test.header, test.expected, got)
+		}
+		if data, _ := ioutil.ReadAll(resp.Body); string(data) != "hello world" {
+			t.Errorf("%s: expected the body to be left intact, got %q", test.alg, data)
+		}
+	}
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewResponder(200, "", AutoChecksum("whirlpool", "Digest"))(req); err == nil {
+		t.Error("expected an unknown algorithm to fail")
+	}
+}
+
+func TestAutoChecksumGzip(t *testing.T) {
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	gzipped := NewConditionalGzipResponder(200, []byte("hello world"))
+
+	resp, err := AutoChecksum("md5", "Content-MD5")(gzipped)(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, _ := ioutil.ReadAll(resp.Body)
+	sum := md5.Sum(compressed)
+	if got := resp.Header.Get("Content-MD5"); got != base64.StdEncoding.EncodeToString(sum[:]) {
+		t.Errorf("expected the checksum of the compressed body, got %q", got)
+	}
+
+	resp, err = AutoChecksum("md5", "Content-MD5", ChecksumDecoded())(gzipped)(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("Content-MD5"); got != "XrY7u+Ae7tCTyyK7j1rNww==" {
+		t.Errorf("expected the checksum of the decoded body, got %q", got)
+	}
+}
+
+func TestMatchBodyChecksumHeader(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte("hello world"))
+	zw.Close()
+
+	tests := []struct {
+		name     string
+		body     []byte
+		headers  map[string]string
+		opts     []ChecksumOption
+		expected string
+	}{
+		{"valid", []byte("hello world"), map[string]string{"Content-MD5": "XrY7u+Ae7tCTyyK7j1rNww=="}, nil, ""},
+		{"wrong", []byte("hello world!"), map[string]string{"Content-MD5": "XrY7u+Ae7tCTyyK7j1rNww=="}, nil,
+			`header Content-MD5 is "XrY7u+Ae7tCTyyK7j1rNww==", the md5 of the body is "/D/5joxqDTCH1RXARz+Gdw=="`},
+		{"missing", []byte("hello world"), nil, nil, "header Content-MD5 missing"},
+		{"decoded", compressed.Bytes(), map[string]string{"Content-MD5": "XrY7u+Ae7tCTyyK7j1rNww==", "Content-Encoding": "gzip"},
+			[]ChecksumOption{ChecksumDecoded()}, ""},
+		{"on the wire", compressed.Bytes(), map[string]string{"Content-MD5": "XrY7u+Ae7tCTyyK7j1rNww==", "Content-Encoding": "gzip"},
+			nil, "the md5 of the body is"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("PUT", testUrl, bytes.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range test.headers {
+			req.Header.Set(key, value)
+		}
+		err = MatchBodyChecksumHeader("md5", "Content-MD5", test.opts...)(req)
+		switch {
+		case test.expected == "" && err != nil:
+			t.Errorf("%s: expected a match, got %v", test.name, err)
+		case test.expected != "" && (err == nil || !strings.Contains(err.Error(), test.expected)):
+			t.Errorf("%s: expected a mismatch mentioning %q, got %v", test.name, test.expected, err)
+		}
+		if data, _ := ioutil.ReadAll(req.Body); !bytes.Equal(data, test.body) {
+			t.Errorf("%s: expected the body to be left intact", test.name)
+		}
+	}
+}
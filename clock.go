@@ -0,0 +1,111 @@
+package httpmock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by the package for anything time based, such as expiring
+// registrations.  Use SetClock to swap in a MockClock so tests can move time forward without
+// sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+var (
+	clockMu      sync.RWMutex
+	currentClock Clock = realClock{}
+)
+
+// SetClock replaces the clock used by the package.  Passing nil restores the real clock.
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	clockMu.Lock()
+	currentClock = c
+	clockMu.Unlock()
+}
+
+func getClock() Clock {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return currentClock
+}
+
+func now() time.Time {
+	return getClock().Now()
+}
+
+// MockClock is a Clock that only moves when told to.  Channels returned by After fire once
+// Advance or Set has moved the clock past their deadline.
+type MockClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []mockClockWaiter
+}
+
+type mockClockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewMockClock creates a MockClock set to the given time.
+func NewMockClock(start time.Time) *MockClock {
+	return &MockClock{now: start}
+}
+
+// Now returns the current time of the clock.
+func (c *MockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the clock's time once it has been advanced by d.
+func (c *MockClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, mockClockWaiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d, firing any After channels that are due.
+func (c *MockClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set moves the clock to t, firing any After channels that are due.
+func (c *MockClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(t)
+}
+
+// set moves the clock to t with c.mu held.
+func (c *MockClock) set(t time.Time) {
+	c.now = t
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.deadline.After(t) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- t
+	}
+	c.waiters = pending
+}
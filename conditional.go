@@ -0,0 +1,71 @@
+package httpmock
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Conditional is a Responder picking how to answer each request of a single registration by
+// evaluating predicates in order, the first one holding choosing the responder.  It is started
+// with When and registered through Else or its Respond method:
+//
+//	httpmock.RegisterResponder("GET", "https://api.mybiz.com/articles",
+//		httpmock.When(httpmock.Passes(httpmock.HeaderEquals("X-Dry-Run", "true")), httpmock.NewStringResponder(204, "")).
+//			ElseWhen(httpmock.Passes(httpmock.QueryEquals("page", "2")), httpmock.NewStringResponder(200, "[]")).
+//			Else(httpmock.NewStringResponder(200, `[{"id": 1}]`)))
+//
+// Matchers (see WithMatcher) pick between registrations, a Conditional branches within one, which
+// sometimes makes for a clearer test.
+type Conditional struct {
+	branches []conditionalBranch
+}
+
+type conditionalBranch struct {
+	pred func(*http.Request) bool
+	then Responder
+}
+
+// When starts a Conditional answering with then the requests pred holds for.  Predicates are
+// given a request whose body they can read freely, the body is restored before the next predicate
+// and the responder see it.
+func When(pred func(*http.Request) bool, then Responder) *Conditional {
+	return &Conditional{branches: []conditionalBranch{{pred: pred, then: then}}}
+}
+
+// ElseWhen returns a Conditional also answering with then the requests pred holds for, among
+// those none of the previous predicates held for.
+func (c *Conditional) ElseWhen(pred func(*http.Request) bool, then Responder) *Conditional {
+	branches := append(c.branches[:len(c.branches):len(c.branches)], conditionalBranch{pred: pred, then: then})
+	return &Conditional{branches: branches}
+}
+
+// Else returns a Responder answering like the Conditional, and with otherwise the requests none of
+// its predicates held for.
+func (c *Conditional) Else(otherwise Responder) Responder {
+	return c.ElseWhen(func(*http.Request) bool { return true }, otherwise).Respond
+}
+
+// Respond is the Responder of the Conditional.  Requests none of its predicates hold for fail with
+// an error naming them.
+func (c *Conditional) Respond(req *http.Request) (*http.Response, error) {
+	body, err := ReplayableRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, branch := range c.branches {
+		held := branch.pred(req)
+		setRequestBody(req, body)
+		if held {
+			return branch.then(req)
+		}
+	}
+	return nil, fmt.Errorf("httpmock: no condition of %d held for %s %s and no Else is set", len(c.branches), req.Method, req.URL)
+}
+
+// Passes turns check into a predicate for When, holding for the requests passing it.
+func Passes(check RequestCheck) func(*http.Request) bool {
+	return func(req *http.Request) bool {
+		return check.Check(req) == nil
+	}
+}
@@ -0,0 +1,68 @@
+package httpmock
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestConditional(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", testUrl,
+		When(func(req *http.Request) bool { return req.Header.Get("Authorization") == "" }, NewStringResponder(401, "unauthorized")).
+			ElseWhen(Passes(BodyContains(`"dryRun":true`)), NewStringResponder(200, "dry run")).
+			ElseWhen(Passes(QueryEquals("async", "1")), NewStringResponder(202, "accepted")).
+			Else(func(req *http.Request) (*http.Response, error) {
+				body, err := ioutil.ReadAll(req.Body)
+				if err != nil {
+					return nil, err
+				}
+				return NewStringResponse(201, "created "+string(body)), nil
+			}))
+	client := &http.Client{Transport: tr}
+
+	tests := []struct {
+		url, auth, body string
+		expected        string
+	}{
+		{testUrl, "", `{}`, "unauthorized"},
+		{testUrl, "token", `{"dryRun":true}`, "dry run"},
+		{testUrl + "?async=1", "token", `{}`, "accepted"},
+		{testUrl, "token", `{"name":"gadget"}`, `created {"name":"gadget"}`},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("POST", test.url, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.expected {
+			t.Errorf("%s %s: expected %q, got %q", test.url, test.body, test.expected, data)
+		}
+	}
+}
+
+func TestConditionalWithoutElse(t *testing.T) {
+	responder := When(Passes(HeaderEquals("X-Mode", "fast")), NewStringResponder(200, ""))
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = responder.Respond(req)
+	if err == nil || !strings.Contains(err.Error(), "GET "+testUrl) {
+		t.Errorf("expected an error naming the request, got %v", err)
+	}
+}
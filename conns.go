@@ -0,0 +1,156 @@
+package httpmock
+
+import (
+	"net/http"
+)
+
+// ConnectionStats counts the connections a MockTransport pretends to open and close.  There are no
+// real sockets: a connection to an address is opened by the first request to it, reused by the
+// following ones and closed by a response asking for it (see WithClose and SimulateClosingHost), by
+// a request setting Close, or by a failed exchange.  The mock keeps at most one connection per
+// address, even for concurrent requests.
+type ConnectionStats struct {
+	Opened int
+	Closed int
+}
+
+// ConnectionStats returns how many connections the MockTransport opened and closed since it was
+// created or last reset.  A pooling layer reusing its connections keeps Opened low, one that churns
+// them sees both grow with every request.
+func (m *MockTransport) ConnectionStats() ConnectionStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.connStats
+}
+
+// SimulateClosingHost makes host behave like a server closing the connection after every response:
+// responses from host get Close set and a "Connection: close" header.  host follows the same rules
+// as SimulateHostError.
+func (m *MockTransport) SimulateClosingHost(host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closingHosts == nil {
+		m.closingHosts = make(map[string]bool)
+	}
+	m.closingHosts[host] = true
+}
+
+// WithClose marks responses as the last one on their connection: Close is set and a
+// "Connection: close" header added, as a server about to hang up would.
+func WithClose() ResponderOption {
+	return func(inner Responder) Responder {
+		return decorateResponse(inner, markClose)
+	}
+}
+
+// markClose marks resp as closing its connection.  resp must be owned by the caller.
+func markClose(resp *http.Response) {
+	resp.Close = true
+	resp.Header.Set("Connection", "close")
+}
+
+// connect gets a connection to the address of req, reporting whether an open one was reused.
+func (m *MockTransport) connect(req *http.Request) bool {
+	addr := requestAddr(req).String()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.connected[addr] {
+		return true
+	}
+	if m.connected == nil {
+		m.connected = make(map[string]bool)
+	}
+	m.connected[addr] = true
+	m.connStats.Opened++
+	return false
+}
+
+// closeIfRequested marks resp as closing its connection when the request asked for it or the host
+// is simulated as closing, as a real server would.  resp must be owned by the call.
+func (m *MockTransport) closeIfRequested(req *http.Request, resp *http.Response) {
+	if resp.Close {
+		return
+	}
+
+	closing := req.Close
+	m.mu.Lock()
+	for pattern := range m.closingHosts {
+		closing = closing || hostMatches(pattern, req.URL)
+	}
+	m.mu.Unlock()
+
+	if closing {
+		resp.Header = resp.Header.Clone()
+		if resp.Header == nil {
+			resp.Header = http.Header{}
+		}
+		markClose(resp)
+	}
+}
+
+// release gives back the connection of req once its exchange is over, closing it if the response
+// asked for it or the exchange failed.
+func (m *MockTransport) release(req *http.Request, resp *http.Response, err error) {
+	if err == nil && resp != nil && !resp.Close {
+		return
+	}
+
+	addr := requestAddr(req).String()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.connected[addr] {
+		delete(m.connected, addr)
+		m.connStats.Closed++
+	}
+}
+
+// CloseIdleConnections closes the connections the MockTransport has open, all of them being idle
+// between requests, so that the next request to each address opens a new one.  There is nothing
+// else to close: the call is counted (see CloseIdleConnectionsCallCount) and the callback set with
+// OnCloseIdleConnections is run.  http.Client.CloseIdleConnections calls it.
+func (m *MockTransport) CloseIdleConnections() {
+	m.mu.Lock()
+	m.closeIdleCalls++
+	m.connStats.Closed += len(m.connected)
+	m.connected = nil
+	callback := m.onCloseIdle
+	m.mu.Unlock()
+
+	if callback != nil {
+		callback()
+	}
+}
+
+// CloseIdleConnectionsCallCount returns how many times CloseIdleConnections was called since the
+// MockTransport was created or last reset.
+func (m *MockTransport) CloseIdleConnectionsCallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closeIdleCalls
+}
+
+// OnCloseIdleConnections sets a callback run on every call to CloseIdleConnections.  A nil
+// callback removes it.
+func (m *MockTransport) OnCloseIdleConnections(callback func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onCloseIdle = callback
+}
+
+// CancelRequest implements the legacy cancellation interface http.Client still uses when its
+// Timeout expires.  Requests are canceled through their context, so the call is only counted (see
+// CancelRequestCallCount).
+func (m *MockTransport) CancelRequest(req *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancelRequestCalls++
+}
+
+// CancelRequestCallCount returns how many times CancelRequest was called since the MockTransport
+// was created or last reset.
+func (m *MockTransport) CancelRequestCallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cancelRequestCalls
+}
@@ -0,0 +1,119 @@
+package httpmock
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMockTransportConnectionStats(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", "http://keep.example.com/", NewStringResponder(200, "ok"))
+	tr.RegisterResponder("GET", "http://close.example.com/", NewResponder(200, "bye", WithClose()))
+	tr.RegisterResponder("GET", "http://churn.example.com/", NewStringResponder(200, "ok"))
+	tr.SimulateClosingHost("churn.example.com")
+	client := &http.Client{Transport: tr}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get("http://keep.example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Close || resp.Header.Get("Connection") != "" {
+			t.Fatalf("expected the connection to be kept alive, got Close=%v %q", resp.Close, resp.Header.Get("Connection"))
+		}
+	}
+	if stats := tr.ConnectionStats(); stats != (ConnectionStats{Opened: 1}) {
+		t.Fatalf("expected a single reused connection, got %+v", stats)
+	}
+
+	for _, u := range []string{"http://close.example.com/", "http://churn.example.com/"} {
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resp.Close || resp.Header.Get("Connection") != "close" {
+				t.Fatalf("%s: expected the response to close the connection, got Close=%v %q", u, resp.Close, resp.Header.Get("Connection"))
+			}
+		}
+	}
+	if stats := tr.ConnectionStats(); stats != (ConnectionStats{Opened: 5, Closed: 4}) {
+		t.Fatalf("expected a connection per closing response, got %+v", stats)
+	}
+
+	req, _ := http.NewRequest("GET", "http://keep.example.com/", nil)
+	req.Close = true
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Close || resp.Header.Get("Connection") != "close" {
+		t.Fatalf("expected the request's Close to be reflected, got Close=%v %q", resp.Close, resp.Header.Get("Connection"))
+	}
+	if stats := tr.ConnectionStats(); stats != (ConnectionStats{Opened: 5, Closed: 5}) {
+		t.Fatalf("expected the kept alive connection to be closed, got %+v", stats)
+	}
+
+	tr.RestoreHost("churn.example.com")
+	if resp, err := client.Get("http://churn.example.com/"); err != nil || resp.Close {
+		t.Fatalf("expected the host to keep connections alive again, got %v %v", resp, err)
+	}
+
+	// the shared response of the responder must not have been modified along the way
+	if resp, _ := client.Get("http://keep.example.com/"); resp.Header.Get("Connection") != "" {
+		t.Fatal("expected closing a connection not to leak into other calls")
+	}
+}
+
+func TestMockTransportCloseIdleConnections(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "ok"))
+	client := &http.Client{Transport: tr}
+
+	callbacks := 0
+	tr.OnCloseIdleConnections(func() { callbacks++ })
+
+	if _, err := client.Get(testUrl); err != nil {
+		t.Fatal(err)
+	}
+	client.CloseIdleConnections()
+	client.CloseIdleConnections()
+	if _, err := client.Get(testUrl); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := tr.CloseIdleConnectionsCallCount(); n != 2 || callbacks != 2 {
+		t.Fatalf("expected 2 calls and callbacks, got %d and %d", n, callbacks)
+	}
+	if stats := tr.ConnectionStats(); stats != (ConnectionStats{Opened: 2, Closed: 1}) {
+		t.Fatalf("expected the request after the close to reconnect, got %+v", stats)
+	}
+
+	tr.Reset()
+	if n := tr.CloseIdleConnectionsCallCount(); n != 0 {
+		t.Fatalf("expected Reset to clear the count, got %d", n)
+	}
+}
+
+func TestMockTransportCancelRequest(t *testing.T) {
+	responder, release := NewGatedResponder(NewStringResponder(200, "late"))
+	defer release()
+
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, responder)
+	client := &http.Client{Transport: tr, Timeout: 10 * time.Millisecond}
+
+	if _, err := client.Get(testUrl); err == nil {
+		t.Fatal("expected the request to time out")
+	}
+
+	// the client cancels the context before calling CancelRequest, so the call may come late
+	deadline := time.Now().Add(5 * time.Second)
+	for tr.CancelRequestCallCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := tr.CancelRequestCallCount(); n != 1 {
+		t.Fatalf("expected the client to cancel the request through the transport, got %d calls", n)
+	}
+}
@@ -0,0 +1,166 @@
+package httpmock
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// SetStrictContentLength makes the MockTransport check that every response it returns declares a
+// length its body actually has, reporting the violations with t.Errorf.  The Content-Length
+// header, the ContentLength field and the number of bytes read from the body must all agree; the
+// body is checked once read up to io.EOF.  A real client and server would choke on a mismatch the
+// mock otherwise lets through, so this catches hand-built responses gone stale.  Responses of
+// unknown length (ContentLength -1), and those declaring no length at all (ContentLength 0 without
+// a header, as the New*Response helpers build them), are exempt, as are the bodies of HEAD
+// requests, 204 and 304 responses.  Pass nil to stop checking.
+func (m *MockTransport) SetStrictContentLength(t testing.TB) {
+	if t == nil {
+		m.setContentLengthReport(nil)
+		return
+	}
+	m.setContentLengthReport(func(err error) {
+		t.Helper()
+		t.Errorf("%s", err)
+	})
+}
+
+// SetStrictContentLengthErrors is like SetStrictContentLength, but sends the violations to errs
+// instead.  The sends don't block: errs must be buffered enough to hold them, or the violations
+// that don't fit are dropped.
+func (m *MockTransport) SetStrictContentLengthErrors(errs chan<- error) {
+	if errs == nil {
+		m.setContentLengthReport(nil)
+		return
+	}
+	m.setContentLengthReport(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+}
+
+func (m *MockTransport) setContentLengthReport(report func(error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.contentLengthReport = report
+}
+
+// checkContentLength reports the disagreements between the declared lengths of resp, and has its
+// body checked against them, if strict checking is on.
+func (m *MockTransport) checkContentLength(req *http.Request, resp *http.Response, key string) {
+	m.mu.Lock()
+	report := m.contentLengthReport
+	m.mu.Unlock()
+	if report == nil || resp.ContentLength < 0 {
+		return
+	}
+
+	header, declared := resp.Header.Get("Content-Length"), resp.ContentLength
+	if header == "" && declared == 0 {
+		return
+	}
+	if key == "" {
+		key = "no responder"
+	}
+	fail := func(format string, args ...interface{}) {
+		report(fmt.Errorf("httpmock: %s %s (%s): %s", req.Method, req.URL, key, fmt.Sprintf(format, args...)))
+	}
+
+	if header != "" {
+		n, err := strconv.ParseInt(header, 10, 64)
+		switch {
+		case err != nil || n < 0:
+			fail("invalid Content-Length header %q", header)
+			return
+		case n != declared:
+			fail("Content-Length header %d but ContentLength %d", n, declared)
+			return
+		}
+	}
+
+	if req.Method == "HEAD" || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified ||
+		resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+	body := &lengthCheckedBody{ReadCloser: resp.Body, declared: declared, fail: fail}
+	if _, ok := resp.Body.(io.WriterTo); ok {
+		resp.Body = lengthCheckedWriterToBody{body}
+		return
+	}
+	resp.Body = body
+}
+
+// lengthCheckedBody counts the bytes read from a body, reporting once whether they add up to the
+// declared length.
+type lengthCheckedBody struct {
+	io.ReadCloser
+	declared int64
+	fail     func(format string, args ...interface{})
+
+	mu   sync.Mutex
+	read int64
+	done bool
+}
+
+func (b *lengthCheckedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.count(n, err)
+	return n, err
+}
+
+// count adds n bytes read to the total, checking it against the declared length once the body is
+// over or too long.
+func (b *lengthCheckedBody) count(n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.read += int64(n)
+	if b.done {
+		return
+	}
+	switch {
+	case b.read > b.declared:
+		b.done = true
+		b.fail("body longer than its declared %d bytes", b.declared)
+	case err == io.EOF && b.read < b.declared:
+		b.done = true
+		b.fail("body of %d bytes but %d declared", b.read, b.declared)
+	case err == io.EOF:
+		b.done = true
+	}
+}
+
+func (b *lengthCheckedBody) unwrapBody() io.ReadCloser {
+	return b.ReadCloser
+}
+
+// lengthCheckedWriterToBody is a lengthCheckedBody keeping the io.WriterTo of the body it wraps,
+// for io.Copy to still take the fast path.
+type lengthCheckedWriterToBody struct {
+	*lengthCheckedBody
+}
+
+func (b lengthCheckedWriterToBody) WriteTo(w io.Writer) (int64, error) {
+	n, err := b.ReadCloser.(io.WriterTo).WriteTo(lengthCheckedWriter{w: w, body: b.lengthCheckedBody})
+	if err == nil {
+		// WriteTo returns nil once the body is over
+		b.count(0, io.EOF)
+	}
+	return n, err
+}
+
+// lengthCheckedWriter counts in body what is written to w, as read by the client.
+type lengthCheckedWriter struct {
+	w    io.Writer
+	body *lengthCheckedBody
+}
+
+func (l lengthCheckedWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	l.body.count(n, nil)
+	return n, err
+}
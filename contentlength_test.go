@@ -0,0 +1,90 @@
+package httpmock
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSetStrictContentLength(t *testing.T) {
+	withLength := func(header string, length int64, body string) Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			resp := NewStringResponse(200, body)
+			if header != "" {
+				resp.Header.Set("Content-Length", header)
+			}
+			resp.ContentLength = length
+			return resp, nil
+		}
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		responder Responder
+		expected  string
+	}{
+		{"consistent", "GET", withLength("5", 5, "hello"), ""},
+		{"no header", "GET", withLength("", 5, "hello"), ""},
+		{"undeclared", "GET", NewStringResponder(200, "hello"), ""},
+		{"unknown", "GET", withLength("", -1, "hello"), ""},
+		{"head", "HEAD", withLength("5", 5, ""), ""},
+		{"header mismatch", "GET", withLength("10", 5, "hello"), "Content-Length header 10 but ContentLength 5"},
+		{"invalid header", "GET", withLength("five", 5, "hello"), `invalid Content-Length header "five"`},
+		{"short body", "GET", withLength("10", 10, "hello"), "body of 5 bytes but 10 declared"},
+		{"long body", "GET", withLength("", 3, "hello"), "body longer than its declared 3 bytes"},
+	}
+
+	for _, test := range tests {
+		tr := NewMockTransport()
+		tr.RegisterResponder(test.method, testUrl, test.responder)
+		errs := make(chan error, 10)
+		tr.SetStrictContentLengthErrors(errs)
+
+		req, err := http.NewRequest(test.method, testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		close(errs)
+		var got []string
+		for err := range errs {
+			got = append(got, err.Error())
+		}
+		switch {
+		case test.expected == "" && len(got) > 0:
+			t.Errorf("%s: expected no violation, got %v", test.name, got)
+		case test.expected != "" && (len(got) != 1 || !strings.Contains(got[0], test.expected) ||
+			!strings.Contains(got[0], "("+test.method+" "+testUrl+")")):
+			t.Errorf("%s: expected a violation of the registration mentioning %q, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestSetStrictContentLengthReportsToTest(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(200, "hello")
+		resp.ContentLength = 4
+		return resp, nil
+	})
+	rec := &recordingTB{TB: t}
+	tr.SetStrictContentLength(rec)
+
+	resp, err := (&http.Client{Transport: tr}).Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if len(rec.errors) != 1 {
+		t.Errorf("expected the long body to be reported, got %v", rec.errors)
+	}
+}
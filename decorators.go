@@ -0,0 +1,136 @@
+package httpmock
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithTLSState creates a Responder returning the responses of inner with their TLS field set to
+// state, so that code inspecting resp.TLS (certificate pinning, cipher suite checks) can be tested
+// without a TLS server.
+func WithTLSState(inner Responder, state *tls.ConnectionState) Responder {
+	return decorateResponse(inner, func(resp *http.Response) {
+		resp.TLS = state
+	})
+}
+
+// WithConnectionClose creates a Responder returning the responses of inner with Close set and a
+// "Connection: close" header, so that clients can be tested not to reuse a connection the server
+// wants closed.  It is the decorator form of the WithClose option.
+func WithConnectionClose(inner Responder) Responder {
+	return decorateResponse(inner, markClose)
+}
+
+// WithVary creates a Responder adding fields to the Vary header of the responses of inner, so that
+// cache-aware clients can be tested to key their caches on the right request headers.  Fields
+// already listed, whatever their case, aren't repeated, and a Vary of "*" is left as is.
+func WithVary(inner Responder, fields ...string) Responder {
+	return decorateResponse(inner, func(resp *http.Response) {
+		var vary []string
+		seen := make(map[string]bool)
+		for _, line := range resp.Header.Values("Vary") {
+			for _, field := range strings.Split(line, ",") {
+				if field = strings.TrimSpace(field); field != "" && !seen[strings.ToLower(field)] {
+					seen[strings.ToLower(field)] = true
+					vary = append(vary, field)
+				}
+			}
+		}
+		if seen["*"] {
+			return
+		}
+		for _, field := range fields {
+			if !seen[strings.ToLower(field)] {
+				seen[strings.ToLower(field)] = true
+				vary = append(vary, field)
+			}
+		}
+		if len(vary) > 0 {
+			resp.Header.Set("Vary", strings.Join(vary, ", "))
+		}
+	})
+}
+
+// WithVia creates a Responder adding via, as in "1.1 squid", to the Via header of the responses of
+// inner, as a transparent proxy between the client and the server would.  The Via values already
+// set by inner are kept, via coming after them.
+func WithVia(inner Responder, via string) Responder {
+	return decorateResponse(inner, func(resp *http.Response) {
+		resp.Header.Add("Via", via)
+	})
+}
+
+// WithServerTiming creates a Responder adding to the responses of inner a Server-Timing header
+// reporting, in milliseconds, how long inner took to produce them, as in "app;dur=12.3".  The span
+// is measured on the monotonic clock and includes any delay applied inside inner.
+func WithServerTiming(inner Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		var elapsed time.Duration
+		timed := func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			defer func() { elapsed = time.Since(start) }()
+			return inner(req)
+		}
+		return decorateResponse(timed, func(resp *http.Response) {
+			ms := float64(elapsed) / float64(time.Millisecond)
+			resp.Header.Add("Server-Timing", "app;dur="+strconv.FormatFloat(ms, 'f', 1, 64))
+		})(req)
+	}
+}
+
+// decorateResponse creates a Responder applying fn to a copy of every response returned by inner,
+// so that responses shared between calls are never modified.  The copy has its own Header.
+func decorateResponse(inner Responder, fn func(*http.Response)) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := inner(req)
+		if err != nil || resp == nil {
+			return resp, err
+		}
+
+		decorated := *resp
+		decorated.Header = resp.Header.Clone()
+		if decorated.Header == nil {
+			decorated.Header = http.Header{}
+		}
+		fn(&decorated)
+		return &decorated, nil
+	}
+}
+
+// hopByHopHeaders are the headers RFC 7230 reserves for a single connection, which proxies don't
+// forward.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// StripHopByHop creates a Responder passing inner a copy of each request without its hop-by-hop
+// headers: those RFC 7230 reserves for a single connection, such as Connection, Keep-Alive, TE and
+// Transfer-Encoding, and any header the Connection header names.  It keeps checks and responders
+// in proxy style tests focused on the end-to-end headers.  The original request is not modified.
+func StripHopByHop(inner Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		stripped := req.Clone(req.Context())
+		for _, value := range stripped.Header.Values("Connection") {
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					stripped.Header.Del(name)
+				}
+			}
+		}
+		for _, name := range hopByHopHeaders {
+			stripped.Header.Del(name)
+		}
+		return inner(stripped)
+	}
+}
@@ -0,0 +1,172 @@
+package httpmock
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithTLSState(t *testing.T) {
+	state := &tls.ConnectionState{
+		Version:     tls.VersionTLS13,
+		CipherSuite: tls.TLS_AES_128_GCM_SHA256,
+	}
+
+	base := NewStringResponse(200, "secure")
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, WithTLSState(ResponderFromResponse(base), state))
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TLS != state {
+		t.Fatalf("expected the TLS state to be attached, got %v", resp.TLS)
+	}
+	if base.TLS != nil {
+		t.Fatal("expected the original response to be left untouched")
+	}
+}
+
+func TestStripHopByHop(t *testing.T) {
+	var seen http.Header
+	responder := StripHopByHop(func(req *http.Request) (*http.Response, error) {
+		seen = req.Header
+		return NewStringResponse(200, ""), nil
+	})
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Connection", "keep-alive, X-Private")
+	req.Header.Set("Keep-Alive", "timeout=5")
+	req.Header.Set("TE", "trailers")
+	req.Header.Set("X-Private", "secret")
+	req.Header.Set("Accept", "application/json")
+
+	if _, err := responder(req); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Connection", "Keep-Alive", "TE", "X-Private"} {
+		if seen.Get(name) != "" {
+			t.Errorf("expected %s to be stripped, got %q", name, seen.Get(name))
+		}
+	}
+	if seen.Get("Accept") != "application/json" {
+		t.Errorf("expected end-to-end headers to be kept, got %v", seen)
+	}
+	if req.Header.Get("Connection") == "" || req.Header.Get("X-Private") == "" {
+		t.Errorf("expected the original request to be left untouched, got %v", req.Header)
+	}
+}
+
+func TestWithServerTiming(t *testing.T) {
+	responder := WithServerTiming(NewResponder(200, "", WithFixedDelay(20*time.Millisecond)))
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := responder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timing := resp.Header.Get("Server-Timing")
+	if !strings.HasPrefix(timing, "app;dur=") {
+		t.Fatalf("expected a Server-Timing header, got %q", timing)
+	}
+	dur, err := strconv.ParseFloat(strings.TrimPrefix(timing, "app;dur="), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dur < 20 {
+		t.Fatalf("expected the duration to include the delay, got %s", timing)
+	}
+}
+
+func TestWithVary(t *testing.T) {
+	withVary := func(vary ...string) Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			resp := NewStringResponse(200, "")
+			for _, v := range vary {
+				resp.Header.Add("Vary", v)
+			}
+			return resp, nil
+		}
+	}
+
+	tests := []struct {
+		existing []string
+		fields   []string
+		expected string
+	}{
+		{nil, []string{"Accept-Encoding", "Accept-Language"}, "Accept-Encoding, Accept-Language"},
+		{[]string{"Origin"}, []string{"Accept-Encoding"}, "Origin, Accept-Encoding"},
+		{[]string{"Origin, accept-encoding", "Cookie"}, []string{"Accept-Encoding", "Authorization"}, "Origin, accept-encoding, Cookie, Authorization"},
+		{[]string{"*"}, []string{"Accept-Encoding"}, "*"},
+		{nil, nil, ""},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := WithVary(withVary(test.existing...), test.fields...)(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vary := strings.Join(resp.Header.Values("Vary"), " | "); vary != test.expected {
+			t.Errorf("%q + %q: expected Vary %q, got %q", test.existing, test.fields, test.expected, vary)
+		}
+	}
+}
+
+func TestWithConnectionClose(t *testing.T) {
+	base := NewStringResponse(200, "bye")
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, WithConnectionClose(ResponderFromResponse(base)))
+	client := &http.Client{Transport: tr}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(testUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if !resp.Close || resp.Header.Get("Connection") != "close" {
+			t.Fatalf("expected the response to close its connection, got Close=%v %v", resp.Close, resp.Header)
+		}
+	}
+	if base.Close || base.Header.Get("Connection") != "" {
+		t.Fatal("expected the original response to be left untouched")
+	}
+	if stats := tr.ConnectionStats(); stats != (ConnectionStats{Opened: 2, Closed: 2}) {
+		t.Fatalf("expected no connection reuse, got %+v", stats)
+	}
+}
+
+func TestWithVia(t *testing.T) {
+	inner := func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(200, "")
+		resp.Header.Set("Via", "1.1 origin-cache")
+		return resp, nil
+	}
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, WithVia(WithVia(inner, "1.1 squid"), "1.0 corporate"))
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if via := strings.Join(resp.Header.Values("Via"), ", "); via != "1.1 origin-cache, 1.1 squid, 1.0 corporate" {
+		t.Errorf("expected the Via values appended in order, got %q", via)
+	}
+}
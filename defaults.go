@@ -0,0 +1,68 @@
+package httpmock
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SetDefaultResponder makes responder answer every request under pattern that no registration
+// matches, before falling back on the no responder, for smoke tests that don't care about the
+// exact endpoints:
+//
+//	tr.SetDefaultResponder("api.example.com", httpmock.NewStringResponder(200, "{}"))
+//	tr.SetDefaultResponder("*.example.com/v1", httpmock.NewStringResponder(200, "{}"))
+//
+// pattern is a host, following the same rules as the host of SimulateHostError, optionally followed
+// by a path prefix that the request path must start with, segment wise.  When several patterns
+// match, the most specific host wins, then the longest path prefix.  The calls it answers are
+// recorded with pattern as Key, and Default set.  A nil responder removes the pattern.  Reset
+// clears them all.
+func (m *MockTransport) SetDefaultResponder(pattern string, responder Responder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if responder == nil {
+		delete(m.defaults, pattern)
+		return
+	}
+	if m.defaults == nil {
+		m.defaults = make(map[string]Responder)
+	}
+	m.defaults[pattern] = responder
+}
+
+// defaultResponder returns the responder of the most specific default pattern covering req, if
+// any, and the pattern.
+func (m *MockTransport) defaultResponder(req *http.Request) (Responder, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	best, bestHost, bestPath := "", -1, -1
+	for pattern := range m.defaults {
+		host, prefix := splitDefaultPattern(pattern)
+		if !hostMatches(host, req.URL) || !pathHasPrefix(req.URL.Path, prefix) {
+			continue
+		}
+		hostScore := patternSpecificity(host)
+		if hostScore > bestHost || (hostScore == bestHost && len(prefix) > bestPath) ||
+			(hostScore == bestHost && len(prefix) == bestPath && pattern < best) {
+			best, bestHost, bestPath = pattern, hostScore, len(prefix)
+		}
+	}
+	if bestHost < 0 {
+		return nil, ""
+	}
+	return m.defaults[best], best
+}
+
+// splitDefaultPattern splits a pattern of SetDefaultResponder into its host and path prefix.
+func splitDefaultPattern(pattern string) (host, prefix string) {
+	if i := strings.IndexByte(pattern, '/'); i >= 0 {
+		return pattern[:i], strings.TrimSuffix(pattern[i:], "/")
+	}
+	return pattern, ""
+}
+
+// pathHasPrefix reports whether path is prefix or lies below it.
+func pathHasPrefix(path, prefix string) bool {
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
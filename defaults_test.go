@@ -0,0 +1,83 @@
+package httpmock
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSetDefaultResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", "http://api.example.com/v1/users", NewStringResponder(200, "users"))
+	tr.RegisterResponder("GET", "http://api.example.com/v1/admin", NewStringResponder(200, "admin"),
+		WithMatcher(func(req *http.Request) error {
+			if req.Header.Get("X-Admin") == "" {
+				return errors.New("not an admin")
+			}
+			return nil
+		}))
+	tr.SetDefaultResponder("api.example.com", NewStringResponder(200, "{}"))
+	tr.SetDefaultResponder("*.example.com/v1", NewStringResponder(200, "v1"))
+	tr.SetDefaultResponder("api.example.com/v1/", NewStringResponder(200, "api v1"))
+	tr.RegisterNoResponder(NewStringResponder(404, "none"))
+	client := &http.Client{Transport: tr}
+
+	tests := []struct {
+		url, admin string
+		expected   string
+	}{
+		{"http://api.example.com/v1/users", "", "users"},
+		{"http://api.example.com/v1/admin", "yes", "admin"},
+		{"http://api.example.com/v1/admin", "", "api v1"},
+		{"http://api.example.com/v2/anything", "", "{}"},
+		{"http://api.example.com/v10", "", "{}"},
+		{"http://cdn.example.com/v1/logo.png", "", "v1"},
+		{"http://cdn.example.com/v2/logo.png", "", "none"},
+		{"http://other.com/", "", "none"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.admin != "" {
+			req.Header.Set("X-Admin", test.admin)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(data) != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.url, test.expected, data)
+		}
+	}
+
+	expected := map[string]int{
+		"GET http://api.example.com/v1/users": 1,
+		"GET http://api.example.com/v1/admin": 1,
+		"api.example.com/v1/":                 1,
+		"api.example.com":                     2,
+		"*.example.com/v1":                    1,
+	}
+	if counts := tr.CallCounts(); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected call counts %v, got %v", expected, counts)
+	}
+	if history := tr.History(); !history[2].Default || history[0].Default || history[6].Default {
+		t.Error("expected only the calls answered by a default responder to be marked as such")
+	}
+	if n := len(tr.UnmatchedRequests()); n != 2 {
+		t.Errorf("expected the calls past the default responders to be unmatched, got %d", n)
+	}
+
+	tr.SetDefaultResponder("api.example.com", nil)
+	tr.Reset()
+	resp, err := client.Get("http://api.example.com/v1/users")
+	if err == nil {
+		resp.Body.Close()
+		t.Error("expected Reset to clear the default responders")
+	}
+}
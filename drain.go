@@ -0,0 +1,82 @@
+package httpmock
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// DefaultRequestBodyDrainLimit is the number of bytes a MockTransport drains from request bodies
+// by default, see SetRequestBodyDrain.
+const DefaultRequestBodyDrainLimit = 1 << 20
+
+// SetRequestBodyDrain sets how many bytes the MockTransport drains from the body of a request once
+// done with it, before closing it, as a real server reads or discards what the client sent.  The
+// body is usually read in full before matching, to record it; draining only matters when it was
+// not, as for requests whose GetBody provided the copy.  Either way the body is closed exactly
+// once, whatever the responder did with its own copy, and Call.BodyRead tells how many bytes were
+// read from it.  A limit of zero or less turns draining off, leaving bodies the transport didn't
+// need to read unread and open.  Reset leaves the setting in place.
+func (m *MockTransport) SetRequestBodyDrain(limit int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.noDrain = limit <= 0
+	m.drainLimit = limit
+}
+
+// trackRequestBody wraps the body of req to know how much of it was read and whether it was
+// closed.  It returns nil for requests without body.
+func trackRequestBody(req *http.Request) *trackedBody {
+	if req.Body == nil {
+		return nil
+	}
+	tracked := &trackedBody{rc: req.Body}
+	req.Body = tracked
+	return tracked
+}
+
+// finishRequestBody drains and closes the body of the client, recording how much of it was read
+// in call.
+func (m *MockTransport) finishRequestBody(body *trackedBody, call *Call) {
+	if body == nil {
+		return
+	}
+
+	m.mu.Lock()
+	drain, limit := !m.noDrain, m.drainLimit
+	m.mu.Unlock()
+	if limit <= 0 {
+		limit = DefaultRequestBodyDrainLimit
+	}
+	if drain && !body.closed {
+		io.CopyN(ioutil.Discard, body, limit)
+		body.Close()
+	}
+
+	if call != nil {
+		m.mu.Lock()
+		call.BodyRead = body.n
+		m.mu.Unlock()
+	}
+}
+
+// trackedBody counts the bytes read from a request body and closes it at most once.
+type trackedBody struct {
+	rc     io.ReadCloser
+	n      int64
+	closed bool
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *trackedBody) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return b.rc.Close()
+}
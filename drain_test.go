@@ -0,0 +1,125 @@
+package httpmock
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// closeCountingBody counts the bytes read from it and the calls to Close.
+type closeCountingBody struct {
+	r      io.Reader
+	read   int
+	closes int
+}
+
+func (b *closeCountingBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.read += n
+	return n, err
+}
+
+func (b *closeCountingBody) Close() error {
+	b.closes++
+	return nil
+}
+
+func TestRequestBodyDrain(t *testing.T) {
+	consuming := func(req *http.Request) (*http.Response, error) {
+		ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		return NewStringResponse(200, ""), nil
+	}
+	ignoring := NewStringResponder(200, "")
+
+	for name, responder := range map[string]Responder{"consuming": consuming, "ignoring": ignoring} {
+		tr := NewMockTransport()
+		tr.RegisterResponder("POST", testUrl, responder)
+
+		// a body read to record it
+		body := &closeCountingBody{r: strings.NewReader("payload")}
+		req, _ := http.NewRequest("POST", testUrl, body)
+		if _, err := tr.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		if body.closes != 1 || body.read != 7 || tr.LastCall().BodyRead != 7 {
+			t.Errorf("%s: expected the body read and closed once, got %d bytes, %d closes, BodyRead %d",
+				name, body.read, body.closes, tr.LastCall().BodyRead)
+		}
+
+		// a body whose copy came from GetBody is drained
+		body = &closeCountingBody{r: strings.NewReader("payload")}
+		req, _ = http.NewRequest("POST", testUrl, body)
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader("payload")), nil
+		}
+		if _, err := tr.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		if body.closes != 1 || body.read != 7 || tr.LastCall().BodyRead != 7 {
+			t.Errorf("%s: expected the body drained and closed once, got %d bytes, %d closes, BodyRead %d",
+				name, body.read, body.closes, tr.LastCall().BodyRead)
+		}
+	}
+}
+
+func TestRequestBodyDrainLimit(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", testUrl, NewStringResponder(200, ""))
+	tr.SetRequestBodyDrain(4)
+
+	post := func() *closeCountingBody {
+		data := bytes.Repeat([]byte("x"), 10)
+		body := &closeCountingBody{r: bytes.NewReader(data)}
+		req, _ := http.NewRequest("POST", testUrl, body)
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		if _, err := tr.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	if body := post(); body.read != 4 || body.closes != 1 || tr.LastCall().BodyRead != 4 {
+		t.Errorf("expected the drain capped, got %d bytes, %d closes", body.read, body.closes)
+	}
+	if len(tr.LastCall().Body) != 10 {
+		t.Errorf("expected the whole body recorded, got %d bytes", len(tr.LastCall().Body))
+	}
+
+	tr.SetRequestBodyDrain(0)
+	if body := post(); body.read != 0 || body.closes != 0 {
+		t.Errorf("expected no draining, got %d bytes, %d closes", body.read, body.closes)
+	}
+}
+
+func TestEarlyResponseResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("PUT", testUrl, NewEarlyResponseResponder(413, "too large"))
+	tr.SetRequestBodyDrain(0)
+
+	body := &closeCountingBody{r: strings.NewReader("a large upload")}
+	req, _ := http.NewRequest("PUT", testUrl, body)
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("a large upload")), nil
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 413 || string(data) != "too large" || !resp.Close {
+		t.Errorf("expected a 413 closing the connection, got %d %q Close=%v", resp.StatusCode, data, resp.Close)
+	}
+	if body.read != 0 || body.closes != 0 || tr.LastCall().BodyRead != 0 {
+		t.Errorf("expected the upload to be left unread, got %d bytes, %d closes, BodyRead %d",
+			body.read, body.closes, tr.LastCall().BodyRead)
+	}
+	if string(tr.LastCall().Body) != "a large upload" {
+		t.Errorf("expected the call to record the body from GetBody, got %q", tr.LastCall().Body)
+	}
+}
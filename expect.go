@@ -0,0 +1,106 @@
+package httpmock
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// RequestCheck is an assertion about a request, see ExpectingResponder.
+type RequestCheck struct {
+	// Name identifies the check in failure messages.
+	Name string
+	// Check returns nil if the request passes, or an error describing the difference.
+	Check Matcher
+}
+
+// HeaderEquals checks that the request has the header key set to value.
+func HeaderEquals(key, value string) RequestCheck {
+	return RequestCheck{
+		Name: fmt.Sprintf("HeaderEquals(%q, %q)", key, value),
+		Check: func(req *http.Request) error {
+			if got := req.Header.Values(key); len(got) != 1 || got[0] != value {
+				return fmt.Errorf("expected %q, got %q", value, got)
+			}
+			return nil
+		},
+	}
+}
+
+// QueryEquals checks that the request query has the parameter key set to value.
+func QueryEquals(key, value string) RequestCheck {
+	return RequestCheck{
+		Name: fmt.Sprintf("QueryEquals(%q, %q)", key, value),
+		Check: func(req *http.Request) error {
+			if got := req.URL.Query()[key]; len(got) != 1 || got[0] != value {
+				return fmt.Errorf("expected %q, got %q", value, got)
+			}
+			return nil
+		},
+	}
+}
+
+// BodyContains checks that the request body contains substr.
+func BodyContains(substr string) RequestCheck {
+	return RequestCheck{
+		Name: fmt.Sprintf("BodyContains(%q)", substr),
+		Check: func(req *http.Request) error {
+			body, err := ReplayableRequestBody(req)
+			if err != nil {
+				return err
+			}
+			if !bytes.Contains(body, []byte(substr)) {
+				return fmt.Errorf("body %q doesn't contain it", body)
+			}
+			return nil
+		},
+	}
+}
+
+// BodyJSONEquals checks that the request body is the same JSON as expected, as MatchBodyJSON
+// does.
+func BodyJSONEquals(expected interface{}, opts ...JSONOption) RequestCheck {
+	return RequestCheck{
+		Name:  "BodyJSONEquals",
+		Check: MatchBodyJSON(expected, opts...),
+	}
+}
+
+// ExpectingResponder creates a Responder asserting that every request it receives passes checks.
+// Failed checks are reported with t.Errorf, naming the check, the difference and the line where
+// the ExpectingResponder was created, but the request is still answered by inner so that the
+// client doesn't hang:
+//
+//	httpmock.RegisterResponder("POST", "https://api.mybiz.com/articles.json",
+//		httpmock.ExpectingResponder(t, httpmock.NewStringResponder(201, ""),
+//			httpmock.HeaderEquals("Content-Type", "application/json"),
+//			httpmock.BodyJSONEquals(`{"title": "My Great Article"}`)))
+func ExpectingResponder(t testing.TB, inner Responder, checks ...RequestCheck) Responder {
+	t.Helper()
+
+	registeredAt := "unknown location"
+	if _, file, line, ok := runtime.Caller(1); ok {
+		registeredAt = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+
+	return func(req *http.Request) (*http.Response, error) {
+		body, err := ReplayableRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, check := range checks {
+			setRequestBody(req, body)
+			if err := check.Check(req); err != nil {
+				t.Errorf("httpmock: %s %s failed %s (expectation registered at %s): %s",
+					req.Method, req.URL, check.Name, registeredAt, err)
+			}
+		}
+
+		setRequestBody(req, body)
+		return inner(req)
+	}
+}
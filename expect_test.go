@@ -0,0 +1,58 @@
+package httpmock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExpectingResponder(t *testing.T) {
+	rec := &recordingTB{TB: t}
+
+	tr := NewMockTransport()
+	_, _, line, _ := runtime.Caller(0)
+	tr.RegisterResponder("POST", testUrl, ExpectingResponder(rec, func(req *http.Request) (*http.Response, error) {
+		data, _ := ioutil.ReadAll(req.Body)
+		return NewStringResponse(201, string(data)), nil
+	},
+		HeaderEquals("Content-Type", "application/json"),
+		QueryEquals("dry_run", "false"),
+		BodyJSONEquals(`{"title": "hello"}`),
+	))
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Post(testUrl+"?dry_run=false", "application/json", strings.NewReader(`{"title":"hello"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.errors) != 0 {
+		t.Fatalf("expected the checks to pass, got %q", rec.errors)
+	}
+
+	resp, err = client.Post(testUrl, "text/plain", strings.NewReader(`{"title":"bye"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 201 || string(data) != `{"title":"bye"}` {
+		t.Fatalf("expected the inner responder to still run with the body, got %d %q", resp.StatusCode, data)
+	}
+
+	if len(rec.errors) != 3 {
+		t.Fatalf("expected three failed checks, got %q", rec.errors)
+	}
+	// the expectations are reported at the line registering them, the one after runtime.Caller
+	registered := fmt.Sprintf("(expectation registered at expect_test.go:%d)", line+1)
+	for i, want := range []string{
+		`HeaderEquals("Content-Type", "application/json") ` + registered + `: expected "application/json", got ["text/plain"]`,
+		`QueryEquals("dry_run", "false")`,
+		`BodyJSONEquals ` + registered + `: body JSON differs: $.title: expected "hello", got "bye"`,
+	} {
+		if !strings.Contains(rec.errors[i], want) {
+			t.Errorf("expected %q in %q", want, rec.errors[i])
+		}
+	}
+}
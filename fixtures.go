@@ -0,0 +1,90 @@
+package httpmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// fixture is an entry of a fixtures file.
+type fixture struct {
+	Status  *int              `json:"status"`
+	Body    string            `json:"body"`
+	Headers map[string]string `json:"headers"`
+}
+
+// LoadFixtures reads the JSON fixtures file at path and creates a Responder for each of its
+// entries.  The file maps "METHOD URL" keys to the status, body and headers of the response:
+//
+//	{
+//		"GET /users/1": {"status": 200, "body": "{\"id\": 1}", "headers": {"Content-Type": "application/json"}},
+//		"DELETE /users/1": {"status": 204}
+//	}
+//
+// The returned map keeps those keys, so registering all the fixtures is a matter of:
+//
+//	for key, responder := range fixtures {
+//		parts := strings.SplitN(key, " ", 2)
+//		httpmock.RegisterResponder(parts[0], parts[1], responder)
+//	}
+//
+// The status of each entry is required and body defaults to empty.  Malformed entries make the
+// whole file fail, with an error naming their key.
+func LoadFixtures(path string) (map[string]Responder, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("httpmock: invalid fixtures file %s: %s", path, err)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	responders := make(map[string]Responder, len(entries))
+	for _, key := range keys {
+		responder, err := fixtureResponder(key, entries[key])
+		if err != nil {
+			return nil, fmt.Errorf("httpmock: fixture %q in %s: %s", key, path, err)
+		}
+		responders[key] = responder
+	}
+	return responders, nil
+}
+
+// fixtureResponder creates the Responder of a single fixtures file entry.
+func fixtureResponder(key string, raw json.RawMessage) (Responder, error) {
+	parts := strings.SplitN(key, " ", 2)
+	if len(parts) != 2 || parts[0] == "" || strings.TrimSpace(parts[1]) == "" {
+		return nil, errors.New(`key must be "METHOD URL"`)
+	}
+
+	var f fixture
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&f); err != nil {
+		return nil, err
+	}
+	if f.Status == nil {
+		return nil, errors.New("missing status")
+	}
+	if *f.Status < 100 || *f.Status > 999 {
+		return nil, fmt.Errorf("invalid status %d", *f.Status)
+	}
+
+	resp := NewStringResponse(*f.Status, f.Body)
+	for name, value := range f.Headers {
+		resp.Header.Set(name, value)
+	}
+	return ResponderFromResponse(resp), nil
+}
@@ -0,0 +1,81 @@
+package httpmock
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFixtures(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFixtures(t *testing.T) {
+	fixtures, err := LoadFixtures(writeFixtures(t, `{
+		"GET http://www.example.com/users/1": {"status": 200, "body": "{\"id\": 1}", "headers": {"Content-Type": "application/json"}},
+		"DELETE http://www.example.com/users/1": {"status": 204}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != 2 {
+		t.Fatalf("expected 2 fixtures, got %d", len(fixtures))
+	}
+
+	tr := NewMockTransport()
+	for key, responder := range fixtures {
+		parts := strings.SplitN(key, " ", 2)
+		tr.RegisterResponder(parts[0], parts[1], responder)
+	}
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get("http://www.example.com/users/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(data) != `{"id": 1}` || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response %d %q %v", resp.StatusCode, data, resp.Header)
+	}
+
+	req, _ := http.NewRequest("DELETE", "http://www.example.com/users/1", nil)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 204 {
+		t.Fatalf("expected a 204, got %d", resp.StatusCode)
+	}
+}
+
+func TestLoadFixturesErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{`[]`, "invalid fixtures file"},
+		{`{"/users": {"status": 200}}`, `fixture "/users"`},
+		{`{"GET /users": {"body": "x"}}`, `fixture "GET /users" in`},
+		{`{"GET /users": {"status": 42}}`, "invalid status 42"},
+		{`{"GET /users": {"status": 200, "body": 12}}`, `fixture "GET /users"`},
+		{`{"GET /users": {"status": 200, "header": {}}}`, `unknown field "header"`},
+	}
+
+	for _, test := range tests {
+		_, err := LoadFixtures(writeFixtures(t, test.content))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.content, test.err, err)
+		}
+	}
+
+	if _, err := LoadFixtures(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected a missing file to fail")
+	}
+}
@@ -0,0 +1,231 @@
+package httpmock
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"unicode/utf8"
+)
+
+var updateGoldenFlag = flag.Bool("httpmock.update", false, "rewrite httpmock golden files instead of comparing against them")
+
+// updatingGolden reports whether golden files should be rewritten, which is the case when either
+// -httpmock.update or a boolean -update flag defined by the test binary is set.
+func updatingGolden() bool {
+	if *updateGoldenFlag {
+		return true
+	}
+	if f := flag.Lookup("update"); f != nil {
+		if getter, ok := f.Value.(flag.Getter); ok {
+			update, _ := getter.Get().(bool)
+			return update
+		}
+	}
+	return false
+}
+
+// GoldenOption configures a golden file assertion.
+type GoldenOption func(*goldenConfig)
+
+type goldenConfig struct {
+	json *jsonConfig
+}
+
+// GoldenJSON compares bodies as JSON: both sides are re-encoded with sorted keys and stable
+// indentation, after applying the given options, before being compared.
+func GoldenJSON(opts ...JSONOption) GoldenOption {
+	return func(c *goldenConfig) {
+		c.json = newJSONConfig(opts)
+	}
+}
+
+// AssertRequestBodyGolden compares the body of req against the golden file at goldenPath and
+// reports any difference as a unified diff.  When the test binary is run with -update (or
+// -httpmock.update) the golden file is rewritten instead.  Binary bodies are compared byte for
+// byte, with a hex excerpt around the first difference.
+//
+// The body of req is left intact, so requests taken from the call history can be asserted
+// directly:
+// 		httpmock.AssertRequestBodyGolden(t, transport.LastCall().Request, "testdata/create.golden",
+// 			httpmock.GoldenJSON(httpmock.IgnoreFields("createdAt")))
+func AssertRequestBodyGolden(t testing.TB, req *http.Request, goldenPath string, opts ...GoldenOption) {
+	t.Helper()
+
+	if req == nil {
+		t.Errorf("httpmock: no request to compare against %s", goldenPath)
+		return
+	}
+
+	body, err := ReplayableRequestBody(req)
+	if err != nil {
+		t.Errorf("httpmock: could not read request body: %s", err)
+		return
+	}
+
+	assertGolden(t, "request body", body, goldenPath, opts)
+}
+
+// AssertGolden calls r with req and compares the response, status line, headers and body, against
+// the golden file at goldenPath, reporting any difference as a unified diff.  As with
+// AssertRequestBodyGolden, running the test binary with -update rewrites the golden file instead.
+// The response is written in a canonical form, with headers sorted by name:
+//
+//	200 OK
+//	Content-Type: application/json
+//
+//	{"id": 1}
+//
+// The body of req is buffered, so r sees it intact and it is still there after the assertion.
+func AssertGolden(t testing.TB, r Responder, req *http.Request, goldenPath string) {
+	t.Helper()
+
+	body, err := ReplayableRequestBody(req)
+	if err != nil {
+		t.Errorf("httpmock: could not read request body: %s", err)
+		return
+	}
+	resp, err := r(req)
+	setRequestBody(req, body)
+	if err != nil {
+		t.Errorf("httpmock: responder failed: %s", err)
+		return
+	}
+	if resp == nil {
+		t.Errorf("httpmock: responder returned no response")
+		return
+	}
+
+	actual, err := canonicalResponse(resp)
+	if err != nil {
+		t.Errorf("httpmock: could not read response body: %s", err)
+		return
+	}
+	assertGolden(t, "response", actual, goldenPath, nil)
+}
+
+// canonicalResponse serializes the status line, headers, sorted by name, and body of resp.  The
+// status line is resp.Status, as the responder wrote it, or the standard one for resp.StatusCode
+// when it is empty.
+func canonicalResponse(resp *http.Response) ([]byte, error) {
+	var buf bytes.Buffer
+	if resp.Status != "" {
+		fmt.Fprintf(&buf, "%s\n", resp.Status)
+	} else {
+		fmt.Fprintf(&buf, "%d %s\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			fmt.Fprintf(&buf, "%s: %s\n", name, value)
+		}
+	}
+	buf.WriteString("\n")
+
+	if resp.Body != nil {
+		defer resp.Body.Close()
+		if _, err := io.Copy(&buf, resp.Body); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func assertGolden(t testing.TB, what string, actual []byte, goldenPath string, opts []GoldenOption) {
+	t.Helper()
+
+	cfg := &goldenConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.json != nil {
+		normalized, err := cfg.json.normalizeJSON(actual)
+		if err != nil {
+			t.Errorf("httpmock: %s is not valid JSON: %s", what, err)
+			return
+		}
+		actual = normalized
+	}
+
+	if updatingGolden() {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			t.Errorf("httpmock: could not create directory for %s: %s", goldenPath, err)
+			return
+		}
+		if err := ioutil.WriteFile(goldenPath, actual, 0644); err != nil {
+			t.Errorf("httpmock: could not update %s: %s", goldenPath, err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Errorf("httpmock: could not read golden file (run with -update to create it): %s", err)
+		return
+	}
+
+	if cfg.json != nil {
+		if normalized, err := cfg.json.normalizeJSON(expected); err == nil {
+			expected = normalized
+		}
+	}
+
+	if bytes.Equal(expected, actual) {
+		return
+	}
+
+	if isText(expected) && isText(actual) {
+		t.Errorf("httpmock: %s does not match %s:\n%s", what, goldenPath,
+			unifiedDiff(goldenPath, "actual", string(expected), string(actual)))
+		return
+	}
+	t.Errorf("httpmock: %s does not match %s:\n%s", what, goldenPath, hexDiff(expected, actual))
+}
+
+// isText reports whether data looks like text rather than binary content.
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) == -1
+}
+
+// hexDiffWindow is the number of bytes shown on each side of the first difference by hexDiff.
+const hexDiffWindow = 32
+
+// hexDiff describes where two byte slices first differ, with a hex dump of both around that
+// offset.
+func hexDiff(expected, actual []byte) string {
+	offset := 0
+	for offset < len(expected) && offset < len(actual) && expected[offset] == actual[offset] {
+		offset++
+	}
+
+	from := offset - offset%16 - 16
+	if from < 0 {
+		from = 0
+	}
+	excerpt := func(data []byte) string {
+		if from >= len(data) {
+			return "(no data)\n"
+		}
+		to := from + 2*hexDiffWindow
+		if to > len(data) {
+			to = len(data)
+		}
+		return hex.Dump(data[from:to])
+	}
+
+	return fmt.Sprintf("first difference at byte %d (golden has %d bytes, actual has %d)\n"+
+		"golden from byte %d:\n%sactual from byte %d:\n%s",
+		offset, len(expected), len(actual), from, excerpt(expected), from, excerpt(actual))
+}
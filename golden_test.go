@@ -0,0 +1,191 @@
+package httpmock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordingTB captures the failures reported through it instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertRequestBodyGolden(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", testUrl, NewStringResponder(201, ""))
+	client := &http.Client{Transport: tr}
+
+	golden := filepath.Join(t.TempDir(), "testdata", "create.golden")
+
+	post := func(body string) {
+		resp, err := client.Post(testUrl, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// create the golden file
+	*updateGoldenFlag = true
+	post(`{"name":"widget","createdAt":"2020-01-01","tags":["a","b"]}`)
+	AssertRequestBodyGolden(t, tr.LastCall().Request, golden, GoldenJSON(IgnoreFields("createdAt")))
+	*updateGoldenFlag = false
+
+	data, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "createdAt") {
+		t.Fatalf("expected ignored field to be left out of the golden file, got:\n%s", data)
+	}
+
+	// key order and ignored fields don't matter
+	post(`{"tags":["a","b"],"createdAt":"2021-06-01","name":"widget"}`)
+	AssertRequestBodyGolden(t, tr.LastCall().Request, golden, GoldenJSON(IgnoreFields("createdAt")))
+
+	// but values do
+	rec := &recordingTB{TB: t}
+	post(`{"name":"gadget","tags":["a","b"]}`)
+	AssertRequestBodyGolden(rec, tr.LastCall().Request, golden, GoldenJSON(IgnoreFields("createdAt")))
+	if len(rec.errors) != 1 {
+		t.Fatalf("expected one failure, got %d", len(rec.errors))
+	}
+	if !strings.Contains(rec.errors[0], `-  "name": "widget",`) || !strings.Contains(rec.errors[0], `+  "name": "gadget",`) {
+		t.Fatalf("expected a unified diff of the name, got:\n%s", rec.errors[0])
+	}
+
+	// the body is still readable after the assertion
+	body, err := ioutil.ReadAll(tr.LastCall().Request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"name":"gadget","tags":["a","b"]}` {
+		t.Fatalf("expected the request body to be intact, got %s", body)
+	}
+}
+
+func TestAssertRequestBodyGoldenBinary(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "binary.golden")
+	if err := ioutil.WriteFile(golden, []byte{0, 1, 2, 3, 4}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", testUrl, strings.NewReader("\x00\x01\x02\xff\x04"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &recordingTB{TB: t}
+	AssertRequestBodyGolden(rec, req, golden)
+	if len(rec.errors) != 1 {
+		t.Fatalf("expected one failure, got %d", len(rec.errors))
+	}
+	if !strings.Contains(rec.errors[0], "first difference at byte 3") {
+		t.Fatalf("expected a hex diff excerpt, got:\n%s", rec.errors[0])
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "one\ntwo\nthree\n"
+	b := "one\n2\nthree\n"
+
+	want := "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n"
+	if got := unifiedDiff("a", "b", a, b); got != want {
+		t.Fatalf("expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	if got := unifiedDiff("a", "b", a, a); got != "" {
+		t.Fatalf("expected no diff for equal inputs, got:\n%s", got)
+	}
+}
+
+func TestAssertRequestBodyGoldenJSONOptions(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "numbers.golden")
+	if err := ioutil.WriteFile(golden, []byte(`{"total": 10.0, "items": [{"id": "a", "qty": 1}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", testUrl, strings.NewReader(`{"total":1e1,"items":[{"id":"b","qty":1.0,"note":null}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	AssertRequestBodyGolden(t, req, golden, GoldenJSON(NumericallyEqual(), TreatNullAsAbsent(), IgnoreFields("items.*.id")))
+}
+
+func TestAssertGolden(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "testdata", "echo.golden")
+	echo := func(req *http.Request) (*http.Response, error) {
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		resp := NewStringResponse(201, "echo: "+string(data))
+		resp.Header.Set("X-Zeta", "z")
+		resp.Header.Add("Set-Cookie", "a=1")
+		resp.Header.Add("Set-Cookie", "b=2")
+		return resp, nil
+	}
+	newRequest := func(body string) *http.Request {
+		req, err := http.NewRequest("POST", testUrl, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	*updateGoldenFlag = true
+	AssertGolden(t, echo, newRequest("hello"), golden)
+	*updateGoldenFlag = false
+
+	data, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "201 Created\nSet-Cookie: a=1\nSet-Cookie: b=2\nX-Zeta: z\n\necho: hello"
+	if string(data) != expected {
+		t.Fatalf("expected the canonical response in the golden file, got:\n%s", data)
+	}
+
+	req := newRequest("hello")
+	AssertGolden(t, echo, req, golden)
+	if body, _ := ioutil.ReadAll(req.Body); string(body) != "hello" {
+		t.Fatalf("expected the request body to be intact, got %q", body)
+	}
+
+	rec := &recordingTB{TB: t}
+	AssertGolden(rec, echo, newRequest("bye"), golden)
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "+echo: bye") {
+		t.Fatalf("expected a diff of the body, got %q", rec.errors)
+	}
+}
+
+func TestCanonicalResponseStatus(t *testing.T) {
+	tests := []struct {
+		resp     *http.Response
+		expected string
+	}{
+		{&http.Response{StatusCode: 200, Status: "200 Alright"}, "200 Alright\n"},
+		{&http.Response{StatusCode: 404}, "404 Not Found\n"},
+	}
+	for _, test := range tests {
+		data, err := canonicalResponse(test.resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), test.expected) {
+			t.Errorf("expected the status line %q, got %q", test.expected, data)
+		}
+	}
+}
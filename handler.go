@@ -0,0 +1,99 @@
+package httpmock
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// HandlerResponder creates a Responder answering with h, so that an existing http.Handler (a
+// router from another package's tests, say) can be registered like any other responder.  The
+// handler sees the request as a server would, with RequestURI and RemoteAddr set, and runs to
+// completion against an httptest.ResponseRecorder before the response is returned: its body is
+// buffered, streaming handlers included.  Status codes set implicitly by a first Write, repeated
+// headers such as Set-Cookie, and trailers (declared through the Trailer header or set with the
+// http.TrailerPrefix) are carried over to the response.
+func HandlerResponder(h http.Handler) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		r := req.Clone(req.Context())
+		r.RequestURI = req.URL.RequestURI()
+		if r.RemoteAddr == "" {
+			r.RemoteAddr = "192.0.2.1:1234"
+		}
+
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, r)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	}
+}
+
+// ResponderHandler creates an http.Handler answering with responder, for mounting a responder in a
+// real server.  The request URL is made absolute, from the Host header and whether the connection
+// uses TLS, before it reaches the responder.  A responder failing with NoResponderFound gets a 404,
+// any other error a 502, with the error as body.
+func ResponderHandler(responder Responder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := r
+		if !r.URL.IsAbs() {
+			req = r.Clone(r.Context())
+			req.URL.Scheme = "http"
+			if r.TLS != nil {
+				req.URL.Scheme = "https"
+			}
+			req.URL.Host = r.Host
+		}
+
+		resp, err := responder(req)
+		if err == nil && resp == nil {
+			err = errors.New("httpmock: responder returned no response")
+		}
+		if err != nil {
+			status := http.StatusBadGateway
+			if errors.Is(err, NoResponderFound) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		writeResponse(w, resp)
+	})
+}
+
+// writeResponse writes resp to w, trailers included, and closes its body.
+func writeResponse(w http.ResponseWriter, resp *http.Response) {
+	if resp.Body != nil {
+		defer resp.Body.Close()
+	}
+
+	header := w.Header()
+	for key, values := range resp.Header {
+		if key == "Trailer" {
+			continue
+		}
+		header[key] = append([]string(nil), values...)
+	}
+	trailers := make([]string, 0, len(resp.Trailer))
+	for key := range resp.Trailer {
+		trailers = append(trailers, key)
+	}
+	if len(trailers) > 0 {
+		header.Set("Trailer", strings.Join(trailers, ", "))
+	}
+
+	status := resp.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+
+	if resp.Body != nil {
+		io.Copy(w, resp.Body)
+	}
+	for key, values := range resp.Trailer {
+		header[key] = append([]string(nil), values...)
+	}
+}
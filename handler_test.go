@@ -0,0 +1,79 @@
+package httpmock
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerResponderRoundTrip(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/items" || r.RequestURI != "/items?id=1" {
+			http.Error(w, "unexpected request "+r.RequestURI, 400)
+			return
+		}
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		// no explicit WriteHeader: the first Write implies a 200
+		io.WriteString(w, "item 1")
+		w.Header().Set("X-Checksum", "abc")
+		w.Header().Set(http.TrailerPrefix+"X-Late", "late")
+	})
+
+	server := httptest.NewServer(ResponderHandler(HandlerResponder(handler)))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/items?id=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || string(data) != "item 1" {
+		t.Fatalf("expected a 200 with the body of the handler, got %d %q", resp.StatusCode, data)
+	}
+	if cookies := resp.Header.Values("Set-Cookie"); len(cookies) != 2 {
+		t.Fatalf("expected both cookies, got %q", cookies)
+	}
+	if resp.Trailer.Get("X-Checksum") != "abc" || resp.Trailer.Get("X-Late") != "late" {
+		t.Fatalf("expected the trailers to survive, got %v", resp.Trailer)
+	}
+}
+
+func TestHandlerResponderThroughTransport(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, HandlerResponder(http.NotFoundHandler()))
+
+	resp, err := (&http.Client{Transport: tr}).Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 404 {
+		t.Fatalf("expected the status of the handler, got %d", resp.StatusCode)
+	}
+}
+
+func TestResponderHandlerErrors(t *testing.T) {
+	tests := []struct {
+		responder Responder
+		status    int
+	}{
+		{ConnectionFailure, 404},
+		{NewConnRefusedResponder(), 502},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		ResponderHandler(test.responder).ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+		if recorder.Code != test.status {
+			t.Errorf("expected %d, got %d", test.status, recorder.Code)
+		}
+	}
+}
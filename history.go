@@ -0,0 +1,224 @@
+package httpmock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// Call is a request handled by a MockTransport, as recorded in its history.
+type Call struct {
+	// Index is the position of the call in the history, starting at 0.
+	Index int
+	// Time is when the transport received the request, according to the package Clock.
+	Time time.Time
+	// Key is the "METHOD URL" of the registration that answered, or empty if none did.
+	Key string
+	// Request is a copy of the request whose body can be read any number of times.
+	Request *http.Request
+	// Body holds the request body.
+	Body []byte
+	// BodyRead is the number of bytes read from the body of the client, which may be less than
+	// the size of Body if it came from GetBody, see MockTransport.SetRequestBodyDrain.
+	BodyRead int64
+	// Proxy is the proxy the request was routed through, or nil if it went direct or proxies are
+	// ignored (see MockTransport.SetProxy).
+	Proxy *url.URL
+	// Bypassed is true if the request was forwarded to the real transport rather than mocked, see
+	// WithRealTransport and MockTransport.AllowPassThrough.
+	Bypassed bool
+	// Default is true if the request was answered by a default responder, see
+	// MockTransport.SetDefaultResponder.  Key is then the pattern of the default responder.
+	Default bool
+	// Profile is the host pattern of the profile applied to the request, if any, see
+	// MockTransport.SetHostProfile.  ProfileLatency is the delay it added, and ProfileFailed whether
+	// it made the request fail.
+	Profile        string
+	ProfileLatency time.Duration
+	ProfileFailed  bool
+	// ChaosLatency is the delay chaos mode added to the request, and ChaosFailed whether it made
+	// the request fail, see MockTransport.EnableChaos.
+	ChaosLatency time.Duration
+	ChaosFailed  bool
+	// Responded is when the response, or the error, came back from the responder, according to
+	// the package Clock.  BodyDone is when the body of the response was read up to io.EOF, or zero
+	// if it wasn't.  Canceled is true if the request was canceled before either.  They are only
+	// filled in for the calls answered by the mock, see Call.Latency and MockTransport.LatencyStats.
+	Responded time.Time
+	BodyDone  time.Time
+	Canceled  bool
+	// StatusCode and ResponseHeader describe the response the call got, as returned by the
+	// responder, and Err the error it failed with instead.  ResponseBody holds the beginning of
+	// what the client read of the response body, up to the limit set with
+	// MockTransport.SetResponseCapture, and ResponseSize how many bytes it read in all.  Like
+	// Responded, they are only filled in for the calls answered by the mock, see
+	// MockTransport.ExportTimeline.
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	ResponseSize   int64
+	Err            error
+
+	// responseSum is the SHA-256 of everything the client read of the response body.
+	responseSum hash.Hash
+}
+
+// History returns every call made through the MockTransport since it was created or last reset,
+// in the order they were received.
+func (m *MockTransport) History() []*Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Call(nil), m.history...)
+}
+
+// LastCall returns the most recent call made through the MockTransport, or nil if there is none.
+func (m *MockTransport) LastCall() *Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.history) == 0 {
+		return nil
+	}
+	return m.history[len(m.history)-1]
+}
+
+// CallCounts returns how many calls each registration answered since the MockTransport was created
+// or last reset, keyed by "METHOD URL", along with those answered by each default responder, keyed
+// by its pattern (see SetDefaultResponder).
+func (m *MockTransport) CallCounts() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, call := range m.history {
+		if call.Key != "" {
+			counts[call.Key]++
+		}
+	}
+	return counts
+}
+
+// record appends a call for req to the history.
+func (m *MockTransport) record(req *http.Request, key string, body []byte) *Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	call := &Call{
+		Index:   len(m.history),
+		Time:    now(),
+		Key:     key,
+		Request: req.Clone(req.Context()),
+		Body:    body,
+		Proxy:   RequestProxy(req),
+	}
+	setRequestBody(call.Request, body)
+	m.history = append(m.history, call)
+	m.notifyCalls()
+	return call
+}
+
+// ErrTransportReset is returned by WaitForCallCount when the MockTransport is reset while waiting.
+var ErrTransportReset = errors.New("httpmock: transport reset while waiting for calls")
+
+// WaitForCallCount blocks until the registration for key ("METHOD URL") has answered n calls since
+// the MockTransport was created or last reset, for code under test making its calls from other
+// goroutines.  It returns the context's error if ctx is done first, and ErrTransportReset if the
+// transport is reset in the meantime.
+func (m *MockTransport) WaitForCallCount(ctx context.Context, key string, n int) error {
+	m.mu.Lock()
+	resets := m.resets
+	for {
+		if m.resets != resets {
+			m.mu.Unlock()
+			return ErrTransportReset
+		}
+
+		count := 0
+		for _, call := range m.history {
+			if call.Key == key {
+				count++
+			}
+		}
+		if count >= n {
+			m.mu.Unlock()
+			return nil
+		}
+
+		if m.callsChanged == nil {
+			m.callsChanged = make(chan struct{})
+		}
+		changed := m.callsChanged
+		m.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		m.mu.Lock()
+	}
+}
+
+// AwaitCallCount is like WaitForCallCount, but gives up after timeout and reports the failure
+// through t, with the number of calls answered so far.
+func (m *MockTransport) AwaitCallCount(t testing.TB, key string, n int, timeout time.Duration) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := m.WaitForCallCount(ctx, key, n); err != nil {
+		t.Errorf("httpmock: waiting for %d calls to %s: %s (got %d)", n, key, err, m.CallCounts()[key])
+	}
+}
+
+// notifyCalls wakes up whoever waits for the history to change.  m.mu must be held.
+func (m *MockTransport) notifyCalls() {
+	if m.callsChanged != nil {
+		close(m.callsChanged)
+		m.callsChanged = nil
+	}
+}
+
+// ReplayableRequestBody reads the whole body of req and puts an identical, bytes-backed one back,
+// along with a GetBody returning fresh copies of it and a matching ContentLength.  The request can
+// then be read again, and the http.Client can replay it when retrying or following a 307 or 308
+// redirect.  Responders and matchers reading the body should go through it rather than read
+// req.Body directly.  A request without a body gets nil.
+func ReplayableRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, err := ioutil.ReadAll(body)
+			body.Close()
+			if err == nil {
+				setRequestBody(req, data)
+				return data, nil
+			}
+		}
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	setRequestBody(req, data)
+	return data, err
+}
+
+// setRequestBody makes data the body of req, readable again through GetBody.
+func setRequestBody(req *http.Request, data []byte) {
+	if data == nil {
+		return
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.ContentLength = int64(len(data))
+}
@@ -0,0 +1,189 @@
+package httpmock
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMockTransportHistory(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	tr.RegisterResponder("POST", testUrl, func(req *http.Request) (*http.Response, error) {
+		// consuming the body here must not affect the history
+		ioutil.ReadAll(req.Body)
+		return NewStringResponse(200, ""), nil
+	})
+
+	if tr.LastCall() != nil {
+		t.Fatal("expected no calls yet")
+	}
+
+	if _, err := client.Post(testUrl+"?a=b", "text/plain", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(testUrl + "missing"); err == nil {
+		t.Fatal("expected an error for the unmatched request")
+	}
+
+	history := tr.History()
+	if len(history) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(history))
+	}
+	if history[0].Key != "POST "+testUrl || string(history[0].Body) != "hello" {
+		t.Fatalf("unexpected first call: %+v", history[0])
+	}
+	if history[1].Key != "" || history[1].Index != 1 {
+		t.Fatalf("expected the second call to be unmatched, got %+v", history[1])
+	}
+
+	for i := 0; i < 2; i++ {
+		body, err := ioutil.ReadAll(history[0].Request.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "hello" {
+			t.Fatalf("expected the recorded body to be readable, got %q", body)
+		}
+		history[0].Request.Body, _ = history[0].Request.GetBody()
+	}
+
+	tr.Reset()
+	if len(tr.History()) != 0 {
+		t.Fatal("expected Reset to clear the history")
+	}
+}
+
+func TestReplayableRequestBody(t *testing.T) {
+	// a reader of an unknown type leaves the request without GetBody
+	req, err := http.NewRequest("POST", testUrl, struct{ io.Reader }{strings.NewReader("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.GetBody != nil {
+		t.Fatal("expected no GetBody to start with")
+	}
+
+	for i := 0; i < 2; i++ {
+		body, err := ReplayableRequestBody(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "hello" {
+			t.Fatalf("expected the body to be readable again, got %q", body)
+		}
+	}
+	if req.ContentLength != 5 || req.GetBody == nil {
+		t.Fatalf("expected ContentLength and GetBody to be set, got %d", req.ContentLength)
+	}
+	if body, _ := ioutil.ReadAll(req.Body); string(body) != "hello" {
+		t.Fatalf("expected the body to be put back, got %q", body)
+	}
+}
+
+func TestRedirectReplaysRequestBody(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	tr.RegisterResponder("POST", testUrl+"old", func(req *http.Request) (*http.Response, error) {
+		// consuming the body here must not prevent the client from sending it again
+		ioutil.ReadAll(req.Body)
+		return NewRedirectResponder(http.StatusTemporaryRedirect, testUrl+"new")(req)
+	})
+	var received string
+	tr.RegisterResponder("POST", testUrl+"new", func(req *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		received = string(body)
+		return NewStringResponse(200, ""), nil
+	})
+
+	// the client replays the body through the GetBody that http.NewRequest gave the request
+	resp, err := client.Post(testUrl+"old", "application/json", strings.NewReader(`{"id": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected the redirect to be followed, got %d", resp.StatusCode)
+	}
+	if received != `{"id": 1}` {
+		t.Fatalf("expected the redirected request to carry the same body, got %q", received)
+	}
+
+	// as with a real transport, a body the client can't replay stops at the redirect: the request
+	// of the client is left alone, without a GetBody made up for it
+	req, err := http.NewRequest("POST", testUrl+"old", struct{ io.Reader }{strings.NewReader(`{"id": 2}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err = client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusTemporaryRedirect || req.GetBody != nil || resp.Request != req {
+		t.Fatalf("expected the redirect returned for the untouched request, got %d", resp.StatusCode)
+	}
+}
+
+func TestWaitForCallCount(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", testUrl, NewStringResponder(202, ""))
+	client := &http.Client{Transport: tr}
+
+	// the worker keeps sending after the code under test returned, and only once the wait started
+	go func() {
+		waitForCallWaiter(tr)
+		for i := 0; i < 3; i++ {
+			client.Post(testUrl, "text/plain", nil)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tr.WaitForCallCount(ctx, "POST "+testUrl, 3); err != nil {
+		t.Fatal(err)
+	}
+	if n := tr.CallCounts()["POST "+testUrl]; n != 3 {
+		t.Fatalf("expected 3 calls once the wait is over, got %d", n)
+	}
+	tr.AwaitCallCount(t, "POST "+testUrl, 3, time.Second)
+
+	expired, cancelExpired := context.WithCancel(context.Background())
+	cancelExpired()
+	if err := tr.WaitForCallCount(expired, "POST "+testUrl, 4); err != context.Canceled {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+}
+
+// waitForCallWaiter returns once a WaitForCallCount is parked on tr.
+func waitForCallWaiter(tr *MockTransport) {
+	for {
+		tr.mu.Lock()
+		waiting := tr.callsChanged != nil
+		tr.mu.Unlock()
+		if waiting {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestWaitForCallCountReset(t *testing.T) {
+	tr := NewMockTransport()
+
+	errs := make(chan error)
+	go func() {
+		errs <- tr.WaitForCallCount(context.Background(), "GET "+testUrl, 1)
+	}()
+
+	waitForCallWaiter(tr)
+	tr.Reset()
+
+	if err := <-errs; err != ErrTransportReset {
+		t.Fatalf("expected ErrTransportReset, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package httpmock
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// hostMatches reports whether u targets the host described by pattern.  A pattern is a host name,
+// compared case insensitively, optionally followed by a port that must then match too.  A pattern
+// starting with "*." matches every subdomain of the rest of the pattern, but not the domain
+// itself.  A lone "*" matches every host.
+func hostMatches(pattern string, u *url.URL) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	host, port := pattern, ""
+	if h, p, err := net.SplitHostPort(pattern); err == nil {
+		host, port = h, p
+	}
+	if port != "" && port != urlPort(u) {
+		return false
+	}
+
+	hostname := strings.ToLower(u.Hostname())
+	host = strings.ToLower(host)
+	if strings.HasPrefix(host, "*.") {
+		return strings.HasSuffix(hostname, host[1:])
+	}
+	return hostname == host
+}
+
+// RequireHost creates a Responder delegating to inner the requests whose host is expected, and
+// failing the others with an error naming both hosts, so that a client built with the wrong base
+// URL fails right away instead of being answered as if it were right.  The host is taken from the
+// URL of the request, or from its Host field when the URL has none, and hosts are compared case
+// insensitively, the default port of the scheme being the same as no port.
+func RequireHost(expected string, inner Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		host := req.URL.Host
+		if host == "" {
+			host = req.Host
+		}
+		u := &url.URL{Scheme: req.URL.Scheme, Host: host}
+		want := &url.URL{Scheme: req.URL.Scheme, Host: expected}
+		if !strings.EqualFold(u.Hostname(), want.Hostname()) || urlPort(u) != urlPort(want) {
+			return nil, fmt.Errorf("httpmock: %s %s hit host %s, expected %s", req.Method, req.URL, host, expected)
+		}
+		return inner(req)
+	}
+}
+
+// urlPort returns the port of u, defaulting to the one of its scheme.
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
@@ -0,0 +1,33 @@
+package httpmock
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRequireHost(t *testing.T) {
+	responder := RequireHost("LocalHost", NewStringResponder(200, "ok"))
+
+	for _, target := range []string{"http://localhost/api", "http://LOCALHOST:80/api", "https://localhost:443/api"} {
+		req, _ := http.NewRequest("GET", target, nil)
+		if resp, err := responder(req); err != nil || resp.StatusCode != 200 {
+			t.Errorf("%s: expected the request to be delegated, got %v", target, err)
+		}
+	}
+
+	for _, target := range []string{"http://api.example.com/api", "http://localhost:8080/api", "https://localhost:80/api"} {
+		req, _ := http.NewRequest("GET", target, nil)
+		_, err := responder(req)
+		if err == nil || !strings.Contains(err.Error(), "expected LocalHost") {
+			t.Errorf("%s: expected a wrong host error, got %v", target, err)
+		}
+	}
+
+	// without host in the URL, as for server side requests, the Host field is used
+	req, _ := http.NewRequest("GET", "/api", nil)
+	req.Host = "localhost"
+	if _, err := responder(req); err != nil {
+		t.Errorf("expected the Host field to be used, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package httpmock
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+)
+
+// ContinueMode is how a registration answers requests sent with "Expect: 100-continue".
+type ContinueMode int
+
+const (
+	// ContinueAccept plays the interim 100 Continue response, reads the whole request body and
+	// then delivers the final response of the responder.
+	ContinueAccept ContinueMode = iota
+	// ContinueReject answers with 417 Expectation Failed without reading the request body or
+	// calling the responder.
+	ContinueReject
+	// ContinueSkip delivers the final response of the responder straight away, without an interim
+	// response, as a server refusing an upload with e.g. a 401 or a 413 would.
+	ContinueSkip
+)
+
+func (c ContinueMode) String() string {
+	switch c {
+	case ContinueAccept:
+		return "accept"
+	case ContinueReject:
+		return "reject"
+	case ContinueSkip:
+		return "skip"
+	}
+	return fmt.Sprintf("ContinueMode(%d)", int(c))
+}
+
+// WithExpectContinue makes a registration answer requests sent with "Expect: 100-continue"
+// according to mode.  Interim responses are delivered through the Got1xxResponse and
+// Got100Continue callbacks of any httptrace.ClientTrace attached to the request context.  Requests
+// without the header are not affected.
+//
+// As with NewExpectContinueResponder, MockTransport has already buffered the request body for its
+// call history when the registration runs, so what "unread" means here is that neither the mode
+// nor the responder consumes it.
+func WithExpectContinue(mode ContinueMode) RegisterOption {
+	return func(r *registration) {
+		r.continueMode = &mode
+	}
+}
+
+// WithContinueHook registers hook to be called with the mode taken each time a registration set
+// up with WithExpectContinue answers a request sent with "Expect: 100-continue".
+func WithContinueHook(hook func(req *http.Request, mode ContinueMode)) RegisterOption {
+	return func(r *registration) {
+		r.continueHook = hook
+	}
+}
+
+// WithEarlyHints makes a registration deliver a 103 Early Hints interim response carrying header
+// before its final response, through the Got1xxResponse callback of any httptrace.ClientTrace
+// attached to the request context.
+func WithEarlyHints(header http.Header) RegisterOption {
+	return func(r *registration) {
+		r.earlyHints = header
+	}
+}
+
+// interim wraps the responder of r so that it delivers the interim responses r is configured for.
+func (r *registration) interim(inner Responder) Responder {
+	if r.continueMode == nil && r.earlyHints == nil {
+		return inner
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		if r.continueMode != nil && expectsContinue(req) {
+			mode := *r.continueMode
+			if r.continueHook != nil {
+				r.continueHook(req, mode)
+			}
+			switch mode {
+			case ContinueReject:
+				return NewStringResponse(http.StatusExpectationFailed, ""), nil
+			case ContinueAccept:
+				if err := sendContinue(req); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if r.earlyHints != nil {
+			if err := send1xx(req, http.StatusEarlyHints, r.earlyHints); err != nil {
+				return nil, err
+			}
+		}
+		return inner(req)
+	}
+}
+
+// expectsContinue reports whether req waits for a 100 Continue before sending its body.
+func expectsContinue(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Expect"), "100-continue")
+}
+
+// send1xx delivers an interim response to the httptrace.ClientTrace of req, if any.
+func send1xx(req *http.Request, code int, header http.Header) error {
+	trace := httptrace.ContextClientTrace(req.Context())
+	if trace == nil || trace.Got1xxResponse == nil {
+		return nil
+	}
+	return trace.Got1xxResponse(code, textproto.MIMEHeader(header.Clone()))
+}
+
+// sendContinue delivers a 100 Continue to the httptrace.ClientTrace of req, then reads the whole
+// request body as the client would now send it.
+func sendContinue(req *http.Request) error {
+	if err := send1xx(req, http.StatusContinue, http.Header{}); err != nil {
+		return err
+	}
+	if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.Got100Continue != nil {
+		trace.Got100Continue()
+	}
+
+	// ReplayableRequestBody sets ContentLength to what it read, so keep the length the client declared
+	declared := req.ContentLength
+	body, err := ReplayableRequestBody(req)
+	if err != nil {
+		return fmt.Errorf("httpmock: could not read request body after 100 Continue: %s", err)
+	}
+	if declared > 0 && int64(len(body)) != declared {
+		return fmt.Errorf("httpmock: request body has %d bytes after 100 Continue, expected %d", len(body), declared)
+	}
+	return nil
+}
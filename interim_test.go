@@ -0,0 +1,143 @@
+package httpmock
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWithExpectContinue(t *testing.T) {
+	tests := []struct {
+		mode     ContinueMode
+		status   int
+		interims []int
+		called   bool
+	}{
+		{ContinueAccept, 201, []int{100}, true},
+		{ContinueReject, 417, nil, false},
+		{ContinueSkip, 201, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode.String(), func(t *testing.T) {
+			var modes []ContinueMode
+			called := false
+			tr := NewMockTransport()
+			tr.RegisterResponder("PUT", testUrl, func(req *http.Request) (*http.Response, error) {
+				called = true
+				return NewStringResponse(201, ""), nil
+			}, WithExpectContinue(test.mode), WithContinueHook(func(req *http.Request, mode ContinueMode) {
+				modes = append(modes, mode)
+			}))
+			client := &http.Client{Transport: tr}
+
+			var interims []int
+			trace := &httptrace.ClientTrace{
+				Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+					interims = append(interims, code)
+					return nil
+				},
+			}
+			req, _ := http.NewRequest("PUT", testUrl, strings.NewReader("upload"))
+			req.Header.Set("Expect", "100-continue")
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.status {
+				t.Errorf("expected status %d, got %d", test.status, resp.StatusCode)
+			}
+			if len(interims) != len(test.interims) || (len(interims) > 0 && interims[0] != test.interims[0]) {
+				t.Errorf("expected interim responses %v, got %v", test.interims, interims)
+			}
+			if called != test.called {
+				t.Errorf("expected the responder to be called: %v, got %v", test.called, called)
+			}
+			if len(modes) != 1 || modes[0] != test.mode {
+				t.Errorf("expected the hook to see %v, got %v", test.mode, modes)
+			}
+
+			modes = nil
+			if _, err := client.Post(testUrl, "text/plain", strings.NewReader("upload")); err == nil {
+				t.Errorf("expected the registration to be for PUT only")
+			}
+			req, _ = http.NewRequest("PUT", testUrl, strings.NewReader("upload"))
+			if resp, err := client.Do(req); err != nil || resp.StatusCode != 201 || len(modes) != 0 {
+				t.Errorf("expected requests without the header to go straight through, got %v %v %v", resp, err, modes)
+			}
+		})
+	}
+}
+
+func TestWithExpectContinueShortBody(t *testing.T) {
+	called := false
+	tr := NewMockTransport()
+	tr.RegisterResponder("PUT", testUrl, func(req *http.Request) (*http.Response, error) {
+		called = true
+		return NewStringResponse(201, ""), nil
+	}, WithExpectContinue(ContinueAccept))
+
+	bodies := map[string]io.Reader{
+		// a body shorter than the declared ContentLength never arrived in full
+		"short": strings.NewReader("upl"),
+		// and neither did one cut off by a read error
+		"truncated": io.MultiReader(strings.NewReader("upl"), iotest.ErrReader(io.ErrUnexpectedEOF)),
+	}
+	for name, body := range bodies {
+		called = false
+		req, _ := http.NewRequest("PUT", testUrl, ioutil.NopCloser(body))
+		req.ContentLength = 10
+		req.Header.Set("Expect", "100-continue")
+		if resp, err := tr.RoundTrip(req); err == nil {
+			t.Errorf("%s: expected the body to fail the request, got %v", name, resp)
+		}
+		if called {
+			t.Errorf("%s: expected the responder not to be called", name)
+		}
+	}
+
+	req, _ := http.NewRequest("PUT", testUrl, strings.NewReader("upload"))
+	req.Header.Set("Expect", "100-continue")
+	if resp, err := tr.RoundTrip(req); err != nil || resp.StatusCode != 201 || !called {
+		t.Errorf("expected a complete body to go through, got %v %v", resp, err)
+	}
+}
+
+func TestWithEarlyHints(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "page"), WithEarlyHints(http.Header{
+		"Link": {"</style.css>; rel=preload; as=style"},
+	}))
+	client := &http.Client{Transport: tr}
+
+	var got []textproto.MIMEHeader
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code != http.StatusEarlyHints {
+				t.Errorf("expected a 103, got %d", code)
+			}
+			got = append(got, header)
+			return nil
+		},
+	}
+	req, _ := http.NewRequest("GET", testUrl, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected the final response, got %d", resp.StatusCode)
+	}
+	if len(got) != 1 || got[0].Get("Link") != "</style.css>; rel=preload; as=style" {
+		t.Fatalf("expected the early hints, got %v", got)
+	}
+}
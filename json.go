@@ -0,0 +1,230 @@
+package httpmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONOption configures how JSON bodies are normalized before they are compared, by MatchBodyJSON
+// and by golden file assertions using GoldenJSON.
+type JSONOption func(*jsonConfig)
+
+type jsonConfig struct {
+	ignore       [][]string
+	numeric      bool
+	nullAsAbsent bool
+}
+
+// IgnoreFields removes the fields at the given dotted paths (e.g. "meta.requestId") before
+// comparison, which is handy for timestamps and generated ids.  A "*" segment matches every field
+// of an object or every element of an array, as in "items.*.id", and a numeric segment matches
+// the element of an array at that index.
+func IgnoreFields(paths ...string) JSONOption {
+	return func(c *jsonConfig) {
+		for _, path := range paths {
+			c.ignore = append(c.ignore, strings.Split(path, "."))
+		}
+	}
+}
+
+// NumericallyEqual compares numbers by value rather than by how they are written, so that 1, 1.0
+// and 1e0 are equal.
+func NumericallyEqual() JSONOption {
+	return func(c *jsonConfig) {
+		c.numeric = true
+	}
+}
+
+// TreatNullAsAbsent considers object fields set to null to be missing.
+func TreatNullAsAbsent() JSONOption {
+	return func(c *jsonConfig) {
+		c.nullAsAbsent = true
+	}
+}
+
+func newJSONConfig(opts []JSONOption) *jsonConfig {
+	c := &jsonConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// decodeJSON decodes data, keeping numbers as written, and applies the configured options.
+func (c *jsonConfig) decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return c.normalize(v), nil
+}
+
+// normalize applies the configured options to a decoded JSON value.
+func (c *jsonConfig) normalize(v interface{}) interface{} {
+	for _, path := range c.ignore {
+		v = removeJSONPath(v, path)
+	}
+	if c.numeric || c.nullAsAbsent {
+		v = c.rewrite(v)
+	}
+	return v
+}
+
+// rewrite drops null fields and canonicalizes numbers, as configured.
+func (c *jsonConfig) rewrite(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if child == nil && c.nullAsAbsent {
+				delete(node, key)
+				continue
+			}
+			node[key] = c.rewrite(child)
+		}
+	case []interface{}:
+		for i, child := range node {
+			node[i] = c.rewrite(child)
+		}
+	case json.Number:
+		if c.numeric {
+			if f, ok := new(big.Float).SetPrec(256).SetString(string(node)); ok {
+				return json.Number(f.Text('g', -1))
+			}
+		}
+	}
+	return v
+}
+
+// normalizeJSON re-encodes data with sorted keys and stable indentation, after applying the
+// configured options.
+func (c *jsonConfig) normalizeJSON(data []byte) ([]byte, error) {
+	v, err := c.decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func removeJSONPath(v interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return v
+	}
+	head, rest := path[0], path[1:]
+
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if head != "*" && head != key {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(node, key)
+			} else {
+				node[key] = removeJSONPath(child, rest)
+			}
+		}
+		return node
+	case []interface{}:
+		index, err := strconv.Atoi(head)
+		if head != "*" && err != nil {
+			return node
+		}
+		kept := node[:0]
+		for i, child := range node {
+			if head != "*" && i != index {
+				kept = append(kept, child)
+				continue
+			}
+			if len(rest) > 0 {
+				kept = append(kept, removeJSONPath(child, rest))
+			}
+		}
+		return kept
+	}
+	return v
+}
+
+// jsonDiff lists the paths at which two normalized JSON values differ.
+func jsonDiff(path string, expected, actual interface{}) []string {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(e)+len(a))
+		for key := range e {
+			keys = append(keys, key)
+		}
+		for key := range a {
+			if _, ok := e[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		var diffs []string
+		for _, key := range keys {
+			child := path + "." + key
+			ev, inExpected := e[key]
+			av, inActual := a[key]
+			switch {
+			case !inActual:
+				diffs = append(diffs, fmt.Sprintf("%s: missing, expected %s", child, encodeJSONValue(ev)))
+			case !inExpected:
+				diffs = append(diffs, fmt.Sprintf("%s: unexpected %s", child, encodeJSONValue(av)))
+			default:
+				diffs = append(diffs, jsonDiff(child, ev, av)...)
+			}
+		}
+		return diffs
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			break
+		}
+		var diffs []string
+		for i := 0; i < len(e) || i < len(a); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(a):
+				diffs = append(diffs, fmt.Sprintf("%s: missing, expected %s", child, encodeJSONValue(e[i])))
+			case i >= len(e):
+				diffs = append(diffs, fmt.Sprintf("%s: unexpected %s", child, encodeJSONValue(a[i])))
+			default:
+				diffs = append(diffs, jsonDiff(child, e[i], a[i])...)
+			}
+		}
+		return diffs
+	}
+
+	if reflect.DeepEqual(expected, actual) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: expected %s, got %s", path, encodeJSONValue(expected), encodeJSONValue(actual))}
+}
+
+func encodeJSONValue(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(encoded)
+}
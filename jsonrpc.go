@@ -0,0 +1,137 @@
+package httpmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+)
+
+// JSONRPCError is a JSON-RPC 2.0 error object.  It implements error so that handlers can return
+// it directly.
+type JSONRPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+type jsonrpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type jsonrpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+var jsonrpcNullID = json.RawMessage("null")
+
+// NewJSONRPCResponder creates a Responder that speaks JSON-RPC 2.0.  The request body is parsed as
+// either a single call or a batch, each call is dispatched to the handler registered for its
+// method, and the results are assembled into a single or batched response with matching ids.
+//
+// The value returned by a handler is encoded as the call's result.  Returning a *JSONRPCError lets
+// the handler choose the error code, any other error is reported as an internal error.  Calls to
+// unknown methods yield a -32601 error object.  Notifications (calls without an id) are
+// dispatched but produce no response entry; a request made only of notifications gets a 204.
+func NewJSONRPCResponder(handlers map[string]func(params json.RawMessage) (interface{}, error)) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		body, err := ReplayableRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+
+		body = bytes.TrimSpace(body)
+		if len(body) == 0 || body[0] != '[' {
+			var raw json.RawMessage
+			if err := json.Unmarshal(body, &raw); err != nil {
+				return newJSONRPCResponse(jsonrpcFailure(jsonrpcNullID, JSONRPCParseError, "Parse error"))
+			}
+			resp := dispatchJSONRPC(handlers, raw)
+			if resp == nil {
+				return NewStringResponse(http.StatusNoContent, ""), nil
+			}
+			return newJSONRPCResponse(resp)
+		}
+
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return newJSONRPCResponse(jsonrpcFailure(jsonrpcNullID, JSONRPCParseError, "Parse error"))
+		}
+		if len(batch) == 0 {
+			return newJSONRPCResponse(jsonrpcFailure(jsonrpcNullID, JSONRPCInvalidRequest, "Invalid Request"))
+		}
+
+		responses := make([]*jsonrpcResponse, 0, len(batch))
+		for _, raw := range batch {
+			if resp := dispatchJSONRPC(handlers, raw); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			return NewStringResponse(http.StatusNoContent, ""), nil
+		}
+		return newJSONRPCResponse(responses)
+	}
+}
+
+// dispatchJSONRPC runs a single call and returns its response, or nil for a notification.
+func dispatchJSONRPC(handlers map[string]func(params json.RawMessage) (interface{}, error), raw json.RawMessage) *jsonrpcResponse {
+	var call jsonrpcRequest
+	if err := json.Unmarshal(raw, &call); err != nil || call.JSONRPC != "2.0" || call.Method == "" {
+		return jsonrpcFailure(jsonrpcNullID, JSONRPCInvalidRequest, "Invalid Request")
+	}
+
+	handler, ok := handlers[call.Method]
+	if call.ID == nil {
+		if ok {
+			handler(call.Params)
+		}
+		return nil
+	}
+	if !ok {
+		return jsonrpcFailure(call.ID, JSONRPCMethodNotFound, "Method not found")
+	}
+
+	result, err := handler(call.Params)
+	if err != nil {
+		if rpcErr, ok := err.(*JSONRPCError); ok {
+			return &jsonrpcResponse{JSONRPC: "2.0", Error: rpcErr, ID: call.ID}
+		}
+		return jsonrpcFailure(call.ID, JSONRPCInternalError, err.Error())
+	}
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	return &jsonrpcResponse{JSONRPC: "2.0", Result: result, ID: call.ID}
+}
+
+func jsonrpcFailure(id json.RawMessage, code int, message string) *jsonrpcResponse {
+	return &jsonrpcResponse{
+		JSONRPC: "2.0",
+		Error:   &JSONRPCError{Code: code, Message: message},
+		ID:      id,
+	}
+}
+
+func newJSONRPCResponse(body interface{}) (*http.Response, error) {
+	return NewJsonResponse(http.StatusOK, body)
+}
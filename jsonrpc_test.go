@@ -0,0 +1,130 @@
+package httpmock
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func jsonrpcCall(t *testing.T, responder Responder, body string) (*http.Response, string) {
+	req, err := http.NewRequest("POST", testUrl, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := responder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(data)
+}
+
+func TestJSONRPCResponder(t *testing.T) {
+	responder := NewJSONRPCResponder(map[string]func(json.RawMessage) (interface{}, error){
+		"sum": func(params json.RawMessage) (interface{}, error) {
+			var nums []int
+			if err := json.Unmarshal(params, &nums); err != nil {
+				return nil, &JSONRPCError{Code: JSONRPCInvalidParams, Message: "Invalid params"}
+			}
+			total := 0
+			for _, n := range nums {
+				total += n
+			}
+			return total, nil
+		},
+		"fail": func(json.RawMessage) (interface{}, error) {
+			return nil, errors.New("boom")
+		},
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			name:   "single",
+			body:   `{"jsonrpc":"2.0","method":"sum","params":[1,2,3],"id":1}`,
+			status: 200,
+			want:   `{"jsonrpc":"2.0","result":6,"id":1}`,
+		},
+		{
+			name:   "batch",
+			body:   `[{"jsonrpc":"2.0","method":"sum","params":[1],"id":"a"},{"jsonrpc":"2.0","method":"nope","id":"b"},{"jsonrpc":"2.0","method":"sum","params":[1]}]`,
+			status: 200,
+			want:   `[{"jsonrpc":"2.0","result":1,"id":"a"},{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":"b"}]`,
+		},
+		{
+			name:   "handler error",
+			body:   `{"jsonrpc":"2.0","method":"fail","id":7}`,
+			status: 200,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32603,"message":"boom"},"id":7}`,
+		},
+		{
+			name:   "custom error",
+			body:   `{"jsonrpc":"2.0","method":"sum","params":{},"id":8}`,
+			status: 200,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":8}`,
+		},
+		{
+			name:   "parse error",
+			body:   `{"jsonrpc":`,
+			status: 200,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+		{
+			name:   "empty batch",
+			body:   `[]`,
+			status: 200,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+		},
+		{
+			name:   "notifications only",
+			body:   `[{"jsonrpc":"2.0","method":"sum","params":[1]}]`,
+			status: 204,
+			want:   ``,
+		},
+	}
+
+	for _, test := range tests {
+		resp, body := jsonrpcCall(t, responder, test.body)
+		if resp.StatusCode != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, resp.StatusCode)
+		}
+		if body != test.want {
+			t.Errorf("%s: expected body %s, got %s", test.name, test.want, body)
+		}
+	}
+}
+
+func TestJSONRPCResponderRestoresBody(t *testing.T) {
+	responder := NewJSONRPCResponder(map[string]func(json.RawMessage) (interface{}, error){
+		"ping": func(json.RawMessage) (interface{}, error) { return "pong", nil },
+	})
+
+	payload := `{"jsonrpc":"2.0","method":"ping","id":1}`
+	req, err := http.NewRequest("POST", testUrl, strings.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := responder(req); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != payload {
+		t.Fatalf("expected the request body to be put back, got %q", data)
+	}
+}
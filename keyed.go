@@ -0,0 +1,154 @@
+package httpmock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// KeyedResponder routes the requests of a single registration to one of several responders,
+// based on a key extracted from each request.  It is created with NewKeyedResponder and
+// registered through its Respond method:
+//
+//	keyed := httpmock.NewKeyedResponder(httpmock.JSONFieldKey("type"), map[string]httpmock.Responder{
+//		"create": httpmock.NewStringResponder(201, ""),
+//		"delete": httpmock.NewStringResponder(204, ""),
+//	}, nil)
+//	httpmock.RegisterResponder("POST", "https://api.mybiz.com/events", keyed.Respond)
+type KeyedResponder struct {
+	keyFn    func(*http.Request) (string, error)
+	routes   map[string]Responder
+	fallback Responder
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewKeyedResponder creates a KeyedResponder.  keyFn is given a request whose body it can read
+// freely, the body is restored before the request reaches the responder.  Requests whose key has
+// no route go to fallback, or fail with an error listing the known keys if fallback is nil.
+func NewKeyedResponder(keyFn func(*http.Request) (string, error), routes map[string]Responder, fallback Responder) *KeyedResponder {
+	return &KeyedResponder{
+		keyFn:    keyFn,
+		routes:   routes,
+		fallback: fallback,
+		counts:   make(map[string]int),
+	}
+}
+
+// Respond is the Responder of the KeyedResponder.
+func (k *KeyedResponder) Respond(req *http.Request) (*http.Response, error) {
+	body, err := ReplayableRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := k.keyFn(req)
+	setRequestBody(req, body)
+	if err != nil {
+		return nil, err
+	}
+
+	responder, ok := k.routes[key]
+	if !ok {
+		if k.fallback == nil {
+			return nil, fmt.Errorf("httpmock: no route for key %q (known keys: %s)", key, strings.Join(k.knownKeys(), ", "))
+		}
+		responder = k.fallback
+	}
+
+	k.mu.Lock()
+	k.counts[key]++
+	k.mu.Unlock()
+
+	return responder(req)
+}
+
+// RouteCounts returns how many requests were answered for each key, including those answered by
+// the fallback.
+func (k *KeyedResponder) RouteCounts() map[string]int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	counts := make(map[string]int, len(k.counts))
+	for key, n := range k.counts {
+		counts[key] = n
+	}
+	return counts
+}
+
+func (k *KeyedResponder) knownKeys() []string {
+	keys := make([]string, 0, len(k.routes))
+	for key := range k.routes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// BodySHA256Key is a key function for NewKeyedResponder returning the hex encoded SHA-256 of the
+// request body.
+func BodySHA256Key(req *http.Request) (string, error) {
+	body, err := ReplayableRequestBody(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// JSONFieldKey is a key function for NewKeyedResponder returning the value of the field at the
+// given dotted path (e.g. "order.items.0.sku") of a JSON request body.  Strings are returned as
+// is, other values in their JSON encoding.
+func JSONFieldKey(path string) func(*http.Request) (string, error) {
+	return func(req *http.Request) (string, error) {
+		body, err := ReplayableRequestBody(req)
+		if err != nil {
+			return "", err
+		}
+
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return "", fmt.Errorf("httpmock: request body is not valid JSON: %s", err)
+		}
+
+		for _, part := range strings.Split(path, ".") {
+			switch node := v.(type) {
+			case map[string]interface{}:
+				child, ok := node[part]
+				if !ok {
+					return "", fmt.Errorf("httpmock: request body has no field %q", path)
+				}
+				v = child
+			case []interface{}:
+				i, err := strconv.Atoi(part)
+				if err != nil || i < 0 || i >= len(node) {
+					return "", fmt.Errorf("httpmock: request body has no field %q", path)
+				}
+				v = node[i]
+			default:
+				return "", fmt.Errorf("httpmock: request body has no field %q", path)
+			}
+		}
+
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	}
+}
+
+// QueryParamKey is a key function for NewKeyedResponder returning the value of the given query
+// parameter.
+func QueryParamKey(name string) func(*http.Request) (string, error) {
+	return func(req *http.Request) (string, error) {
+		return req.URL.Query().Get(name), nil
+	}
+}
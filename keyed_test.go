@@ -0,0 +1,94 @@
+package httpmock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestKeyedResponder(t *testing.T) {
+	keyed := NewKeyedResponder(JSONFieldKey("event.type"), map[string]Responder{
+		"create": func(req *http.Request) (*http.Response, error) {
+			// the body must be intact after the key was extracted
+			data, _ := ioutil.ReadAll(req.Body)
+			return NewStringResponse(201, string(data)), nil
+		},
+		"delete": NewStringResponder(204, ""),
+	}, nil)
+
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", testUrl, keyed.Respond)
+	client := &http.Client{Transport: tr}
+
+	post := func(body string) (*http.Response, error) {
+		return client.Post(testUrl, "application/json", strings.NewReader(body))
+	}
+
+	resp, err := post(`{"event":{"type":"create"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 201 || string(data) != `{"event":{"type":"create"}}` {
+		t.Fatalf("unexpected create response: %d %s", resp.StatusCode, data)
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp, err := post(`{"event":{"type":"delete"}}`); err != nil || resp.StatusCode != 204 {
+			t.Fatalf("unexpected delete response: %v %v", resp, err)
+		}
+	}
+
+	_, err = post(`{"event":{"type":"update"}}`)
+	if err == nil || !strings.Contains(err.Error(), `no route for key "update" (known keys: create, delete)`) {
+		t.Fatalf("expected an error listing the known keys, got %v", err)
+	}
+
+	counts := keyed.RouteCounts()
+	if counts["create"] != 1 || counts["delete"] != 2 {
+		t.Fatalf("unexpected route counts: %v", counts)
+	}
+	if infos := tr.Registrations(); infos[0].Calls != 4 {
+		t.Fatalf("expected all calls counted under one registration, got %d", infos[0].Calls)
+	}
+}
+
+func TestKeyedResponderExtractors(t *testing.T) {
+	sum := sha256.Sum256([]byte("payload"))
+	bodyKeyed := NewKeyedResponder(BodySHA256Key, map[string]Responder{
+		hex.EncodeToString(sum[:]): NewStringResponder(200, "known payload"),
+	}, NewStringResponder(404, "unknown payload"))
+
+	queryKeyed := NewKeyedResponder(QueryParamKey("region"), map[string]Responder{
+		"eu": NewStringResponder(200, "eu"),
+	}, NewStringResponder(404, "elsewhere"))
+
+	tests := []struct {
+		responder *KeyedResponder
+		url       string
+		body      string
+		status    int
+	}{
+		{bodyKeyed, testUrl, "payload", 200},
+		{bodyKeyed, testUrl, "other", 404},
+		{queryKeyed, testUrl + "?region=eu", "", 200},
+		{queryKeyed, testUrl + "?region=us", "", 404},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("POST", test.url, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := test.responder.Respond(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %q: expected status %d, got %d", test.url, test.body, test.status, resp.StatusCode)
+		}
+	}
+}
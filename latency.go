@@ -0,0 +1,71 @@
+package httpmock
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewLatencyReplayResponder creates a Responder replaying a distribution of observed latencies:
+// every call waits one of the latencies listed in the CSV file at csvPath, drawn uniformly from the
+// observed values, before calling inner.  The latencies are read from the first column, in
+// milliseconds, possibly fractional; a first row that isn't a number is taken as a header.  The
+// wait is measured by the package Clock and ends early with the context's error if the request is
+// canceled.  The draws use the package source of randomness, see SetRandSource.
+func NewLatencyReplayResponder(csvPath string, inner Responder) (Responder, error) {
+	latencies, err := loadLatencies(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		latency := latencies[randInt63n(req, int64(len(latencies)))]
+		if err := sleepContext(req, latency); err != nil {
+			return nil, err
+		}
+		return inner(req)
+	}, nil
+}
+
+// loadLatencies reads the millisecond latencies of the first column of the CSV file at path.
+func loadLatencies(path string) ([]time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("httpmock: invalid latencies file %s: %s", path, err)
+	}
+
+	var latencies []time.Duration
+	for i, record := range records {
+		ms, err := strconv.ParseFloat(strings.TrimSpace(record[0]), 64)
+		if err != nil {
+			if i == 0 {
+				continue // header
+			}
+			return nil, fmt.Errorf("httpmock: invalid latency on line %d of %s: %q", i+1, path, record[0])
+		}
+		if math.IsNaN(ms) || math.IsInf(ms, 0) || ms > float64(math.MaxInt64)/float64(time.Millisecond) {
+			return nil, fmt.Errorf("httpmock: invalid latency on line %d of %s: %q", i+1, path, record[0])
+		}
+		if ms < 0 {
+			return nil, fmt.Errorf("httpmock: negative latency on line %d of %s", i+1, path)
+		}
+		latencies = append(latencies, time.Duration(ms*float64(time.Millisecond)))
+	}
+	if len(latencies) == 0 {
+		return nil, errors.New("httpmock: no latencies in " + path)
+	}
+	return latencies, nil
+}
@@ -0,0 +1,83 @@
+package httpmock
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeLatencies(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "latencies.csv")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLatencyReplayResponder(t *testing.T) {
+	SetRandSource(rand.NewSource(7))
+	defer SetRandSource(nil)
+	clock := &recordingClock{}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	responder, err := NewLatencyReplayResponder(writeLatencies(t, "latency_ms,endpoint\n12,/a\n250.5,/b\n40,/c\n"),
+		NewStringResponder(200, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[time.Duration]int{}
+	for i := 0; i < 300; i++ {
+		if _, err := responder(httptestRequest(t)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, wait := range clock.waits {
+		seen[wait]++
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected the three observed latencies, got %v", seen)
+	}
+	for _, latency := range []time.Duration{12 * time.Millisecond, 250500 * time.Microsecond, 40 * time.Millisecond} {
+		if seen[latency] < 60 {
+			t.Errorf("expected %s to be drawn about a third of the time, got %d times", latency, seen[latency])
+		}
+	}
+}
+
+func TestLatencyReplayResponderCanceled(t *testing.T) {
+	responder, err := NewLatencyReplayResponder(writeLatencies(t, "3600000\n"), NewStringResponder(200, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	cancel()
+	if _, err := responder(req); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLatencyReplayResponderErrors(t *testing.T) {
+	for _, content := range []string{"", "latency\n", "10\nfast\n", "-5\n"} {
+		if _, err := NewLatencyReplayResponder(writeLatencies(t, content), NewStringResponder(200, "")); err == nil {
+			t.Errorf("%q: expected an error", content)
+		}
+	}
+
+	// values that parse but aren't latencies are reported with their line
+	for _, value := range []string{"NaN", "Inf", "-Inf", "1e300", "-5"} {
+		_, err := NewLatencyReplayResponder(writeLatencies(t, "latency\n10\n"+value+"\n"), NewStringResponder(200, ""))
+		if err == nil || !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("%q: expected an error naming line 3, got %v", value, err)
+		}
+	}
+}
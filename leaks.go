@@ -0,0 +1,170 @@
+package httpmock
+
+import (
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// BodyTrackingOption configures the response body tracking enabled by TrackResponseBodies.
+type BodyTrackingOption func(*bodyTracker)
+
+// CaptureStacks records the stack of the request that got each response body, reported along with
+// the bodies left open to pinpoint where they came from.
+func CaptureStacks() BodyTrackingOption {
+	return func(t *bodyTracker) {
+		t.stacks = true
+	}
+}
+
+// AllowReadToEOF stops reporting the bodies that were read up to io.EOF without being closed.
+// Such bodies are reported by default: http.Client needs them closed too to reuse connections.
+func AllowReadToEOF() BodyTrackingOption {
+	return func(t *bodyTracker) {
+		t.allowEOF = true
+	}
+}
+
+// bodyTracker keeps track of the response bodies handed out by a MockTransport.
+type bodyTracker struct {
+	stacks   bool
+	allowEOF bool
+
+	mu     sync.Mutex
+	bodies []*leakTrackedBody
+}
+
+// TrackResponseBodies makes the MockTransport keep track of every response body it hands out, so
+// that AssertAllResponseBodiesClosed can report the ones the code under test forgot to close.
+// Tracking is off by default, and costs nothing until turned on.  Calling it again starts over
+// with the new options.  Reset forgets the bodies handed out so far but keeps tracking.
+func (m *MockTransport) TrackResponseBodies(opts ...BodyTrackingOption) {
+	tracker := &bodyTracker{}
+	for _, opt := range opts {
+		opt(tracker)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bodyTracker = tracker
+}
+
+// AssertAllResponseBodiesClosed reports through t every response body handed out since tracking
+// started, or the MockTransport was last reset, that was never closed, with the "METHOD URL" of
+// its call and, with CaptureStacks, where the request came from.  It fails if tracking is off, see
+// TrackResponseBodies.
+func (m *MockTransport) AssertAllResponseBodiesClosed(t testing.TB) {
+	t.Helper()
+
+	m.mu.Lock()
+	tracker := m.bodyTracker
+	m.mu.Unlock()
+	if tracker == nil {
+		t.Errorf("httpmock: response bodies are not tracked, call TrackResponseBodies first")
+		return
+	}
+	tracker.assertClosed(t)
+}
+
+func (tracker *bodyTracker) assertClosed(t testing.TB) {
+	t.Helper()
+
+	tracker.mu.Lock()
+	bodies := append([]*leakTrackedBody(nil), tracker.bodies...)
+	tracker.mu.Unlock()
+
+	var leaks []string
+	for _, body := range bodies {
+		if atomic.LoadInt32(&body.closed) == 1 || (tracker.allowEOF && atomic.LoadInt32(&body.eof) == 1) {
+			continue
+		}
+		leak := body.call
+		if atomic.LoadInt32(&body.eof) == 1 {
+			leak += " (read to EOF)"
+		}
+		if body.stack != nil {
+			leak += "\n" + string(body.stack)
+		}
+		leaks = append(leaks, leak)
+	}
+	if len(leaks) > 0 {
+		t.Errorf("httpmock: %d response bodies were never closed:\n%s", len(leaks), strings.Join(leaks, "\n"))
+	}
+}
+
+// trackResponseBody makes resp report to the tracker of the MockTransport whether its body gets
+// closed, if tracking is on.  The body of a 101 is a connection the client writes to as well, so
+// it is left alone.
+func (m *MockTransport) trackResponseBody(req *http.Request, resp *http.Response) {
+	m.mu.Lock()
+	tracker := m.bodyTracker
+	m.mu.Unlock()
+	if tracker == nil || resp.Body == nil || resp.Body == http.NoBody ||
+		resp.StatusCode == http.StatusSwitchingProtocols {
+		return
+	}
+
+	body := &leakTrackedBody{ReadCloser: resp.Body, call: req.Method + " " + req.URL.String()}
+	if tracker.stacks {
+		body.stack = debug.Stack()
+	}
+	tracker.mu.Lock()
+	tracker.bodies = append(tracker.bodies, body)
+	tracker.mu.Unlock()
+	if _, ok := resp.Body.(io.WriterTo); ok {
+		resp.Body = leakTrackedWriterToBody{body}
+		return
+	}
+	resp.Body = body
+}
+
+// reset forgets the bodies tracked so far.
+func (tracker *bodyTracker) reset() {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.bodies = nil
+}
+
+// leakTrackedBody records whether a response body was read up to io.EOF and closed.
+type leakTrackedBody struct {
+	io.ReadCloser
+	call   string
+	stack  []byte
+	eof    int32
+	closed int32
+}
+
+func (b *leakTrackedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		atomic.StoreInt32(&b.eof, 1)
+	}
+	return n, err
+}
+
+func (b *leakTrackedBody) Close() error {
+	atomic.StoreInt32(&b.closed, 1)
+	return b.ReadCloser.Close()
+}
+
+func (b *leakTrackedBody) unwrapBody() io.ReadCloser {
+	return b.ReadCloser
+}
+
+// leakTrackedWriterToBody is a leakTrackedBody keeping the io.WriterTo of the body it wraps, for
+// io.Copy to still take the fast path.
+type leakTrackedWriterToBody struct {
+	*leakTrackedBody
+}
+
+func (b leakTrackedWriterToBody) WriteTo(w io.Writer) (int64, error) {
+	n, err := b.ReadCloser.(io.WriterTo).WriteTo(w)
+	if err == nil {
+		// WriteTo returns nil once the body is over
+		atomic.StoreInt32(&b.eof, 1)
+	}
+	return n, err
+}
@@ -0,0 +1,90 @@
+package httpmock
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAssertAllResponseBodiesClosed(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "hello"))
+	tr.TrackResponseBodies(CaptureStacks())
+	client := &http.Client{Transport: tr}
+
+	get := func(url string) *http.Response {
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	get(testUrl).Body.Close()
+	read := get(testUrl + "?read")
+	if _, err := ioutil.ReadAll(read.Body); err != nil {
+		t.Fatal(err)
+	}
+	get(testUrl + "?leak")
+
+	rec := &recordingTB{TB: t}
+	tr.AssertAllResponseBodiesClosed(rec)
+	if len(rec.errors) != 1 {
+		t.Fatalf("expected one failure, got %d", len(rec.errors))
+	}
+	for _, expected := range []string{
+		"2 response bodies were never closed",
+		"GET " + testUrl + "?read (read to EOF)",
+		"GET " + testUrl + "?leak",
+		"TestAssertAllResponseBodiesClosed",
+	} {
+		if !strings.Contains(rec.errors[0], expected) {
+			t.Errorf("expected the failure to mention %q, got:\n%s", expected, rec.errors[0])
+		}
+	}
+
+	tr.TrackResponseBodies(AllowReadToEOF())
+	read = get(testUrl)
+	if _, err := ioutil.ReadAll(read.Body); err != nil {
+		t.Fatal(err)
+	}
+	rec = &recordingTB{TB: t}
+	tr.AssertAllResponseBodiesClosed(rec)
+	if len(rec.errors) != 0 {
+		t.Errorf("expected bodies read to EOF to be allowed, got %v", rec.errors)
+	}
+
+	get(testUrl)
+	tr.Reset()
+	tr.AssertAllResponseBodiesClosed(t)
+}
+
+func TestAssertAllResponseBodiesClosedUntracked(t *testing.T) {
+	tr := NewMockTransport()
+	rec := &recordingTB{TB: t}
+	tr.AssertAllResponseBodiesClosed(rec)
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "not tracked") {
+		t.Errorf("expected untracked bodies to be reported, got %v", rec.errors)
+	}
+}
+
+func TestDeactivateAndResetReportsLeaks(t *testing.T) {
+	Activate()
+	DefaultTransport.TrackResponseBodies()
+	defer func() {
+		DefaultTransport.mu.Lock()
+		DefaultTransport.bodyTracker = nil
+		DefaultTransport.mu.Unlock()
+	}()
+	RegisterResponder("GET", testUrl, NewStringResponder(200, "hello"))
+
+	if _, err := http.Get(testUrl); err != nil {
+		t.Fatal(err)
+	}
+	rec := &recordingTB{TB: t}
+	DeactivateAndReset(rec)
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "GET "+testUrl) {
+		t.Errorf("expected the leaked body to be reported, got %v", rec.errors)
+	}
+}
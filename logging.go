@@ -0,0 +1,110 @@
+package httpmock
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Logger receives the traces of a MockTransport.  *testing.T and *testing.B satisfy it.
+type Logger interface {
+	Logf(format string, args ...interface{})
+}
+
+// LoggerFunc adapts a function to the Logger interface, which makes forwarding traces elsewhere
+// a one liner:
+// 		transport.SetLogger(httpmock.LoggerFunc(func(format string, args ...interface{}) {
+// 			logger.Debug(fmt.Sprintf(format, args...))
+// 		}))
+type LoggerFunc func(format string, args ...interface{})
+
+// Logf calls f(format, args...).
+func (f LoggerFunc) Logf(format string, args ...interface{}) {
+	f(format, args...)
+}
+
+// SetLogger makes the MockTransport trace every exchange to l: one line per request with the
+// registration that matched, the status returned, the time spent in the responder (which includes
+// any injected delay) and the body sizes.  Bodies are never consumed for the sake of logging.
+// Pass nil, the default, to turn tracing off.
+//
+// A *testing.T must not be used after its test has completed, so reset the logger (or the
+// transport) before the end of the test.
+func (m *MockTransport) SetLogger(l Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger = l
+}
+
+// SetVerboseLogging makes the MockTransport also trace the request and response headers of every
+// exchange.  It has no effect unless a logger is set with SetLogger.
+func (m *MockTransport) SetVerboseLogging(verbose bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logVerbose = verbose
+}
+
+// logExchange traces a request and its outcome to logger.  req may be nil or lack a URL when it
+// was rejected before reaching any responder.
+func logExchange(logger Logger, verbose bool, req *http.Request, key string, reqSize int, resp *http.Response, err error, elapsed time.Duration) {
+	matched := "matched nothing"
+	if key != "" {
+		matched = fmt.Sprintf("matched %q", key)
+	}
+	method, target, header := "", "<nil>", http.Header(nil)
+	if req != nil {
+		method, header = req.Method, req.Header
+		if req.URL != nil {
+			target = req.URL.String()
+		}
+	}
+
+	outcome := fmt.Sprintf("error=%q", fmt.Sprint(err))
+	if err == nil && resp != nil {
+		outcome = fmt.Sprintf("status=%d response=%s", resp.StatusCode, responseBodySize(resp))
+	}
+
+	logger.Logf("httpmock: %s %s %s %s elapsed=%s request=%dB",
+		method, target, matched, outcome, elapsed, reqSize)
+
+	if !verbose {
+		return
+	}
+	logHeaders(logger, ">", header)
+	if err == nil && resp != nil {
+		logHeaders(logger, "<", resp.Header)
+	}
+}
+
+func logHeaders(logger Logger, direction string, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		logger.Logf("httpmock:   %s %s: %s", direction, key, strings.Join(header[key], ", "))
+	}
+}
+
+// responseBodySize describes the size of a response body without reading it.
+func responseBodySize(resp *http.Response) string {
+	if body, ok := responderBody(resp.Body).(*dummyReadCloser); ok {
+		current, err := body.body.Seek(0, io.SeekCurrent)
+		if err == nil {
+			end, err := body.body.Seek(0, io.SeekEnd)
+			body.body.Seek(current, io.SeekStart)
+			if err == nil {
+				return fmt.Sprintf("%dB", end)
+			}
+		}
+	}
+	if resp.ContentLength > 0 {
+		return fmt.Sprintf("%dB", resp.ContentLength)
+	}
+	return "unknown"
+}
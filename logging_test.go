@@ -0,0 +1,92 @@
+package httpmock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMockTransportLogger(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	var lines []string
+	tr.SetLogger(LoggerFunc(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}))
+
+	tr.RegisterResponder("POST", testUrl, func(req *http.Request) (*http.Response, error) {
+		data, _ := ioutil.ReadAll(req.Body)
+		resp := NewStringResponse(201, "created: "+string(data))
+		resp.Header.Set("X-Id", "42")
+		return resp, nil
+	})
+
+	resp, err := client.Post(testUrl, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "created: hello" {
+		t.Fatalf("expected logging not to consume the bodies, got %q", data)
+	}
+
+	if len(lines) != 1 {
+		t.Fatalf("expected one line per request, got %q", lines)
+	}
+	for _, want := range []string{`matched "POST http://www.example.com/"`, "status=201", "request=5B", "response=14B", "elapsed="} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("expected %q in %q", want, lines[0])
+		}
+	}
+
+	lines = nil
+	tr.SetVerboseLogging(true)
+	req, err := http.NewRequest("GET", testUrl+"missing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	client.Do(req)
+	if len(lines) < 2 {
+		t.Fatalf("expected header lines in verbose mode, got %q", lines)
+	}
+	if !strings.Contains(lines[0], "matched nothing") || !strings.Contains(lines[0], NoResponderFound.Error()) {
+		t.Errorf("expected the unmatched request to be traced, got %q", lines[0])
+	}
+	if !strings.Contains(strings.Join(lines[1:], "\n"), "> Accept: application/json") {
+		t.Errorf("expected request headers in verbose mode, got %q", lines[1:])
+	}
+}
+
+func benchmarkRoundTrip(b *testing.B, logger Logger) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "hello world"))
+	tr.SetLogger(logger)
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tr.RoundTrip(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRoundTripNilLogger(b *testing.B) {
+	benchmarkRoundTrip(b, nil)
+}
+
+func BenchmarkRoundTripLogger(b *testing.B) {
+	benchmarkRoundTrip(b, LoggerFunc(func(string, ...interface{}) {}))
+}
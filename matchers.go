@@ -0,0 +1,221 @@
+package httpmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Matcher is a condition a request has to meet for a registration to answer it, see WithMatcher.
+// It returns nil when the request matches, or an error explaining why it doesn't, which shows up
+// in the error returned for unmatched requests.  Matchers may read the request body: every
+// matcher, and the responder, gets an intact copy.
+type Matcher func(req *http.Request) error
+
+// MatchBodyJSON matches requests whose body is semantically the same JSON as expected, which can
+// be raw JSON (as a string, []byte or json.RawMessage) or any value encoding to JSON.  Key order
+// and whitespace never matter, opts loosen the comparison further.  Mismatches are reported path
+// by path:
+//
+//	body JSON differs: $.user.name: expected "bob", got "alice"; $.tags[2]: unexpected "x"
+func MatchBodyJSON(expected interface{}, opts ...JSONOption) Matcher {
+	cfg := newJSONConfig(opts)
+
+	var raw []byte
+	var err error
+	switch e := expected.(type) {
+	case string:
+		raw = []byte(e)
+	case []byte:
+		raw = e
+	case json.RawMessage:
+		raw = e
+	default:
+		raw, err = json.Marshal(expected)
+	}
+
+	var want interface{}
+	if err == nil {
+		want, err = cfg.decodeJSON(raw)
+	}
+	if err != nil {
+		err = fmt.Errorf("invalid expected JSON: %s", err)
+		return func(*http.Request) error {
+			return err
+		}
+	}
+
+	return func(req *http.Request) error {
+		body, err := ReplayableRequestBody(req)
+		if err != nil {
+			return err
+		}
+
+		got, err := cfg.decodeJSON(body)
+		if err != nil {
+			return fmt.Errorf("body is not valid JSON: %s", err)
+		}
+		if diffs := jsonDiff("$", want, got); len(diffs) > 0 {
+			return fmt.Errorf("body JSON differs: %s", strings.Join(diffs, "; "))
+		}
+		return nil
+	}
+}
+
+// MatchCookie matches requests carrying a cookie name set to value.  Only the names and values of
+// cookies travel with requests: their attributes, such as Path or Secure, are applied by the
+// cookie jar of the client when picking the cookies to send, and can't be checked here.
+func MatchCookie(name, value string) Matcher {
+	return func(req *http.Request) error {
+		cookie, err := req.Cookie(name)
+		if err != nil {
+			return fmt.Errorf("cookie %s missing", name)
+		}
+		if cookie.Value != value {
+			return fmt.Errorf("cookie %s is %q, expected %q", name, cookie.Value, value)
+		}
+		return nil
+	}
+}
+
+// MatchCookiePresent matches requests carrying a cookie name, whatever its value.  See MatchCookie
+// about cookie attributes.
+func MatchCookiePresent(name string) Matcher {
+	return func(req *http.Request) error {
+		if _, err := req.Cookie(name); err != nil {
+			return fmt.Errorf("cookie %s missing", name)
+		}
+		return nil
+	}
+}
+
+// HeaderAbsent matches requests without a header key.  Next to a registration requiring the
+// header, such as one using HeaderMatchesRegexp, it answers the requests without it for the same
+// URL: as only one of them can match a request, which answers doesn't depend on the order they
+// were registered in.
+func HeaderAbsent(key string) Matcher {
+	return func(req *http.Request) error {
+		if values := req.Header.Values(key); len(values) > 0 {
+			return fmt.Errorf("header %s should be absent, got %q", http.CanonicalHeaderKey(key), values)
+		}
+		return nil
+	}
+}
+
+// QueryParamAbsent matches requests whose query has no parameter key, see HeaderAbsent.
+func QueryParamAbsent(key string) Matcher {
+	return func(req *http.Request) error {
+		if values, ok := req.URL.Query()[key]; ok {
+			return fmt.Errorf("query parameter %s should be absent, got %q", key, values)
+		}
+		return nil
+	}
+}
+
+// CookieAbsent matches requests without a cookie name, see HeaderAbsent.
+func CookieAbsent(name string) Matcher {
+	return func(req *http.Request) error {
+		if cookie, err := req.Cookie(name); err == nil {
+			return fmt.Errorf("cookie %s should be absent, got %q", name, cookie.Value)
+		}
+		return nil
+	}
+}
+
+// BodyEmpty matches requests without a body, or with an empty one, see HeaderAbsent.  The body is
+// read from a copy given by GetBody when the request has one, see ReplayableRequestBody otherwise.
+func BodyEmpty() Matcher {
+	return func(req *http.Request) error {
+		var body []byte
+		var err error
+		if req.GetBody != nil {
+			var rc io.ReadCloser
+			if rc, err = req.GetBody(); err == nil {
+				body, err = ioutil.ReadAll(rc)
+				rc.Close()
+			}
+		} else {
+			body, err = ReplayableRequestBody(req)
+		}
+		if err != nil {
+			return err
+		}
+		if len(body) > 0 {
+			return fmt.Errorf("body should be empty, got %d bytes", len(body))
+		}
+		return nil
+	}
+}
+
+// HeaderMatchOption configures HeaderMatchesRegexp.
+type HeaderMatchOption func(*headerMatchConfig)
+
+type headerMatchConfig struct {
+	allValues bool
+}
+
+// AllValues makes HeaderMatchesRegexp require every value of a multi-valued header to match,
+// rather than any of them.
+func AllValues() HeaderMatchOption {
+	return func(c *headerMatchConfig) {
+		c.allValues = true
+	}
+}
+
+// HeaderMatchesRegexp matches requests having a header key with a value matching re.  Any value of
+// a header set more than once will do, unless AllValues is given.  Mismatches report the actual
+// values:
+//
+//	header User-Agent ["curl/8.4.0"] doesn't match ^mysdk/\d+\.\d+ \(\w+; \w+\)$
+func HeaderMatchesRegexp(key string, re *regexp.Regexp, opts ...HeaderMatchOption) Matcher {
+	var cfg headerMatchConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(req *http.Request) error {
+		values := req.Header.Values(key)
+		if len(values) == 0 {
+			return fmt.Errorf("header %s missing", http.CanonicalHeaderKey(key))
+		}
+
+		matched := 0
+		for _, value := range values {
+			if re.MatchString(value) {
+				matched++
+			}
+		}
+		if matched == len(values) || (matched > 0 && !cfg.allValues) {
+			return nil
+		}
+		return fmt.Errorf("header %s %q doesn't match %s", http.CanonicalHeaderKey(key), values, re)
+	}
+}
+
+// MatchUserAgent matches requests whose User-Agent matches re, to check that an SDK stamps its
+// product, version and platform on its calls:
+//
+//	tr.RegisterResponder("GET", url, responder,
+//		httpmock.WithMatcher(httpmock.MatchUserAgent(regexp.MustCompile(`^mysdk/\d+\.\d+\.\d+ \(\w+; \w+\)$`))))
+func MatchUserAgent(re *regexp.Regexp) Matcher {
+	return HeaderMatchesRegexp("User-Agent", re)
+}
+
+// AssertAllRequestsHadHeader checks that every call in the history of the MockTransport had a
+// header key matching re, as HeaderMatchesRegexp does, whatever answered them.  Each offending call
+// is reported through t with its method, URL and actual values.
+func (m *MockTransport) AssertAllRequestsHadHeader(t testing.TB, key string, re *regexp.Regexp, opts ...HeaderMatchOption) {
+	t.Helper()
+
+	match := HeaderMatchesRegexp(key, re, opts...)
+	for _, call := range m.History() {
+		if err := match(call.Request); err != nil {
+			t.Errorf("httpmock: call %d %s %s: %s", call.Index, call.Request.Method, call.Request.URL, err)
+		}
+	}
+}
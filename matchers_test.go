@@ -0,0 +1,278 @@
+package httpmock
+
+import (
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func matchRequest(t *testing.T, matcher Matcher, body string) error {
+	req, err := http.NewRequest("POST", testUrl, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matcher(req)
+}
+
+func TestMatchBodyJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected interface{}
+		opts     []JSONOption
+		body     string
+		diff     string
+	}{
+		{
+			name:     "key order and spacing",
+			expected: `{"a": 1, "b": [true, null]}`,
+			body:     `{"b":[true,null],"a":1}`,
+		},
+		{
+			name:     "go value",
+			expected: map[string]interface{}{"name": "widget"},
+			body:     `{"name":"widget"}`,
+		},
+		{
+			name:     "numbers as written",
+			expected: `{"n": 1.0}`,
+			body:     `{"n": 1}`,
+			diff:     "$.n: expected 1.0, got 1",
+		},
+		{
+			name:     "numerically equal",
+			expected: `{"n": 1.0, "m": [1e0, 2.50]}`,
+			opts:     []JSONOption{NumericallyEqual()},
+			body:     `{"n": 1, "m": [1, 2.5]}`,
+		},
+		{
+			name:     "ignored fields",
+			expected: `{"meta": {"requestId": "a"}, "items": [{"id": 1, "v": "x"}], "createdAt": "yesterday"}`,
+			opts:     []JSONOption{IgnoreFields("meta.requestId", "items.*.id", "createdAt")},
+			body:     `{"meta": {"requestId": "b"}, "items": [{"id": 2, "v": "x"}], "createdAt": "today"}`,
+		},
+		{
+			name:     "null as absent",
+			expected: `{"a": 1}`,
+			opts:     []JSONOption{TreatNullAsAbsent()},
+			body:     `{"a": 1, "b": null}`,
+		},
+		{
+			name:     "null is a value",
+			expected: `{"a": 1}`,
+			body:     `{"a": 1, "b": null}`,
+			diff:     "$.b: unexpected null",
+		},
+		{
+			name:     "path level diff",
+			expected: `{"user": {"name": "bob"}, "tags": ["a"]}`,
+			body:     `{"user": {"name": "alice"}, "tags": ["a", "x"]}`,
+			diff:     `body JSON differs: $.tags[1]: unexpected "x"; $.user.name: expected "bob", got "alice"`,
+		},
+		{
+			name:     "invalid body",
+			expected: `{}`,
+			body:     `{`,
+			diff:     "body is not valid JSON",
+		},
+	}
+
+	for _, test := range tests {
+		err := matchRequest(t, MatchBodyJSON(test.expected, test.opts...), test.body)
+		if test.diff == "" {
+			if err != nil {
+				t.Errorf("%s: expected a match, got %s", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.diff) {
+			t.Errorf("%s: expected %q, got %v", test.name, test.diff, err)
+		}
+	}
+}
+
+func TestMockTransportMatchers(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	tr.RegisterResponder("POST", testUrl, NewStringResponder(201, "created"),
+		WithMatcher(MatchBodyJSON(`{"name": "widget"}`)))
+
+	// no fallback yet, so the mismatch is explained in the error
+	_, err := client.Post(testUrl, "application/json", strings.NewReader(`{"name": "gadget"}`))
+	if err == nil || !strings.Contains(err.Error(), NoResponderFound.Error()) ||
+		!strings.Contains(err.Error(), `$.name: expected "widget", got "gadget"`) {
+		t.Fatalf("expected the near miss to be explained, got %v", err)
+	}
+
+	tr.RegisterResponder("POST", testUrl, NewStringResponder(400, "bad"))
+	tr.RegisterResponder("POST", testUrl, NewStringResponder(201, "created"),
+		WithMatcher(MatchBodyJSON(`{"name": "widget"}`)))
+
+	for body, status := range map[string]int{`{"name":"widget"}`: 201, `{"name":"gadget"}`: 400} {
+		resp, err := client.Post(testUrl, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected status %d, got %d", body, status, resp.StatusCode)
+		}
+	}
+}
+
+func TestHeaderMatchesRegexp(t *testing.T) {
+	re := regexp.MustCompile(`^mysdk/\d+\.\d+\.\d+ \(\w+; \w+\)$`)
+
+	tests := []struct {
+		name     string
+		values   []string
+		opts     []HeaderMatchOption
+		expected string
+	}{
+		{"match", []string{"mysdk/1.2.3 (linux; amd64)"}, nil, ""},
+		{"mismatch", []string{"mysdk/1.2 (linux; amd64)"}, nil, `header User-Agent ["mysdk/1.2 (linux; amd64)"] doesn't match`},
+		{"missing", nil, nil, "header User-Agent missing"},
+		{"any value", []string{"proxy/1.0", "mysdk/1.2.3 (darwin; arm64)"}, nil, ""},
+		{"all values", []string{"proxy/1.0", "mysdk/1.2.3 (darwin; arm64)"}, []HeaderMatchOption{AllValues()},
+			`["proxy/1.0" "mysdk/1.2.3 (darwin; arm64)"] doesn't match`},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, value := range test.values {
+			req.Header.Add("User-Agent", value)
+		}
+		err = HeaderMatchesRegexp("user-agent", re, test.opts...)(req)
+		switch {
+		case test.expected == "" && err != nil:
+			t.Errorf("%s: expected a match, got %v", test.name, err)
+		case test.expected != "" && (err == nil || !strings.Contains(err.Error(), test.expected)):
+			t.Errorf("%s: expected a mismatch mentioning %q, got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestAssertAllRequestsHadHeader(t *testing.T) {
+	re := regexp.MustCompile(`^mysdk/\d+\.\d+\.\d+ \(\w+; \w+\)$`)
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""), WithMatcher(MatchUserAgent(re)))
+	tr.RegisterNoResponder(NewStringResponder(404, ""))
+	client := &http.Client{Transport: tr}
+
+	for _, ua := range []string{"mysdk/1.0.0 (linux; amd64)", "Go-http-client/1.1"} {
+		req, err := http.NewRequest("GET", testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", ua)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if counts := tr.CallCounts(); counts["GET "+testUrl] != 1 {
+		t.Errorf("expected only the SDK request to match the registration, got %v", counts)
+	}
+
+	rec := &recordingTB{TB: t}
+	tr.AssertAllRequestsHadHeader(rec, "User-Agent", re)
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "call 1 GET "+testUrl) ||
+		!strings.Contains(rec.errors[0], `["Go-http-client/1.1"]`) {
+		t.Errorf("expected the second call to be reported, got %v", rec.errors)
+	}
+}
+
+func TestMatchCookie(t *testing.T) {
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+
+	tests := []struct {
+		matcher  Matcher
+		expected string
+	}{
+		{MatchCookie("session", "abc"), ""},
+		{MatchCookie("session", "xyz"), `cookie session is "abc", expected "xyz"`},
+		{MatchCookie("lang", "en"), "cookie lang missing"},
+		{MatchCookiePresent("theme"), ""},
+		{MatchCookiePresent("lang"), "cookie lang missing"},
+	}
+	for i, test := range tests {
+		err := test.matcher(req)
+		switch {
+		case test.expected == "" && err != nil:
+			t.Errorf("#%d: expected a match, got %v", i, err)
+		case test.expected != "" && (err == nil || err.Error() != test.expected):
+			t.Errorf("#%d: expected %q, got %v", i, test.expected, err)
+		}
+	}
+}
+
+func TestAbsenceMatchers(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", testUrl, NewStringResponder(200, "private"),
+		WithMatcher(HeaderMatchesRegexp("Authorization", regexp.MustCompile(`^Bearer `))))
+	tr.RegisterResponder("POST", testUrl, NewStringResponder(200, "anonymous"),
+		WithMatcher(HeaderAbsent("Authorization"), QueryParamAbsent("debug"), CookieAbsent("session"), BodyEmpty()))
+	client := &http.Client{Transport: tr}
+
+	post := func(url, auth, body string, cookie bool) (string, error) {
+		req, err := http.NewRequest("POST", url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		if cookie {
+			req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		return string(data), err
+	}
+
+	if got, err := post(testUrl, "Bearer token", "", false); err != nil || got != "private" {
+		t.Errorf("expected the authorized registration to answer, got %q, %v", got, err)
+	}
+	if got, err := post(testUrl, "", "", false); err != nil || got != "anonymous" {
+		t.Errorf("expected the anonymous registration to answer, got %q, %v", got, err)
+	}
+
+	for _, test := range []struct {
+		url, auth, body string
+		cookie          bool
+		expected        string
+	}{
+		{testUrl, "Basic abc", "", false, `header Authorization should be absent, got ["Basic abc"]`},
+		{testUrl + "?debug=1", "", "", false, `query parameter debug should be absent, got ["1"]`},
+		{testUrl, "", "", true, `cookie session should be absent, got "abc"`},
+		{testUrl, "", "payload", false, "body should be empty, got 7 bytes"},
+	} {
+		_, err := post(test.url, test.auth, test.body, test.cookie)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected the near miss to mention %q, got %v", test.expected, err)
+		}
+	}
+
+	// BodyEmpty reads a copy of a body with a GetBody, leaving the body itself unread
+	req, _ := http.NewRequest("POST", testUrl, strings.NewReader("payload"))
+	body := req.Body
+	if err := BodyEmpty()(req); err == nil || req.Body != body {
+		t.Errorf("expected BodyEmpty to fail on a copy of the body, got %v", err)
+	}
+	if data, _ := ioutil.ReadAll(req.Body); string(data) != "payload" {
+		t.Errorf("expected the body left unread, got %q", data)
+	}
+}
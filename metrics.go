@@ -0,0 +1,197 @@
+package httpmock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Metrics is notified of every request handled by a MockTransport, see SetMetrics.
+//
+// key is the "METHOD URL" of the registration that answered, or empty when no registration
+// matched.  status is 0 when the exchange failed with err.  elapsed is the time spent answering
+// the request, which includes any delay injected by the responder.
+type Metrics interface {
+	ObserveRequest(key string, status int, err error, elapsed time.Duration)
+}
+
+// SetMetrics makes the MockTransport report every request to m, whatever its outcome.  Pass nil,
+// the default, to stop reporting.
+func (m *MockTransport) SetMetrics(metrics Metrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics = metrics
+}
+
+// DefaultLatencyBuckets are the upper bounds of the latency histograms kept by MemoryMetrics.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// MemoryMetrics is a Metrics implementation keeping per key counters and latency histograms in
+// memory, for assertions.
+type MemoryMetrics struct {
+	mu       sync.Mutex
+	buckets  []time.Duration
+	keys     map[string]*KeyMetrics
+	profiles map[string]*ProfileStats
+}
+
+// KeyMetrics holds what MemoryMetrics observed for a key.
+type KeyMetrics struct {
+	// Count is the number of requests observed.
+	Count int
+	// Errors is the number of requests that failed with an error instead of a response.
+	Errors int
+	// Statuses counts the responses by status code.
+	Statuses map[int]int
+	// Latency is the histogram of the time spent answering the requests.
+	Latency Histogram
+}
+
+// ProfileStats holds what MemoryMetrics observed for a host profile.
+type ProfileStats struct {
+	// Count is the number of requests the profile applied to.
+	Count int
+	// Failures is the number of requests the profile made fail.
+	Failures int
+	// Latency is the histogram of the delays the profile added.
+	Latency Histogram
+}
+
+// Histogram is a cumulative latency histogram, shaped like a Prometheus one.
+type Histogram struct {
+	// Buckets counts, for each upper bound, the observations less than or equal to it.
+	Buckets []Bucket
+	// Count is the total number of observations, including those above every bucket.
+	Count int
+	// Sum is the total of the observations.
+	Sum time.Duration
+}
+
+// Bucket is a histogram bucket.
+type Bucket struct {
+	UpperBound time.Duration
+	Count      int
+}
+
+// NewMemoryMetrics creates a MemoryMetrics using DefaultLatencyBuckets.
+func NewMemoryMetrics() *MemoryMetrics {
+	return NewMemoryMetricsWithBuckets(DefaultLatencyBuckets)
+}
+
+// NewMemoryMetricsWithBuckets creates a MemoryMetrics using the given latency bucket upper bounds.
+func NewMemoryMetricsWithBuckets(buckets []time.Duration) *MemoryMetrics {
+	sorted := append([]time.Duration(nil), buckets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &MemoryMetrics{
+		buckets:  sorted,
+		keys:     make(map[string]*KeyMetrics),
+		profiles: make(map[string]*ProfileStats),
+	}
+}
+
+// ObserveRequest implements Metrics.
+func (mm *MemoryMetrics) ObserveRequest(key string, status int, err error, elapsed time.Duration) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	km, ok := mm.keys[key]
+	if !ok {
+		km = &KeyMetrics{Statuses: make(map[int]int), Latency: mm.newHistogram()}
+		mm.keys[key] = km
+	}
+
+	km.Count++
+	if err != nil {
+		km.Errors++
+	} else {
+		km.Statuses[status]++
+	}
+	km.Latency.observe(elapsed)
+}
+
+// ObserveProfile implements ProfileMetrics.
+func (mm *MemoryMetrics) ObserveProfile(pattern string, latency time.Duration, failed bool) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	ps, ok := mm.profiles[pattern]
+	if !ok {
+		ps = &ProfileStats{Latency: mm.newHistogram()}
+		mm.profiles[pattern] = ps
+	}
+
+	ps.Count++
+	if failed {
+		ps.Failures++
+	}
+	ps.Latency.observe(latency)
+}
+
+// newHistogram creates an empty histogram with the buckets of mm.
+func (mm *MemoryMetrics) newHistogram() Histogram {
+	h := Histogram{Buckets: make([]Bucket, len(mm.buckets))}
+	for i, bound := range mm.buckets {
+		h.Buckets[i].UpperBound = bound
+	}
+	return h
+}
+
+// observe adds d to the histogram.
+func (h *Histogram) observe(d time.Duration) {
+	h.Count++
+	h.Sum += d
+	for i := range h.Buckets {
+		if d <= h.Buckets[i].UpperBound {
+			h.Buckets[i].Count++
+		}
+	}
+}
+
+// Snapshot returns a copy of the metrics observed so far, by key.
+func (mm *MemoryMetrics) Snapshot() map[string]KeyMetrics {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	snapshot := make(map[string]KeyMetrics, len(mm.keys))
+	for key, km := range mm.keys {
+		c := *km
+		c.Statuses = make(map[int]int, len(km.Statuses))
+		for status, n := range km.Statuses {
+			c.Statuses[status] = n
+		}
+		c.Latency.Buckets = append([]Bucket(nil), km.Latency.Buckets...)
+		snapshot[key] = c
+	}
+	return snapshot
+}
+
+// ProfileSnapshot returns a copy of the host profile metrics observed so far, by host pattern.
+func (mm *MemoryMetrics) ProfileSnapshot() map[string]ProfileStats {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	snapshot := make(map[string]ProfileStats, len(mm.profiles))
+	for pattern, ps := range mm.profiles {
+		c := *ps
+		c.Latency.Buckets = append([]Bucket(nil), ps.Latency.Buckets...)
+		snapshot[pattern] = c
+	}
+	return snapshot
+}
+
+// Reset forgets everything observed so far.
+func (mm *MemoryMetrics) Reset() {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.keys = make(map[string]*KeyMetrics)
+	mm.profiles = make(map[string]*ProfileStats)
+}
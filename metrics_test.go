@@ -0,0 +1,94 @@
+package httpmock
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestMockTransportMetrics(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	metrics := NewMemoryMetrics()
+	tr.SetMetrics(metrics)
+
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "ok"))
+	tr.RegisterResponder("POST", testUrl, func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("boom")
+	})
+	tr.RegisterResponder("GET", testUrl+"slow", NewResponder(204, "", WithFixedDelay(20*time.Millisecond)))
+
+	client.Get(testUrl)
+	client.Get(testUrl + "?page=2")
+	client.Post(testUrl, "text/plain", nil)
+	client.Get(testUrl + "slow")
+	client.Get(testUrl + "missing")
+
+	snapshot := metrics.Snapshot()
+
+	get := snapshot["GET "+testUrl]
+	if get.Count != 2 || get.Statuses[200] != 2 || get.Errors != 0 {
+		t.Fatalf("unexpected metrics for GET: %+v", get)
+	}
+
+	post := snapshot["POST "+testUrl]
+	if post.Count != 1 || post.Errors != 1 {
+		t.Fatalf("unexpected metrics for POST: %+v", post)
+	}
+
+	slow := snapshot["GET "+testUrl+"slow"]
+	if slow.Latency.Sum < 20*time.Millisecond {
+		t.Fatalf("expected the injected delay to be observed, got %s", slow.Latency.Sum)
+	}
+	for _, bucket := range slow.Latency.Buckets {
+		if bucket.UpperBound < 20*time.Millisecond && bucket.Count != 0 {
+			t.Fatalf("expected the slow call above the %s bucket", bucket.UpperBound)
+		}
+	}
+
+	unmatched := snapshot[""]
+	if unmatched.Count != 1 || unmatched.Errors != 1 {
+		t.Fatalf("unexpected metrics for unmatched requests: %+v", unmatched)
+	}
+
+	// snapshots are copies
+	get.Statuses[200] = 100
+	if metrics.Snapshot()["GET "+testUrl].Statuses[200] != 2 {
+		t.Fatal("expected snapshots not to share state")
+	}
+}
+
+func TestMockTransportMetricsRejectedRequests(t *testing.T) {
+	tr := NewMockTransport()
+	metrics := NewMemoryMetrics()
+	tr.SetMetrics(metrics)
+	var lines []string
+	tr.SetLogger(LoggerFunc(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "ok"))
+
+	// none of these requests reach a responder
+	tr.RoundTrip(nil)
+	tr.RoundTrip(&http.Request{Method: "GET"})
+	req, _ := http.NewRequest("POST", testUrl, iotest.ErrReader(errors.New("broken body")))
+	tr.RoundTrip(req)
+	tr.SetProxy(func(*http.Request) (*url.URL, error) {
+		return nil, errors.New("broken proxy")
+	})
+	req, _ = http.NewRequest("GET", testUrl, nil)
+	tr.RoundTrip(req)
+
+	unmatched := metrics.Snapshot()[""]
+	if unmatched.Count != 4 || unmatched.Errors != 4 {
+		t.Fatalf("expected the 4 rejected requests to be observed, got %+v", unmatched)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("expected the 4 rejected requests to be logged, got %q", lines)
+	}
+}
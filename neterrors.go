@@ -0,0 +1,104 @@
+package httpmock
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+)
+
+// NewDNSErrorResponder creates a Responder failing like a dial to an unknown host: with a
+// *net.OpError wrapping a *net.DNSError for host whose IsNotFound is true.  If host is empty, the
+// host of the request is used.
+func NewDNSErrorResponder(host string) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		name := host
+		if name == "" {
+			name = req.URL.Hostname()
+		}
+		return nil, &net.OpError{
+			Op:  "dial",
+			Net: "tcp",
+			Err: &net.DNSError{
+				Err:        "no such host",
+				Name:       name,
+				IsNotFound: true,
+			},
+		}
+	}
+}
+
+// NewConnRefusedResponder creates a Responder failing like a dial to a closed port: with a
+// *net.OpError wrapping syscall.ECONNREFUSED.
+func NewConnRefusedResponder() Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		return nil, &net.OpError{
+			Op:   "dial",
+			Net:  "tcp",
+			Addr: requestAddr(req),
+			Err:  os.NewSyscallError("connect", syscall.ECONNREFUSED),
+		}
+	}
+}
+
+// NewTLSHandshakeErrorResponder creates a Responder failing like a TLS handshake rejected by the
+// server, with a *net.OpError whose message reads "remote error: tls: <msg>".
+func NewTLSHandshakeErrorResponder(msg string) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		return nil, &net.OpError{
+			Op:  "remote error",
+			Err: errors.New("tls: " + msg),
+		}
+	}
+}
+
+// requestAddr is the address a request would have been sent to.
+func requestAddr(req *http.Request) net.Addr {
+	return tcpAddr(net.JoinHostPort(req.URL.Hostname(), urlPort(req.URL)))
+}
+
+// tcpAddr is a net.Addr for a host and port that are not resolved.
+type tcpAddr string
+
+func (a tcpAddr) Network() string { return "tcp" }
+func (a tcpAddr) String() string  { return string(a) }
+
+// SimulateHostDown makes every request to host fail as if its server refused connections,
+// whatever the path and the registrations.  host follows the same rules as SimulateHostError.
+func (m *MockTransport) SimulateHostDown(host string) {
+	m.SimulateHostError(host, NewConnRefusedResponder())
+}
+
+// SimulateHostError makes every request to host be answered by responder, typically one of
+// NewDNSErrorResponder, NewConnRefusedResponder or NewTLSHandshakeErrorResponder, whatever the path
+// and the registrations.  host is a host name, optionally with a port, and may start with "*." to
+// cover every subdomain.
+func (m *MockTransport) SimulateHostError(host string, responder Responder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hostFailures == nil {
+		m.hostFailures = make(map[string]Responder)
+	}
+	m.hostFailures[host] = responder
+}
+
+// RestoreHost undoes SimulateHostDown, SimulateHostError and SimulateClosingHost for host.
+func (m *MockTransport) RestoreHost(host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.hostFailures, host)
+	delete(m.closingHosts, host)
+}
+
+// hostFailure returns the responder simulating a failure of the host of req, if any.
+func (m *MockTransport) hostFailure(req *http.Request) Responder {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for pattern, responder := range m.hostFailures {
+		if hostMatches(pattern, req.URL) {
+			return responder
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package httpmock
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestNetworkErrorResponders(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	tr.RegisterResponder("GET", "http://dns.example.com/", NewDNSErrorResponder(""))
+	tr.RegisterResponder("GET", "http://refused.example.com/", NewConnRefusedResponder())
+	tr.RegisterResponder("GET", "https://tls.example.com/", NewTLSHandshakeErrorResponder("handshake failure"))
+
+	_, err := client.Get("http://dns.example.com/")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound || dnsErr.Name != "dns.example.com" {
+		t.Fatalf("expected a not found *net.DNSError, got %#v", err)
+	}
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		t.Fatalf("expected the client to wrap the error in a *url.Error, got %#v", err)
+	}
+
+	_, err = client.Get("http://refused.example.com/")
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "dial" || !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("expected a dial *net.OpError wrapping ECONNREFUSED, got %#v", err)
+	}
+	if !strings.Contains(err.Error(), "dial tcp refused.example.com:80: connect: connection refused") {
+		t.Fatalf("expected the message of a real refused dial, got %q", err)
+	}
+
+	_, err = client.Get("https://tls.example.com/")
+	if !errors.As(err, &opErr) || !strings.Contains(err.Error(), "remote error: tls: handshake failure") {
+		t.Fatalf("expected a TLS handshake error, got %#v", err)
+	}
+}
+
+func TestMockTransportSimulateHostDown(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	tr.RegisterResponder("GET", "http://db.example.com/health", NewStringResponder(200, "ok"))
+	tr.RegisterResponder("GET", "http://api.example.com/", NewStringResponder(200, "ok"))
+	tr.SimulateHostDown("db.example.com")
+	tr.SimulateHostError("*.cache.example.com", NewDNSErrorResponder(""))
+
+	for _, u := range []string{"http://db.example.com/health", "http://DB.example.com/anything"} {
+		if _, err := client.Get(u); !errors.Is(err, syscall.ECONNREFUSED) {
+			t.Errorf("%s: expected connection refused, got %v", u, err)
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if _, err := client.Get("http://eu.cache.example.com/key"); !errors.As(err, &dnsErr) {
+		t.Errorf("expected a DNS error for the wildcard host, got %v", err)
+	}
+
+	if resp, err := client.Get("http://api.example.com/"); err != nil || resp.StatusCode != 200 {
+		t.Errorf("expected other hosts to be unaffected, got %v %v", resp, err)
+	}
+
+	if infos := tr.Registrations(); infos[1].Calls != 0 {
+		t.Errorf("expected calls to a down host not to count against registrations, got %d", infos[1].Calls)
+	}
+
+	tr.RestoreHost("db.example.com")
+	if resp, err := client.Get("http://db.example.com/health"); err != nil || resp.StatusCode != 200 {
+		t.Errorf("expected the host to be back, got %v %v", resp, err)
+	}
+}
+
+func TestHostMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		url     string
+		match   bool
+	}{
+		{"example.com", "http://example.com/", true},
+		{"example.com", "http://EXAMPLE.com:8080/", true},
+		{"example.com", "http://www.example.com/", false},
+		{"example.com:8080", "http://example.com:8080/", true},
+		{"example.com:8080", "http://example.com/", false},
+		{"example.com:443", "https://example.com/", true},
+		{"*.example.com", "http://a.b.example.com/", true},
+		{"*.example.com", "http://example.com/", false},
+		{"*", "http://anything/", true},
+	}
+
+	for _, test := range tests {
+		u, err := url.Parse(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hostMatches(test.pattern, u); got != test.match {
+			t.Errorf("hostMatches(%q, %q): expected %v, got %v", test.pattern, test.url, test.match, got)
+		}
+	}
+}
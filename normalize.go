@@ -0,0 +1,236 @@
+package httpmock
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithRawPath makes a registration compare the path of requests exactly as encoded, for APIs where
+// encoding is significant.  By default, a registration also answers requests whose URL only differs
+// from the registered one by its encoding: international host names match their punycode form,
+// path segments compare on their decoded values, with spaces written %20 or +, and query
+// parameters compare as with MatchQuery.  An encoded slash (%2F) never matches a literal one.
+func WithRawPath() RegisterOption {
+	return func(r *registration) {
+		r.rawPath = true
+	}
+}
+
+// matchRawPath returns an error if the path of req isn't encoded as the one of key.
+func matchRawPath(key string, req *http.Request) error {
+	parts := strings.SplitN(key, " ", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	registered, err := url.Parse(parts[1])
+	if err != nil {
+		return nil
+	}
+	if got, expected := req.URL.EscapedPath(), registered.EscapedPath(); got != expected {
+		return fmt.Errorf("path %q is not encoded as %q", got, expected)
+	}
+	return nil
+}
+
+// equivalentKeys returns the registered keys equivalent to tried, as described by WithRawPath, but
+// spelled differently, in a stable order.  m.mu must be held.
+func (m *MockTransport) equivalentKeys(tried string) []string {
+	var keys []string
+	for _, key := range m.equivalents[normalizeKey(tried)] {
+		if key != tried {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// indexKey adds key, registered for the first time, to the keys indexed by their normalized form
+// for equivalentKeys.  m.mu must be held.
+func (m *MockTransport) indexKey(key string) {
+	if m.equivalents == nil {
+		m.equivalents = make(map[string][]string)
+	}
+	normalized := normalizeKey(key)
+	keys := append(m.equivalents[normalized], key)
+	sort.Strings(keys)
+	m.equivalents[normalized] = keys
+}
+
+// normalizeKey normalizes the URL of a "METHOD URL" key.
+func normalizeKey(key string) string {
+	parts := strings.SplitN(key, " ", 2)
+	if len(parts) != 2 {
+		return key
+	}
+	return parts[0] + " " + normalizeURL(parts[1])
+}
+
+// normalizeURL spells rawURL in a canonical way: lower case scheme, punycode host, path segments
+// escaped the same way whatever their original encoding, and a canonical query.
+func normalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	normalized := ""
+	if u.Scheme != "" {
+		normalized = strings.ToLower(u.Scheme) + ":"
+	}
+	if u.Host != "" {
+		normalized += "//" + normalizeHost(u.Host)
+	}
+
+	// only a literal + stands for a space, an encoded one (%2B) is a plus sign
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		if decoded, err := url.PathUnescape(strings.ReplaceAll(segment, "+", "%20")); err == nil {
+			segments[i] = url.PathEscape(decoded)
+		}
+	}
+	normalized += strings.Join(segments, "/")
+
+	if u.RawQuery != "" || u.ForceQuery {
+		normalized += "?" + canonicalQuery(u.Query())
+	}
+	return normalized
+}
+
+// normalizeHost lower cases hostport and converts its international labels to punycode.
+func normalizeHost(hostport string) string {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = hostport, ""
+	}
+	if unescaped, err := url.PathUnescape(host); err == nil {
+		host = unescaped
+	}
+
+	labels := strings.Split(strings.ToLower(host), ".")
+	for i, label := range labels {
+		if !isASCII(label) {
+			if encoded, err := punycode(label); err == nil {
+				labels[i] = "xn--" + encoded
+			}
+		}
+	}
+	host = strings.Join(labels, ".")
+
+	if port != "" {
+		return net.JoinHostPort(host, port)
+	}
+	return host
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// punycode encodes label as described by RFC 3492, without the "xn--" prefix.
+func punycode(label string) (string, error) {
+	const (
+		base        = 36
+		tmin        = 1
+		tmax        = 26
+		initialBias = 72
+		initialN    = 128
+	)
+	if !utf8.ValidString(label) {
+		return "", errors.New("invalid UTF-8")
+	}
+
+	runes := []rune(label)
+	var out []byte
+	for _, r := range runes {
+		if r < utf8.RuneSelf {
+			out = append(out, byte(r))
+		}
+	}
+	basic := len(out)
+	if basic > 0 {
+		out = append(out, '-')
+	}
+
+	n, delta, bias := rune(initialN), 0, initialBias
+	for handled := basic; handled < len(runes); {
+		next := rune(utf8.MaxRune + 1)
+		for _, r := range runes {
+			if r >= n && r < next {
+				next = r
+			}
+		}
+		delta += int(next-n) * (handled + 1)
+		n = next
+
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+			q := delta
+			for k := base; ; k += base {
+				t := k - bias
+				if t < tmin {
+					t = tmin
+				} else if t > tmax {
+					t = tmax
+				}
+				if q < t {
+					break
+				}
+				out = append(out, punycodeDigit(t+(q-t)%(base-t)))
+				q = (q - t) / (base - t)
+			}
+			out = append(out, punycodeDigit(q))
+			bias = punycodeAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return string(out), nil
+}
+
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+// punycodeAdapt is the bias adaptation function of RFC 3492.
+func punycodeAdapt(delta, points int, first bool) int {
+	const (
+		base = 36
+		tmin = 1
+		tmax = 26
+		skew = 38
+		damp = 700
+	)
+	if first {
+		delta /= damp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+	k := 0
+	for delta > (base-tmin)*tmax/2 {
+		delta /= base - tmin
+		k += base
+	}
+	return k + (base-tmin+1)*delta/(delta+skew)
+}
@@ -0,0 +1,94 @@
+package httpmock
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"https://bücher.example/path", "https://xn--bcher-kva.example/path", true},
+		{"https://BÜCHER.example/path", "https://xn--bcher-kva.example/path", true},
+		{"https://münchen.de:8443/", "https://xn--mnchen-3ya.de:8443/", true},
+		{"https://例え.jp/", "https://xn--r8jz45g.jp/", true},
+		{"HTTPS://WWW.Example.com/a", "https://www.example.com/a", true},
+		{"https://example.com/a%20b", "https://example.com/a b", true},
+		{"https://example.com/a+b", "https://example.com/a%20b", true},
+		{"https://example.com/c%2B%2B", "https://example.com/c%20%20", false},
+		{"https://example.com/c%2B%2B", "https://example.com/c%2b%2b", true},
+		{"https://example.com/%7Euser", "https://example.com/~user", true},
+		{"https://example.com/caf%C3%A9", "https://example.com/café", true},
+		{"https://example.com/a%2Fb", "https://example.com/a/b", false},
+		{"https://example.com/a%2fb", "https://example.com/a%2Fb", true},
+		{"https://example.com/a?x=1&y=2", "https://example.com/a?y=2&x=1", true},
+		{"https://example.com/a?q=a+b", "https://example.com/a?q=a%20b", true},
+		{"https://example.com/a", "https://example.com/a/", false},
+		{"https://example.com/A", "https://example.com/a", false},
+		{"https://bücher.example/path", "https://bucher.example/path", false},
+	}
+
+	for _, test := range tests {
+		if equal := normalizeURL(test.a) == normalizeURL(test.b); equal != test.equal {
+			t.Errorf("%s and %s: expected equal %v, got %q and %q", test.a, test.b, test.equal, normalizeURL(test.a), normalizeURL(test.b))
+		}
+	}
+}
+
+func TestPunycode(t *testing.T) {
+	for label, expected := range map[string]string{
+		"bücher":  "bcher-kva",
+		"münchen": "mnchen-3ya",
+		"例え":      "r8jz45g",
+		"ñandú":   "and-6ma2c",
+		"ü":       "tda",
+		"日本語":     "wgv71a119e",
+	} {
+		if got, err := punycode(label); err != nil || got != expected {
+			t.Errorf("%s: expected %q, got %q %v", label, expected, got, err)
+		}
+	}
+}
+
+func TestEquivalentURLMatching(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", "https://bücher.example/books/a b", NewStringResponder(200, ""))
+	tr.RegisterResponder("GET", "https://api.example.com/files/a%2Fb", NewStringResponder(201, ""))
+	tr.RegisterResponder("GET", "https://raw.example.com/files/a%20b", NewStringResponder(202, ""), WithRawPath())
+	client := &http.Client{Transport: tr}
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"https://xn--bcher-kva.example/books/a%20b", 200},
+		{"https://bücher.example/books/a+b?page=2", 200},
+		{"https://api.example.com/files/a%2fb", 201},
+		{"https://api.example.com/files/a/b", 0},
+		{"https://raw.example.com/files/a%20b", 202},
+		{"https://raw.example.com/files/a+b", 0},
+	}
+	for _, test := range tests {
+		resp, err := client.Get(test.url)
+		if test.status == 0 {
+			if err == nil {
+				t.Errorf("%s: expected no match, got %d", test.url, resp.StatusCode)
+			}
+			continue
+		}
+		if err != nil || resp.StatusCode != test.status {
+			t.Errorf("%s: expected %d, got %v %v", test.url, test.status, resp, err)
+		}
+	}
+
+	if counts := tr.CallCounts(); counts["GET https://bücher.example/books/a b"] != 2 {
+		t.Errorf("expected the calls counted under the registered key, got %v", counts)
+	}
+	// the keys indexed for equivalent matching go away with their registrations
+	tr.Reset()
+	if _, err := client.Get("https://xn--bcher-kva.example/books/a%20b"); err == nil {
+		t.Error("expected no match once reset")
+	}
+}
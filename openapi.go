@@ -0,0 +1,469 @@
+package httpmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NewOpenAPIResponder creates a Responder enforcing the contract of an OpenAPI 3 operation: every
+// request is validated against the operation of the document at specPath whose operationId is
+// operationID, and answered by ok if it conforms.  Requests that don't get a 400 with a JSON body
+// listing the problems:
+//
+//	{"error": "request does not match operation createPet", "details": ["query parameter \"limit\": expected an integer, got \"ten\""]}
+//
+// The method, the path (which may carry a prefix, such as the base path of a server, in front of
+// the operation's path template), the path, query, header and cookie parameters, and JSON request
+// bodies are checked.  Schemas support $ref to the components of the document, type, nullable,
+// enum, required, properties, additionalProperties: false, items, minimum, maximum, minLength,
+// maxLength and pattern; other keywords are ignored.  The document must be JSON.
+//
+// Documents are parsed once per path and cached for the life of the process.  A document that
+// can't be loaded, or that has no such operation, fails every request with an error.
+func NewOpenAPIResponder(specPath, operationID string, ok Responder) Responder {
+	doc, err := loadOpenAPI(specPath)
+	var op *openAPIOperation
+	if err == nil {
+		op, err = doc.operation(operationID)
+	}
+	if err != nil {
+		err = fmt.Errorf("httpmock: %s: %s", specPath, err)
+		return func(*http.Request) (*http.Response, error) {
+			return nil, err
+		}
+	}
+
+	return func(req *http.Request) (*http.Response, error) {
+		body, err := ReplayableRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+
+		problems := doc.validate(op, req, body)
+		setRequestBody(req, body)
+		if len(problems) == 0 {
+			return ok(req)
+		}
+		return NewJsonResponse(http.StatusBadRequest, map[string]interface{}{
+			"error":   "request does not match operation " + operationID,
+			"details": problems,
+		})
+	}
+}
+
+var openAPICache = struct {
+	sync.Mutex
+	docs map[string]*openAPIDoc
+}{docs: make(map[string]*openAPIDoc)}
+
+// loadOpenAPI parses the document at path, or returns it from the cache.
+func loadOpenAPI(path string) (*openAPIDoc, error) {
+	openAPICache.Lock()
+	defer openAPICache.Unlock()
+	if doc, ok := openAPICache.docs[path]; ok {
+		return doc, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := &openAPIDoc{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %s", err)
+	}
+	openAPICache.docs[path] = doc
+	return doc, nil
+}
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas    map[string]*jsonSchema   `json:"schemas"`
+		Parameters map[string]*openAPIParam `json:"parameters"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	OperationID string          `json:"operationId"`
+	Parameters  []*openAPIParam `json:"parameters"`
+	RequestBody *openAPIBody    `json:"requestBody"`
+
+	method     string
+	path       string
+	pathParams []*openAPIParam
+}
+
+type openAPIParam struct {
+	Ref      string      `json:"$ref"`
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *jsonSchema `json:"schema"`
+}
+
+type openAPIBody struct {
+	Required bool `json:"required"`
+	Content  map[string]struct {
+		Schema *jsonSchema `json:"schema"`
+	} `json:"content"`
+}
+
+// jsonSchema is the subset of JSON Schema NewOpenAPIResponder understands.
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Nullable             bool                   `json:"nullable"`
+	Enum                 []interface{}          `json:"enum"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+}
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// operation finds the operation with the given id.
+func (d *openAPIDoc) operation(id string) (*openAPIOperation, error) {
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		item := d.Paths[path]
+		for _, method := range openAPIMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			op := &openAPIOperation{}
+			if err := json.Unmarshal(raw, op); err != nil {
+				return nil, fmt.Errorf("invalid operation %s %s: %s", method, path, err)
+			}
+			if op.OperationID != id {
+				continue
+			}
+			op.method = strings.ToUpper(method)
+			op.path = path
+			if raw, ok := item["parameters"]; ok {
+				if err := json.Unmarshal(raw, &op.pathParams); err != nil {
+					return nil, fmt.Errorf("invalid parameters for %s: %s", path, err)
+				}
+			}
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("no operation %q", id)
+}
+
+// validate lists what is wrong with req, whose body is body, for op.
+func (d *openAPIDoc) validate(op *openAPIOperation, req *http.Request, body []byte) []string {
+	problems := []string{}
+	if req.Method != op.method {
+		problems = append(problems, fmt.Sprintf("method %s, expected %s", req.Method, op.method))
+	}
+	pathValues, ok := matchPathTemplate(op.path, req.URL.Path)
+	if !ok {
+		problems = append(problems, fmt.Sprintf("path %s does not match %s", req.URL.Path, op.path))
+	}
+
+	// operation parameters override the path level ones with the same name and location
+	params := map[string]*openAPIParam{}
+	var order []string
+	for _, p := range append(append([]*openAPIParam(nil), op.pathParams...), op.Parameters...) {
+		p = d.resolveParam(p)
+		if p == nil {
+			continue
+		}
+		id := p.In + " " + p.Name
+		if _, seen := params[id]; !seen {
+			order = append(order, id)
+		}
+		params[id] = p
+	}
+
+	query := req.URL.Query()
+	for _, id := range order {
+		p := params[id]
+		var values []string
+		switch p.In {
+		case "path":
+			if !ok {
+				continue
+			}
+			if v, found := pathValues[p.Name]; found {
+				values = []string{v}
+			}
+		case "query":
+			values = query[p.Name]
+		case "header":
+			values = req.Header.Values(p.Name)
+		case "cookie":
+			if c, err := req.Cookie(p.Name); err == nil {
+				values = []string{c.Value}
+			}
+		}
+
+		what := fmt.Sprintf("%s parameter %q", p.In, p.Name)
+		if len(values) == 0 {
+			if p.Required || p.In == "path" {
+				problems = append(problems, what+": missing")
+			}
+			continue
+		}
+		if p.Schema != nil {
+			problems = append(problems, d.validateParam(what, p.Schema, values)...)
+		}
+	}
+
+	return append(problems, d.validateBody(op.RequestBody, req, body)...)
+}
+
+// validateBody checks the request body against the request body of the operation, if any.
+func (d *openAPIDoc) validateBody(spec *openAPIBody, req *http.Request, body []byte) []string {
+	if spec == nil {
+		return nil
+	}
+	if len(body) == 0 {
+		if spec.Required {
+			return []string{"body: missing"}
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	content, ok := spec.Content[mediaType]
+	if !ok {
+		if wildcard, found := spec.Content[strings.SplitN(mediaType, "/", 2)[0]+"/*"]; found {
+			content, ok = wildcard, true
+		} else if catchAll, found := spec.Content["*/*"]; found {
+			content, ok = catchAll, true
+		}
+	}
+	if !ok {
+		return []string{fmt.Sprintf("body: unsupported content type %q", mediaType)}
+	}
+	if content.Schema == nil || !(mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return []string{fmt.Sprintf("body: invalid JSON: %s", err)}
+	}
+	return d.validateSchema("body $", content.Schema, v)
+}
+
+// validateParam checks the raw values of a parameter against its schema.
+func (d *openAPIDoc) validateParam(what string, s *jsonSchema, values []string) []string {
+	s = d.resolveSchema(s)
+	if s == nil {
+		return nil
+	}
+	if s.Type == "array" {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		var problems []string
+		for i, raw := range values {
+			problems = append(problems, d.validateSchema(fmt.Sprintf("%s[%d]", what, i), s.Items, parseParamValue(d.resolveSchema(s.Items), raw))...)
+		}
+		return problems
+	}
+	return d.validateSchema(what, s, parseParamValue(s, values[0]))
+}
+
+// parseParamValue converts a raw parameter value to the JSON value its schema expects, leaving it
+// a string when it doesn't parse so that the schema reports the mismatch.
+func parseParamValue(s *jsonSchema, raw string) interface{} {
+	if s == nil {
+		return raw
+	}
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err == nil {
+			return json.Number(raw)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+// validateSchema lists how v, a decoded JSON value found at path, departs from s.
+func (d *openAPIDoc) validateSchema(path string, s *jsonSchema, v interface{}) []string {
+	s = d.resolveSchema(s)
+	if s == nil {
+		return nil
+	}
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: expected %s, got null", path, articled(s.Type))}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return []string{fmt.Sprintf("%s: %s is not one of %s", path, encodeJSONValue(v), encodeJSONValue(s.Enum))}
+	}
+
+	var problems []string
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %s", path, encodeJSONValue(v))}
+		}
+		for _, name := range s.Required {
+			if _, found := obj[name]; !found {
+				problems = append(problems, fmt.Sprintf("%s.%s: missing", path, name))
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, known := s.Properties[name]
+			if !known {
+				if string(bytes.TrimSpace(s.AdditionalProperties)) == "false" {
+					problems = append(problems, fmt.Sprintf("%s.%s: unexpected property", path, name))
+				}
+				continue
+			}
+			problems = append(problems, d.validateSchema(path+"."+name, prop, obj[name])...)
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array, got %s", path, encodeJSONValue(v))}
+		}
+		for i, item := range arr {
+			problems = append(problems, d.validateSchema(fmt.Sprintf("%s[%d]", path, i), s.Items, item)...)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected a string, got %s", path, encodeJSONValue(v))}
+		}
+		length := len([]rune(str))
+		if s.MinLength != nil && length < *s.MinLength {
+			problems = append(problems, fmt.Sprintf("%s: %q is shorter than %d", path, str, *s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			problems = append(problems, fmt.Sprintf("%s: %q is longer than %d", path, str, *s.MaxLength))
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(str) {
+				problems = append(problems, fmt.Sprintf("%s: %q does not match %s", path, str, s.Pattern))
+			}
+		}
+	case "integer", "number":
+		n, ok := v.(json.Number)
+		f, err := n.Float64()
+		if !ok || err != nil || (s.Type == "integer" && f != math.Trunc(f)) {
+			return []string{fmt.Sprintf("%s: expected %s, got %s", path, articled(s.Type), encodeJSONValue(v))}
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			problems = append(problems, fmt.Sprintf("%s: %s is less than %v", path, n, *s.Minimum))
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			problems = append(problems, fmt.Sprintf("%s: %s is greater than %v", path, n, *s.Maximum))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected a boolean, got %s", path, encodeJSONValue(v))}
+		}
+	}
+	return problems
+}
+
+// resolveSchema follows the $ref of s, if any, to the components of the document.
+func (d *openAPIDoc) resolveSchema(s *jsonSchema) *jsonSchema {
+	for i := 0; s != nil && s.Ref != "" && i < 32; i++ {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// resolveParam follows the $ref of p, if any, to the components of the document.
+func (d *openAPIDoc) resolveParam(p *openAPIParam) *openAPIParam {
+	if p != nil && p.Ref != "" {
+		return d.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+	}
+	return p
+}
+
+// matchPathTemplate matches path against an OpenAPI path template such as "/pets/{petId}",
+// allowing path to carry a prefix in front of it, and returns the values of the template
+// parameters.
+func matchPathTemplate(template, path string) (map[string]string, bool) {
+	tmpl := strings.Split(strings.Trim(template, "/"), "/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < len(tmpl) {
+		return nil, false
+	}
+	segments = segments[len(segments)-len(tmpl):]
+
+	values := map[string]string{}
+	for i, part := range tmpl {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			values[part[1:len(part)-1]] = segments[i]
+			continue
+		}
+		if part != segments[i] {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+		// enum values are decoded without UseNumber
+		if n, ok := v.(json.Number); ok {
+			if f, ok := e.(float64); ok {
+				if g, err := n.Float64(); err == nil && f == g {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func articled(typ string) string {
+	if typ == "integer" || typ == "object" || typ == "array" {
+		return "an " + typ
+	}
+	return "a " + typ
+}
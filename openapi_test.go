@@ -0,0 +1,134 @@
+package httpmock
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const petstoreSpec = `{
+	"openapi": "3.0.3",
+	"paths": {
+		"/pets/{petId}/toys": {
+			"parameters": [
+				{"name": "petId", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 1}}
+			],
+			"post": {
+				"operationId": "addToy",
+				"parameters": [
+					{"$ref": "#/components/parameters/DryRun"},
+					{"name": "X-Request-Id", "in": "header", "required": true, "schema": {"type": "string"}}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Toy"}}}
+				}
+			}
+		}
+	},
+	"components": {
+		"parameters": {
+			"DryRun": {"name": "dryRun", "in": "query", "schema": {"type": "boolean"}}
+		},
+		"schemas": {
+			"Toy": {
+				"type": "object",
+				"required": ["name", "kind"],
+				"additionalProperties": false,
+				"properties": {
+					"name": {"type": "string", "minLength": 1},
+					"kind": {"type": "string", "enum": ["ball", "rope"]},
+					"tags": {"type": "array", "items": {"type": "string"}}
+				}
+			}
+		}
+	}
+}`
+
+func TestOpenAPIResponder(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "petstore.json")
+	if err := ioutil.WriteFile(spec, []byte(petstoreSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	responder := NewOpenAPIResponder(spec, "addToy", NewStringResponder(201, "created"))
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		header   bool
+		problems []string
+	}{
+		{"valid", "POST", "https://api.example.com/v1/pets/12/toys?dryRun=true", `{"name": "Bob", "kind": "ball", "tags": ["red"]}`, true, nil},
+		{"wrong method", "PUT", "https://api.example.com/pets/12/toys", `{"name": "Bob", "kind": "ball"}`, true,
+			[]string{"method PUT, expected POST"}},
+		{"bad parameters", "POST", "https://api.example.com/pets/zero/toys?dryRun=maybe", `{"name": "Bob", "kind": "ball"}`, false, []string{
+			`path parameter "petId": expected an integer, got "zero"`,
+			`query parameter "dryRun": expected a boolean, got "maybe"`,
+			`header parameter "X-Request-Id": missing`,
+		}},
+		{"bad body", "POST", "https://api.example.com/pets/12/toys", `{"name": "", "tags": [1], "color": "red"}`, true, []string{
+			"body $.kind: missing",
+			"body $.color: unexpected property",
+			`body $.name: "" is shorter than 1`,
+			"body $.tags[0]: expected a string, got 1",
+		}},
+		{"missing body", "POST", "https://api.example.com/pets/12/toys", "", true, []string{"body: missing"}},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if test.header {
+			req.Header.Set("X-Request-Id", "abc")
+		}
+
+		resp, err := responder(req)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if test.problems == nil {
+			if resp.StatusCode != 201 {
+				data, _ := ioutil.ReadAll(resp.Body)
+				t.Errorf("%s: expected the request to go through, got %d %s", test.name, resp.StatusCode, data)
+			}
+			continue
+		}
+
+		var body struct {
+			Details []string `json:"details"`
+		}
+		if resp.StatusCode != 400 {
+			t.Errorf("%s: expected a 400, got %d", test.name, resp.StatusCode)
+			continue
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(body.Details, "\n") != strings.Join(test.problems, "\n") {
+			t.Errorf("%s: expected problems\n%s\ngot\n%s", test.name, strings.Join(test.problems, "\n"), strings.Join(body.Details, "\n"))
+		}
+	}
+}
+
+func TestOpenAPIResponderUnknownOperation(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "petstore.json")
+	if err := ioutil.WriteFile(spec, []byte(petstoreSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewOpenAPIResponder(spec, "deletePet", NewStringResponder(200, ""))(req); err == nil {
+		t.Fatal("expected an unknown operation to fail requests")
+	}
+}
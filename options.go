@@ -0,0 +1,312 @@
+package httpmock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ResponderOption decorates a Responder with extra behavior, such as latency.  Options are applied
+// in order, each one wrapping the result of the previous ones.
+type ResponderOption func(Responder) Responder
+
+// NewResponder creates a Responder from a given body (as a string) and status code, decorated by
+// the given options:
+//
+//	httpmock.NewResponder(200, `{"ok": true}`,
+//		httpmock.WithJitter(100*time.Millisecond, 20*time.Millisecond),
+//		httpmock.WithThrottle(1024))
+func NewResponder(status int, body string, opts ...ResponderOption) Responder {
+	return applyResponderOptions(NewStringResponder(status, body), opts)
+}
+
+func applyResponderOptions(responder Responder, opts []ResponderOption) Responder {
+	for _, opt := range opts {
+		responder = opt(responder)
+	}
+	return responder
+}
+
+// Chain creates a Responder applying middlewares around inner from left to right, each one wrapping
+// the result of the previous ones: the first middleware is the closest to inner, and the last one
+// sees requests first and responses last.  Any func(Responder) Responder works as a middleware,
+// which covers both the ResponderOption values and decorators such as WithServerTiming:
+//
+//	// the Server-Timing header includes the delay
+//	httpmock.Chain(inner, httpmock.WithFixedDelay(100*time.Millisecond), httpmock.WithServerTiming)
+//
+//	// the trailer is filled in once the throttled body has been read
+//	httpmock.Chain(inner, httpmock.WithTrailer("Grpc-Status", "0"), httpmock.WithThrottle(1024))
+func Chain(inner Responder, middlewares ...func(Responder) Responder) Responder {
+	for _, middleware := range middlewares {
+		inner = middleware(inner)
+	}
+	return inner
+}
+
+// WithFixedDelay waits d, as measured by the package Clock, before responding.  The wait ends
+// early with the context's error if the request is canceled.
+func WithFixedDelay(d time.Duration) ResponderOption {
+	return func(inner Responder) Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			if err := sleepContext(req, d); err != nil {
+				return nil, err
+			}
+			return inner(req)
+		}
+	}
+}
+
+// WithJitter waits a random duration between base-jitter and base+jitter before responding.  The
+// wait ends early with the context's error if the request is canceled.
+func WithJitter(base, jitter time.Duration) ResponderOption {
+	return func(inner Responder) Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			d := base
+			if jitter > 0 {
+				d += time.Duration(randInt63n(req, int64(2*jitter+1))) - jitter
+			}
+			if err := sleepContext(req, d); err != nil {
+				return nil, err
+			}
+			return inner(req)
+		}
+	}
+}
+
+// WithHeaderDelay simulates a slow server: it waits d, as measured by the package Clock, before
+// the responder returns, delaying the status line and headers.  It is WithFixedDelay under a name
+// telling it apart from WithBodyDelay.
+func WithHeaderDelay(d time.Duration) ResponderOption {
+	return WithFixedDelay(d)
+}
+
+// WithBodyDelay simulates a slow network: the headers arrive right away and the first byte of the
+// body too, but the rest of it is spread evenly over total, as measured by the package Clock, so
+// reading it up to io.EOF takes total whatever its length.  Reads fail with the context's error if
+// the request is canceled in the meantime.
+func WithBodyDelay(total time.Duration) ResponderOption {
+	return func(inner Responder) Responder {
+		if total <= 0 {
+			return inner
+		}
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := inner(req)
+			if err != nil || resp == nil || resp.Body == nil {
+				return resp, err
+			}
+
+			data, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+
+			ctx := context.Background()
+			if req != nil {
+				ctx = req.Context()
+			}
+			delayed := *resp
+			delayed.Body = deferTrailer(&delayed, &delayedBody{ctx: ctx, data: data, total: total})
+			delayed.ContentLength = int64(len(data))
+			return &delayed, nil
+		}
+	}
+}
+
+// deferTrailer returns body filling in the trailers of resp once read up to io.EOF, and gives resp
+// a Trailer only declaring them, for the options reading the whole inner body up front.
+func deferTrailer(resp *http.Response, body io.ReadCloser) io.ReadCloser {
+	if len(resp.Trailer) == 0 {
+		return body
+	}
+	deferred := &trailerBody{ReadCloser: body, trailer: http.Header{}, values: http.Header{}}
+	for key, values := range resp.Trailer {
+		deferred.trailer[key] = nil
+		deferred.values[key] = append([]string(nil), values...)
+	}
+	resp.Trailer = deferred.trailer
+	return deferred
+}
+
+// delayedBody delivers byte i of data once i/len(data) of total has elapsed, and io.EOF once total
+// has.
+type delayedBody struct {
+	ctx     context.Context
+	data    []byte
+	total   time.Duration
+	pos     int
+	elapsed time.Duration
+}
+
+// offset returns when byte i, or io.EOF for i == len(data), is due.
+func (b *delayedBody) offset(i int) time.Duration {
+	if len(b.data) == 0 {
+		return b.total
+	}
+	return time.Duration(float64(b.total) * float64(i) / float64(len(b.data)))
+}
+
+func (b *delayedBody) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if due := b.offset(b.pos); due > b.elapsed {
+		select {
+		case <-getClock().After(due - b.elapsed):
+		case <-b.ctx.Done():
+			return 0, b.ctx.Err()
+		}
+		b.elapsed = due
+	}
+	if b.pos == len(b.data) {
+		return 0, io.EOF
+	}
+
+	// hand out every byte already due
+	n := 1
+	for n < len(p) && b.pos+n < len(b.data) && b.offset(b.pos+n) <= b.elapsed {
+		n++
+	}
+	copy(p, b.data[b.pos:b.pos+n])
+	b.pos += n
+	return n, nil
+}
+
+func (b *delayedBody) Close() error {
+	return nil
+}
+
+// WithThrottle slows the delivery of the response body down to bps bytes per second, as measured
+// by the package Clock.  A bps of zero or less leaves the body alone.
+func WithThrottle(bps int) ResponderOption {
+	return func(inner Responder) Responder {
+		if bps <= 0 {
+			return inner
+		}
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := inner(req)
+			if err != nil || resp == nil || resp.Body == nil {
+				return resp, err
+			}
+
+			throttled := *resp
+			throttled.Body = throttleBody(resp.Body, bps)
+			return &throttled, nil
+		}
+	}
+}
+
+// throttleBody slows body down to bps bytes per second, keeping it rewindable when it was.
+func throttleBody(body io.ReadCloser, bps int) io.ReadCloser {
+	delay := time.Second / time.Duration(bps)
+	if d, ok := body.(*dummyReadCloser); ok {
+		return &dummyReadCloser{throttledReader{r: d.body, delay: delay}}
+	}
+	return &throttledReadCloser{
+		Reader: throttledReader{r: body, delay: delay},
+		Closer: body,
+	}
+}
+
+// throttledReader hands out one byte per delay, waiting on the package Clock.
+type throttledReader struct {
+	delay time.Duration
+	r     io.Reader
+}
+
+func (tr throttledReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	<-getClock().After(tr.delay)
+	return tr.r.Read(p[:1])
+}
+
+// Seek rewinds the underlying reader when it supports it.
+func (tr throttledReader) Seek(offset int64, whence int) (int64, error) {
+	if seeker, ok := tr.r.(io.Seeker); ok {
+		return seeker.Seek(offset, whence)
+	}
+	return 0, errors.New("httpmock: throttled body is not seekable")
+}
+
+type throttledReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// sleepContext waits d, as measured by the package Clock, unless the request's context is done
+// first, in which case the context's error is returned.
+func sleepContext(req *http.Request, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-getClock().After(d):
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// WithTrailer adds a trailer to the response.  As with net/http, the key shows up in the
+// response's Trailer right away, but its value is only filled in once the body has been read up to
+// io.EOF.  Every call gets its own Trailer map, filled in by its own body.
+func WithTrailer(key, value string) ResponderOption {
+	key = http.CanonicalHeaderKey(key)
+	return func(inner Responder) Responder {
+		return decorateResponse(inner, func(resp *http.Response) {
+			body, ok := resp.Body.(*trailerBody)
+			if !ok {
+				// trailerBody values are created for each call, anything else may be shared
+				body = &trailerBody{ReadCloser: resp.Body, trailer: resp.Trailer.Clone(), values: http.Header{}}
+				if body.ReadCloser == nil {
+					body.ReadCloser = http.NoBody
+				}
+				if body.trailer == nil {
+					body.trailer = http.Header{}
+				}
+				resp.Body = body
+			}
+			body.trailer[key] = nil
+			body.values.Add(key, value)
+			resp.Trailer = body.trailer
+		})
+	}
+}
+
+// trailerBody fills in trailer with values once the body has been read up to io.EOF.
+type trailerBody struct {
+	io.ReadCloser
+	trailer http.Header
+	values  http.Header
+	once    sync.Once
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(func() {
+			for key, values := range b.values {
+				b.trailer[key] = values
+			}
+		})
+	}
+	return n, err
+}
+
+// WithHTTP2 makes the response look like it came over HTTP/2, setting its Proto fields.
+func WithHTTP2() ResponderOption {
+	return func(inner Responder) Responder {
+		return decorateResponse(inner, func(resp *http.Response) {
+			resp.Proto = "HTTP/2.0"
+			resp.ProtoMajor = 2
+			resp.ProtoMinor = 0
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package httpmock
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewResponderFixedDelay(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	responder := NewResponder(200, "hello", WithFixedDelay(time.Minute))
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan *http.Response)
+	go func() {
+		resp, _ := responder(req)
+		done <- resp
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected the responder to wait for the clock")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Minute)
+	resp := <-done
+	if resp == nil || resp.StatusCode != 200 {
+		t.Fatalf("expected a 200 once the delay elapsed, got %v", resp)
+	}
+}
+
+func TestNewResponderDelayCanceled(t *testing.T) {
+	responder := NewResponder(200, "hello", WithFixedDelay(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	if _, err := responder(req); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNewResponderJitterAndThrottle(t *testing.T) {
+	responder := NewResponder(200, "0123456789",
+		WithJitter(20*time.Millisecond, 10*time.Millisecond),
+		WithThrottle(500))
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	resp, err := responder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Fatalf("expected at least base-jitter of delay, got %s", elapsed)
+	}
+
+	start = time.Now()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "0123456789" {
+		t.Fatalf("expected the full body, got %q", data)
+	}
+	// 10 bytes at 500 bytes per second
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected the body to be throttled, read it in %s", elapsed)
+	}
+}
+
+func TestWithThrottleDisabled(t *testing.T) {
+	for _, bps := range []int{0, -1} {
+		resp, err := NewResponder(200, "x", WithThrottle(bps))(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := ioutil.ReadAll(resp.Body); string(data) != "x" {
+			t.Fatalf("WithThrottle(%d): expected the body untouched, got %q", bps, data)
+		}
+	}
+}
+
+func TestWithThrottleMockClock(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	resp, err := NewResponder(200, "ab", WithThrottle(1))(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(resp.Body)
+		done <- data
+	}()
+
+	for i := 0; i < 3; i++ {
+		// one byte per second, and a last read to get io.EOF
+		waitForClockWaiters(t, clock, 1)
+		clock.Advance(time.Second)
+	}
+	if data := <-done; string(data) != "ab" {
+		t.Fatalf("expected the body once the clock moved, got %q", data)
+	}
+}
+
+// waitForClockWaiters waits until n channels returned by clock.After are pending.
+func waitForClockWaiters(t *testing.T, clock *MockClock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		clock.mu.Lock()
+		pending := len(clock.waiters)
+		clock.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d pending waiters on the clock, got %d", n, pending)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWithTrailerAndHTTP2(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", testUrl, NewResponder(200, "payload",
+		WithTrailer("grpc-status", "0"),
+		WithTrailer("grpc-message", "ok"),
+		WithHTTP2()))
+	client := &http.Client{Transport: tr}
+
+	first, err := client.Post(testUrl, "application/grpc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.Post(testUrl, "application/grpc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ProtoMajor != 2 || first.Proto != "HTTP/2.0" {
+		t.Fatalf("expected an HTTP/2 response, got %s", first.Proto)
+	}
+
+	if _, ok := first.Trailer["Grpc-Status"]; !ok {
+		t.Fatalf("expected the trailer to be declared up front, got %v", first.Trailer)
+	}
+	buf := make([]byte, 3)
+	if _, err := first.Body.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	if v := first.Trailer.Get("Grpc-Status"); v != "" {
+		t.Fatalf("expected no trailer value before EOF, got %q", v)
+	}
+
+	if _, err := ioutil.ReadAll(first.Body); err != nil {
+		t.Fatal(err)
+	}
+	if first.Trailer.Get("Grpc-Status") != "0" || first.Trailer.Get("Grpc-Message") != "ok" {
+		t.Fatalf("expected the trailers after EOF, got %v", first.Trailer)
+	}
+	if v := second.Trailer.Get("Grpc-Status"); v != "" {
+		t.Fatalf("expected the second call to have its own trailer, got %q", v)
+	}
+}
+
+func TestWithTrailerWrappedBody(t *testing.T) {
+	// the body filling in the trailer is wrapped by the next option
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewResponder(200, "ok",
+		WithTrailer("Grpc-Status", "0"), WithThrottle(1<<20)))
+	client := &http.Client{Transport: tr}
+
+	first, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := first.Trailer["Grpc-Status"]; !ok || first.Trailer.Get("Grpc-Status") != "" {
+		t.Fatalf("expected the trailer declared without a value before EOF, got %v", first.Trailer)
+	}
+	if _, err := ioutil.ReadAll(first.Body); err != nil {
+		t.Fatal(err)
+	}
+	if v := first.Trailer.Get("Grpc-Status"); v != "0" {
+		t.Fatalf("expected the trailer after EOF, got %q", v)
+	}
+	if v := second.Trailer.Get("Grpc-Status"); v != "" {
+		t.Fatalf("expected the second call to have its own trailer, got %q", v)
+	}
+}
+
+func TestWithTrailerBodyDelay(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	// WithBodyDelay reads the body carrying the trailer up front, the trailer still waits for EOF
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewResponder(200, "ok",
+		WithTrailer("Grpc-Status", "0"), WithBodyDelay(2*time.Second)))
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.Trailer["Grpc-Status"]; !ok || resp.Trailer.Get("Grpc-Status") != "" {
+		t.Fatalf("expected the trailer declared without a value before EOF, got %v", resp.Trailer)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := ioutil.ReadAll(resp.Body)
+		done <- err
+	}()
+	// one second for the second byte, and one for io.EOF
+	for i := 0; i < 2; i++ {
+		waitForClockWaiters(t, clock, 1)
+		clock.Advance(time.Second)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if v := resp.Trailer.Get("Grpc-Status"); v != "0" {
+		t.Fatalf("expected the trailer after EOF, got %q", v)
+	}
+}
+
+func TestHeaderAndBodyDelay(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewMockClock(start)
+	SetClock(clock)
+	defer SetClock(nil)
+
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewResponder(200, "abcd",
+		WithHeaderDelay(time.Second), WithBodyDelay(4*time.Second)))
+	client := &http.Client{Transport: tr}
+
+	var firstByte time.Time
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { firstByte = clock.Now() },
+	})
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan []byte)
+	go func() {
+		resp, err := client.Do(req)
+		if err != nil {
+			done <- nil
+			return
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		done <- data
+	}()
+
+	// one second for the headers, then one per byte after the first, including io.EOF
+	for i := 0; i < 5; i++ {
+		waitForClockWaiters(t, clock, 1)
+		clock.Advance(time.Second)
+	}
+	if data := <-done; string(data) != "abcd" {
+		t.Fatalf("expected the full body, got %q", data)
+	}
+	if got := firstByte.Sub(start); got != time.Second {
+		t.Errorf("expected the first byte after the header delay, got it after %s", got)
+	}
+	if got := clock.Now().Sub(start); got != 5*time.Second {
+		t.Errorf("expected the body to take the body delay, took %s", got)
+	}
+}
+
+func TestBodyDelayCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := NewResponder(200, "abcd", WithBodyDelay(time.Hour))(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContentLength != 4 {
+		t.Errorf("expected the body length to be known, got %d", resp.ContentLength)
+	}
+	cancel()
+	if _, err := ioutil.ReadAll(resp.Body); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	middleware := func(name string) func(Responder) Responder {
+		return func(inner Responder) Responder {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+" in")
+				resp, err := inner(req)
+				order = append(order, name+" out")
+				return resp, err
+			}
+		}
+	}
+
+	inner := func(req *http.Request) (*http.Response, error) {
+		order = append(order, "inner")
+		return NewStringResponse(200, "ok"), nil
+	}
+	responder := Chain(inner, middleware("first"), middleware("second"), WithServerTiming, WithHTTP2())
+
+	resp, err := responder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"second in", "first in", "inner", "first out", "second out"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("expected %q, got %q", expected, order)
+	}
+	if resp.Header.Get("Server-Timing") == "" || resp.ProtoMajor != 2 {
+		t.Fatalf("expected the decorators to apply, got %v %s", resp.Header, resp.Proto)
+	}
+
+	if Chain(nil) != nil {
+		t.Fatal("expected no middleware to leave inner alone")
+	}
+}
+
+func TestChainTrailerThrottle(t *testing.T) {
+	// the example of the Chain documentation
+	inner := NewStringResponder(200, "ok")
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl,
+		Chain(inner, WithTrailer("Grpc-Status", "0"), WithThrottle(1024)))
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := resp.Trailer.Get("Grpc-Status"); v != "" {
+		t.Fatalf("expected no trailer value before the body is read, got %q", v)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil || string(data) != "ok" {
+		t.Fatalf("expected the whole body, got %q, %v", data, err)
+	}
+	if v := resp.Trailer.Get("Grpc-Status"); v != "0" {
+		t.Fatalf("expected the trailer once the throttled body has been read, got %q", v)
+	}
+}
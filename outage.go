@@ -0,0 +1,99 @@
+package httpmock
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OutagePhase describes where an OutageScenario stands.
+type OutagePhase string
+
+const (
+	// OutagePending is the phase of an OutageScenario that hasn't been called yet.
+	OutagePending OutagePhase = "pending"
+	// OutageOngoing is the phase of an OutageScenario whose outage is under way.
+	OutageOngoing OutagePhase = "ongoing"
+	// OutageRecovered is the phase of an OutageScenario whose outage is over.
+	OutageRecovered OutagePhase = "recovered"
+)
+
+// OutageScenario simulates a dependency going down for a while then recovering, to test the
+// resilience of a client.  The outage starts with the first call, whenever the scenario was
+// registered, and lasts for a span of time measured by a Clock, so that a test using a MockClock
+// can move past it without sleeping.  It is created with NewOutageScenario and registered through
+// its Respond method:
+//
+//	clock := httpmock.NewMockClock(time.Now())
+//	outage := httpmock.NewOutageScenario(clock, 30*time.Second, nil, httpmock.NewStringResponder(200, "ok"))
+//	httpmock.RegisterResponder("GET", "https://api.mybiz.com/status", outage.Respond)
+type OutageScenario struct {
+	clock  Clock
+	outage time.Duration
+	during Responder
+	after  Responder
+
+	mu        sync.Mutex
+	started   bool
+	start     time.Time
+	recovered bool
+}
+
+// NewOutageScenario creates an OutageScenario answering with during for outage after its first
+// call, and with after from then on.  A nil during answers with a 503 whose Retry-After header
+// gives the seconds left until the recovery, rounded up.  A nil clock stands for the package Clock.
+func NewOutageScenario(clock Clock, outage time.Duration, during Responder, after Responder) *OutageScenario {
+	return &OutageScenario{clock: clock, outage: outage, during: during, after: after}
+}
+
+// Respond is the Responder of the OutageScenario.
+func (o *OutageScenario) Respond(req *http.Request) (*http.Response, error) {
+	t := o.now()
+
+	o.mu.Lock()
+	if !o.started {
+		o.started, o.start = true, t
+	}
+	// the clock may be set back, but an outage over stays over
+	remaining := o.start.Add(o.outage).Sub(t)
+	if remaining <= 0 {
+		o.recovered = true
+	}
+	recovered := o.recovered
+	o.mu.Unlock()
+
+	if recovered {
+		return o.after(req)
+	}
+	if o.during != nil {
+		return o.during(req)
+	}
+	resp := NewStringResponse(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable))
+	resp.Header.Set("Retry-After", strconv.FormatInt(int64(math.Ceil(remaining.Seconds())), 10))
+	return resp, nil
+}
+
+// Phase returns where the OutageScenario stands, according to its clock.
+func (o *OutageScenario) Phase() OutagePhase {
+	t := o.now()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch {
+	case !o.started:
+		return OutagePending
+	case o.recovered || !t.Before(o.start.Add(o.outage)):
+		return OutageRecovered
+	default:
+		return OutageOngoing
+	}
+}
+
+func (o *OutageScenario) now() time.Time {
+	if o.clock == nil {
+		return now()
+	}
+	return o.clock.Now()
+}
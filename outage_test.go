@@ -0,0 +1,65 @@
+package httpmock
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutageScenario(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	outage := NewOutageScenario(clock, 30*time.Second, nil, NewStringResponder(200, "ok"))
+
+	call := func() *http.Response {
+		req, err := http.NewRequest("GET", testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := outage.Respond(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// the outage starts with the first call, not with the scenario
+	clock.Advance(time.Hour)
+	if phase := outage.Phase(); phase != OutagePending {
+		t.Errorf("expected the outage to be pending, got %s", phase)
+	}
+
+	resp := call()
+	if resp.StatusCode != 503 || resp.Header.Get("Retry-After") != "30" {
+		t.Errorf("expected a 503 retrying after 30s, got %d after %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if phase := outage.Phase(); phase != OutageOngoing {
+		t.Errorf("expected the outage to be ongoing, got %s", phase)
+	}
+
+	clock.Advance(20500 * time.Millisecond)
+	if resp := call(); resp.Header.Get("Retry-After") != "10" {
+		t.Errorf("expected the remaining outage to be rounded up, got %q", resp.Header.Get("Retry-After"))
+	}
+
+	clock.Advance(9500 * time.Millisecond)
+	if phase := outage.Phase(); phase != OutageRecovered {
+		t.Errorf("expected the outage to be over, got %s", phase)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp := call(); resp.StatusCode != 200 {
+				t.Errorf("expected the dependency to have recovered, got %d", resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	clock.Advance(-time.Minute)
+	if resp := call(); resp.StatusCode != 200 {
+		t.Errorf("expected the recovery to stick, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,82 @@
+package httpmock
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NewPaginatedResponder creates a Responder serving items one page of pageSize at a time, as a JSON
+// array, the page number being read from the query parameter pageParam.  Pages are numbered from
+// 1, which is the page served when the parameter is missing.  Each page comes with a Link header
+// (RFC 8288) pointing at the first, last, and where they exist previous and next pages, as well as
+// an X-Total-Count header giving the number of items:
+//
+//	Link: <https://api.mybiz.com/articles?page=3>; rel="next", <https://api.mybiz.com/articles?page=1>; rel="prev", ...
+//
+// Pages past the last one are empty, still with a 200.  A page number that isn't a positive
+// integer gets a 400 Bad Request.  A pageSize of zero or less serves every item on a single page.
+func NewPaginatedResponder(items []interface{}, pageSize int, pageParam string) Responder {
+	if pageSize <= 0 {
+		pageSize = len(items)
+		if pageSize == 0 {
+			pageSize = 1
+		}
+	}
+	last := (len(items) + pageSize - 1) / pageSize
+	if last == 0 {
+		last = 1
+	}
+
+	return func(req *http.Request) (*http.Response, error) {
+		page := 1
+		if value := req.URL.Query().Get(pageParam); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return NewStringResponse(http.StatusBadRequest, fmt.Sprintf("invalid page %q", value)), nil
+			}
+			page = n
+		}
+
+		// past the last page, (page-1)*pageSize could overflow
+		content := []interface{}{}
+		if start := (page - 1) * pageSize; page <= last && start < len(items) {
+			end := start + pageSize
+			if end > len(items) {
+				end = len(items)
+			}
+			content = items[start:end]
+		}
+		resp, err := NewJsonResponse(http.StatusOK, content)
+		if err != nil {
+			return nil, err
+		}
+
+		pageURL := func(n int) string {
+			u := *req.URL
+			query := u.Query()
+			query.Set(pageParam, strconv.Itoa(n))
+			u.RawQuery = query.Encode()
+			return u.String()
+		}
+		var links []string
+		if page < last {
+			links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(page+1)))
+		}
+		if page > 1 {
+			prev := page - 1
+			if prev > last {
+				prev = last
+			}
+			links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(prev)))
+		}
+		links = append(links,
+			fmt.Sprintf(`<%s>; rel="first"`, pageURL(1)),
+			fmt.Sprintf(`<%s>; rel="last"`, pageURL(last)))
+
+		resp.Header.Set("Link", strings.Join(links, ", "))
+		resp.Header.Set("X-Total-Count", strconv.Itoa(len(items)))
+		return resp, nil
+	}
+}
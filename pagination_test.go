@@ -0,0 +1,80 @@
+package httpmock
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestPaginatedResponder(t *testing.T) {
+	items := make([]interface{}, 7)
+	for i := range items {
+		items[i] = i + 1
+	}
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", "http://api.example.com/items", NewPaginatedResponder(items, 3, "page"))
+	client := &http.Client{Transport: tr}
+
+	get := func(url string) (*http.Response, []int) {
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var page []int
+		if resp.StatusCode == 200 {
+			if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp, page
+	}
+
+	// walk the pages the way a client would, following the next links
+	nextLink := regexp.MustCompile(`<([^>]*)>; rel="next"`)
+	var walked []int
+	pages := 0
+	for url := "http://api.example.com/items?sort=asc"; url != ""; pages++ {
+		resp, page := get(url)
+		if total := resp.Header.Get("X-Total-Count"); total != "7" {
+			t.Errorf("expected a total count of 7, got %q", total)
+		}
+		walked = append(walked, page...)
+		url = ""
+		if m := nextLink.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			url = m[1]
+		}
+	}
+	if pages != 3 || !reflect.DeepEqual(walked, []int{1, 2, 3, 4, 5, 6, 7}) {
+		t.Errorf("expected 3 pages with every item, got %d pages with %v", pages, walked)
+	}
+
+	resp, page := get("http://api.example.com/items?page=2&sort=asc")
+	expected := `<http://api.example.com/items?page=3&sort=asc>; rel="next", ` +
+		`<http://api.example.com/items?page=1&sort=asc>; rel="prev", ` +
+		`<http://api.example.com/items?page=1&sort=asc>; rel="first", ` +
+		`<http://api.example.com/items?page=3&sort=asc>; rel="last"`
+	if link := resp.Header.Get("Link"); link != expected {
+		t.Errorf("expected Link\n%s\ngot\n%s", expected, link)
+	}
+	if !reflect.DeepEqual(page, []int{4, 5, 6}) {
+		t.Errorf("expected the second page, got %v", page)
+	}
+
+	resp, page = get("http://api.example.com/items?page=9")
+	if resp.StatusCode != 200 || len(page) != 0 || page == nil {
+		t.Errorf("expected an empty page past the last one, got %d %v", resp.StatusCode, page)
+	}
+
+	// a page number so large that its offset overflows is just past the last page too
+	resp, page = get("http://api.example.com/items?page=4611686018427387905")
+	if resp.StatusCode != 200 || len(page) != 0 || page == nil {
+		t.Errorf("expected an empty page for a huge page number, got %d %v", resp.StatusCode, page)
+	}
+
+	if resp, _ = get("http://api.example.com/items?page=0"); resp.StatusCode != 400 {
+		t.Errorf("expected an invalid page to get a 400, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,131 @@
+package httpmock
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Profile describes the network conditions of a host, see MockTransport.SetHostProfile.
+type Profile struct {
+	// Latency is the average delay added before every response, and Jitter how far it may stray
+	// from it: each delay is drawn from [Latency-Jitter, Latency+Jitter], and never less than zero.
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// FailureRate is the probability, from 0 to 1, that a request fails after the delay instead
+	// of reaching its responder.
+	FailureRate float64
+	// Failure answers the failing requests.  It defaults to NewConnRefusedResponder().
+	Failure Responder
+}
+
+// ProfileMetrics can be implemented by a Metrics to be told, separately from ObserveRequest,
+// what the host profiles did.  pattern is the host pattern of the profile that applied, latency
+// the delay it added and failed whether it made the request fail.
+type ProfileMetrics interface {
+	ObserveProfile(pattern string, latency time.Duration, failed bool)
+}
+
+// SetHostProfile applies p to every request to the hosts matching hostPattern that reaches a
+// registration, or the no responder, describing the network environment once for all of them:
+//
+//	tr.SetHostProfile("payments.example.com", httpmock.Profile{Latency: 300 * time.Millisecond, FailureRate: 0.02})
+//	tr.SetHostProfile("*", httpmock.Profile{Latency: 20 * time.Millisecond})
+//
+// hostPattern follows the same rules as the host of SimulateHostError.  When several patterns
+// match, the most specific one wins: a host name over a "*." pattern, a longer pattern over a
+// shorter one, and "*" last.  The latency of a profile adds up to any delay applied by the
+// responder itself, and is measured by the package Clock.  Which profile applied, and what it did,
+// is recorded in the Call and reported to a Metrics implementing ProfileMetrics.  The random
+// draws use the package source of randomness, see SetRandSource.
+func (m *MockTransport) SetHostProfile(hostPattern string, p Profile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.profiles == nil {
+		m.profiles = make(map[string]Profile)
+	}
+	m.profiles[hostPattern] = p
+}
+
+// RemoveHostProfile undoes SetHostProfile for hostPattern.
+func (m *MockTransport) RemoveHostProfile(hostPattern string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.profiles, hostPattern)
+}
+
+// hostProfile returns the most specific profile applying to req, and its pattern.
+func (m *MockTransport) hostProfile(req *http.Request) (string, *Profile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	best, bestScore := "", -1
+	for pattern := range m.profiles {
+		if !hostMatches(pattern, req.URL) {
+			continue
+		}
+		if score := patternSpecificity(pattern); score > bestScore || (score == bestScore && pattern < best) {
+			best, bestScore = pattern, score
+		}
+	}
+	if bestScore < 0 {
+		return "", nil
+	}
+	p := m.profiles[best]
+	return best, &p
+}
+
+// patternSpecificity ranks host patterns, the higher the more specific.
+func patternSpecificity(pattern string) int {
+	switch {
+	case pattern == "*":
+		return 0
+	case strings.HasPrefix(pattern, "*."):
+		return len(pattern)
+	default:
+		return 1<<16 + len(pattern)
+	}
+}
+
+// applyProfile draws what the profile of req, if any, does to it and returns the responder to run
+// in place of responder, recording the outcome in call.
+func (m *MockTransport) applyProfile(responder Responder, req *http.Request, call *Call) Responder {
+	pattern, p := m.hostProfile(req)
+	if p == nil {
+		return responder
+	}
+
+	latency := p.Latency
+	if p.Jitter > 0 {
+		latency += time.Duration(randInt63n(req, int64(2*p.Jitter+1))) - p.Jitter
+	}
+	if latency < 0 {
+		latency = 0
+	}
+	failed := p.FailureRate > 0 && randFloat64(req) < p.FailureRate
+	if failed {
+		responder = p.Failure
+		if responder == nil {
+			responder = NewConnRefusedResponder()
+		}
+	}
+
+	m.mu.Lock()
+	call.Profile = pattern
+	call.ProfileLatency = latency
+	call.ProfileFailed = failed
+	metrics := m.metrics
+	m.mu.Unlock()
+	if pm, ok := metrics.(ProfileMetrics); ok {
+		pm.ObserveProfile(pattern, latency, failed)
+	}
+
+	inner := responder
+	return func(req *http.Request) (*http.Response, error) {
+		if err := sleepContext(req, latency); err != nil {
+			return nil, err
+		}
+		return inner(req)
+	}
+}
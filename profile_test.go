@@ -0,0 +1,108 @@
+package httpmock
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHostProfile(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	tr := NewMockTransport()
+	metrics := NewMemoryMetrics()
+	tr.SetMetrics(metrics)
+	tr.RegisterResponder("GET", "https://payments.example.com/charge", NewResponder(200, "", WithFixedDelay(time.Second)))
+	tr.RegisterResponder("GET", "https://api.example.com/users", NewStringResponder(200, ""))
+	tr.SetHostProfile("*", Profile{Latency: 20 * time.Millisecond})
+	tr.SetHostProfile("*.example.com", Profile{Latency: 50 * time.Millisecond})
+	tr.SetHostProfile("payments.example.com", Profile{Latency: 300 * time.Millisecond})
+	client := &http.Client{Transport: tr}
+
+	get := func(url string, waits ...time.Duration) {
+		t.Helper()
+		done := make(chan error)
+		go func() {
+			resp, err := client.Get(url)
+			if err == nil {
+				resp.Body.Close()
+			}
+			done <- err
+		}()
+		for _, wait := range waits {
+			waitForClockWaiters(t, clock, 1)
+			clock.Advance(wait)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the profile delay adds up to the one of the responder
+	start := clock.Now()
+	get("https://payments.example.com/charge", 300*time.Millisecond, time.Second)
+	if elapsed := clock.Now().Sub(start); elapsed != 1300*time.Millisecond {
+		t.Fatalf("expected both delays, took %s", elapsed)
+	}
+	get("https://api.example.com/users", 50*time.Millisecond)
+
+	history := tr.History()
+	if history[0].Profile != "payments.example.com" || history[0].ProfileLatency != 300*time.Millisecond {
+		t.Errorf("expected the host profile to apply, got %q %s", history[0].Profile, history[0].ProfileLatency)
+	}
+	if history[1].Profile != "*.example.com" || history[1].ProfileLatency != 50*time.Millisecond {
+		t.Errorf("expected the subdomain profile to apply, got %q %s", history[1].Profile, history[1].ProfileLatency)
+	}
+
+	profiles := metrics.ProfileSnapshot()
+	if p := profiles["payments.example.com"]; p.Count != 1 || p.Latency.Sum != 300*time.Millisecond {
+		t.Errorf("expected the profile to be reported separately, got %+v", p)
+	}
+	if key := metrics.Snapshot()["GET https://payments.example.com/charge"]; key.Count != 1 {
+		t.Errorf("expected the request to be reported too, got %+v", key)
+	}
+
+	// without its profiles, a host is back to plain behavior
+	tr.RemoveHostProfile("*.example.com")
+	tr.RemoveHostProfile("*")
+	get("https://api.example.com/users")
+	if call := tr.LastCall(); call.Profile != "" || call.ProfileLatency != 0 {
+		t.Errorf("expected no profile, got %q %s", call.Profile, call.ProfileLatency)
+	}
+}
+
+func TestHostProfileFailures(t *testing.T) {
+	SetRandSource(rand.NewSource(1))
+	defer SetRandSource(nil)
+
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""))
+	tr.SetHostProfile("www.example.com", Profile{FailureRate: 0.25, Failure: NewStringResponder(503, "")})
+	client := &http.Client{Transport: tr}
+
+	failures := 0
+	for i := 0; i < 400; i++ {
+		resp, err := client.Get(testUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == 503 {
+			failures++
+			if !tr.LastCall().ProfileFailed {
+				t.Fatal("expected the failure to be recorded")
+			}
+		}
+	}
+	if failures < 70 || failures > 130 {
+		t.Fatalf("expected about 100 failures, got %d", failures)
+	}
+
+	tr.SetHostProfile("www.example.com", Profile{FailureRate: 1})
+	if _, err := client.Get(testUrl); err == nil {
+		t.Fatal("expected the default failure to refuse the connection")
+	}
+}
@@ -0,0 +1,108 @@
+package httpmock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type proxyContextKey struct{}
+
+// SetProxy makes the MockTransport aware of proxies: proxy, typically the Proxy field of the
+// http.Transport the mock replaced or http.ProxyFromEnvironment, is asked which proxy each request
+// would have gone through.  The answer is recorded in the call history (see Call.Proxy), can be
+// matched with MatchProxy, read by responders with RequestProxy, and routes the request through
+// the proxy failures set up with SimulateProxyAuthRequired.  An error from proxy fails the request,
+// as it would with a real transport.  Pass nil, the default, to ignore proxies.
+func (m *MockTransport) SetProxy(proxy func(*http.Request) (*url.URL, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.proxy = proxy
+}
+
+// RequestProxy returns the proxy the MockTransport routed req through, or nil if the request went
+// direct or proxies are ignored (see SetProxy).
+func RequestProxy(req *http.Request) *url.URL {
+	proxy, _ := req.Context().Value(proxyContextKey{}).(*url.URL)
+	return proxy
+}
+
+// MatchProxy matches requests routed through the proxy at hostport, see SetProxy.  hostport follows
+// the same rules as the host of SimulateHostError, and an empty hostport matches the requests that
+// went direct.
+func MatchProxy(hostport string) Matcher {
+	return func(req *http.Request) error {
+		proxy := RequestProxy(req)
+		switch {
+		case proxy == nil && hostport == "":
+			return nil
+		case proxy == nil:
+			return fmt.Errorf("request was not proxied, expected proxy %s", hostport)
+		case hostport == "":
+			return fmt.Errorf("request was proxied via %s, expected no proxy", proxy.Host)
+		case !hostMatches(hostport, proxy):
+			return fmt.Errorf("request was proxied via %s, expected proxy %s", proxy.Host, hostport)
+		}
+		return nil
+	}
+}
+
+// SimulateProxyAuthRequired makes the proxy at hostport answer 407 Proxy Authentication Required,
+// with a Proxy-Authenticate header set to challenge (e.g. `Basic realm="proxy"`), to every request
+// routed through it without credentials, that is without a Proxy-Authorization header or a user in
+// the proxy URL.  Requests with credentials go on to the registrations.  hostport follows the same
+// rules as the host of SimulateHostError.  It has no effect unless a proxy func is set with
+// SetProxy.
+func (m *MockTransport) SimulateProxyAuthRequired(hostport, challenge string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.proxyChallenges == nil {
+		m.proxyChallenges = make(map[string]string)
+	}
+	m.proxyChallenges[hostport] = challenge
+}
+
+// routeThroughProxy asks the proxy func, if any, which proxy req goes through, and returns req
+// carrying the answer for RequestProxy.
+func (m *MockTransport) routeThroughProxy(req *http.Request) (*http.Request, error) {
+	m.mu.Lock()
+	proxyFunc := m.proxy
+	m.mu.Unlock()
+	if proxyFunc == nil {
+		return req, nil
+	}
+
+	proxy, err := proxyFunc(req)
+	if err != nil || proxy == nil {
+		return req, err
+	}
+	return req.WithContext(context.WithValue(req.Context(), proxyContextKey{}, proxy)), nil
+}
+
+// proxyFailure returns the responder simulating a failure of the proxy req goes through, if any.
+func (m *MockTransport) proxyFailure(req *http.Request) Responder {
+	proxy := RequestProxy(req)
+	if proxy == nil || proxy.User != nil || req.Header.Get("Proxy-Authorization") != "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for pattern, challenge := range m.proxyChallenges {
+		if hostMatches(pattern, proxy) {
+			return NewProxyAuthRequiredResponder(challenge)
+		}
+	}
+	return nil
+}
+
+// NewProxyAuthRequiredResponder creates a Responder answering 407 Proxy Authentication Required
+// with a Proxy-Authenticate header set to challenge, as a proxy asking for credentials would.
+func NewProxyAuthRequiredResponder(challenge string) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(http.StatusProxyAuthRequired, "")
+		resp.Header.Set("Proxy-Authenticate", challenge)
+		return resp, nil
+	}
+}
@@ -0,0 +1,93 @@
+package httpmock
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMockTransportProxy(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.example.com:3128")
+
+	tr := NewMockTransport()
+	tr.SetProxy(func(req *http.Request) (*url.URL, error) {
+		if req.URL.Hostname() == "internal.example.com" {
+			return nil, nil
+		}
+		return proxyURL, nil
+	})
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "proxied"), WithMatcher(MatchProxy("proxy.example.com:3128")))
+	tr.RegisterResponder("GET", "http://internal.example.com/", NewStringResponder(200, "direct"), WithMatcher(MatchProxy("")))
+	client := &http.Client{Transport: tr}
+
+	for _, u := range []string{testUrl, "http://internal.example.com/"} {
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("%s: expected the proxy matcher to accept the request, got %d", u, resp.StatusCode)
+		}
+	}
+
+	history := tr.History()
+	if history[0].Proxy == nil || history[0].Proxy.Host != "proxy.example.com:3128" {
+		t.Fatalf("expected the proxy to be recorded, got %v", history[0].Proxy)
+	}
+	if history[1].Proxy != nil {
+		t.Fatalf("expected the direct request to record no proxy, got %v", history[1].Proxy)
+	}
+
+	if err := MatchProxy("other.example.com:3128")(history[0].Request); err == nil {
+		t.Fatal("expected a matcher for another proxy to reject the request")
+	}
+}
+
+func TestMockTransportProxyAuthRequired(t *testing.T) {
+	tr := NewMockTransport()
+	tr.SetProxy(http.ProxyURL(&url.URL{Scheme: "http", Host: "proxy.example.com:3128"}))
+	tr.SimulateProxyAuthRequired("proxy.example.com:3128", `Basic realm="proxy"`)
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "ok"))
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusProxyAuthRequired || resp.Header.Get("Proxy-Authenticate") != `Basic realm="proxy"` {
+		t.Fatalf("expected a 407 with a challenge, got %d %q", resp.StatusCode, resp.Header.Get("Proxy-Authenticate"))
+	}
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Proxy-Authorization", "Basic dXNlcjpwYXNz")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected the retry with credentials to go through, got %d", resp.StatusCode)
+	}
+
+	tr.Reset()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "ok"))
+	if resp, err := client.Get(testUrl); err != nil || resp.StatusCode != 200 {
+		t.Fatalf("expected Reset to remove the proxy failure, got %v %v", resp, err)
+	}
+}
+
+func TestMockTransportProxyError(t *testing.T) {
+	tr := NewMockTransport()
+	tr.SetProxy(func(*http.Request) (*url.URL, error) {
+		return nil, errors.New("invalid proxy")
+	})
+	client := &http.Client{Transport: tr}
+
+	if _, err := client.Get(testUrl); err == nil || !strings.Contains(err.Error(), "invalid proxy") {
+		t.Fatalf("expected the proxy error, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package httpmock
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// QueryOption changes how MatchQuery compares query strings.
+type QueryOption func(*queryConfig)
+
+type queryConfig struct {
+	ordered bool
+	atLeast bool
+}
+
+// QueryOrdered makes the values of repeated keys compare in order: ?tag=a&tag=b no longer matches
+// ?tag=b&tag=a.
+func QueryOrdered() QueryOption {
+	return func(c *queryConfig) {
+		c.ordered = true
+	}
+}
+
+// QueryAtLeast makes the expected values a minimum: requests match as long as they hold every one
+// of them, whatever other keys and values they carry.  Combined with QueryOrdered, the expected
+// values of a key must show up in order, but not necessarily next to each other.
+func QueryAtLeast() QueryOption {
+	return func(c *queryConfig) {
+		c.atLeast = true
+	}
+}
+
+// MatchQuery matches requests whose query string holds the given values, however they were escaped
+// and whatever the order of the keys.  By default, the values of a repeated key compare as a
+// multiset: ?tag=a&tag=b&tag=a matches {"tag": {"a", "a", "b"}} but not {"tag": {"a", "b"}}, see
+// QueryOrdered and QueryAtLeast for other semantics.  A key without "=", as in ?flag, has an empty
+// value, like ?flag= does.
+//
+// When the query is the only reason a registration rejects a request, the error explaining why
+// no responder matched lists the parsed values of both sides.
+func MatchQuery(query url.Values, opts ...QueryOption) Matcher {
+	cfg := &queryConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	expected := copyValues(query)
+	return func(req *http.Request) error {
+		got := req.URL.Query()
+		if problem := cfg.compare(got, expected); problem != "" {
+			return &queryMismatch{problem: problem, got: got, expected: expected}
+		}
+		return nil
+	}
+}
+
+// compare returns what is wrong with the query got, or an empty string if it matches expected.
+func (c *queryConfig) compare(got, expected url.Values) string {
+	for _, key := range sortedKeys(expected) {
+		if _, ok := got[key]; !ok {
+			return fmt.Sprintf("missing key %q", key)
+		}
+		if !c.valuesMatch(got[key], expected[key]) {
+			return fmt.Sprintf("key %q has values %q, expected %q", key, got[key], expected[key])
+		}
+	}
+	if !c.atLeast {
+		for _, key := range sortedKeys(got) {
+			if _, ok := expected[key]; !ok {
+				return fmt.Sprintf("unexpected key %q", key)
+			}
+		}
+	}
+	return ""
+}
+
+// valuesMatch compares the values of a key.
+func (c *queryConfig) valuesMatch(got, expected []string) bool {
+	if !c.ordered {
+		got, expected = sortedCopy(got), sortedCopy(expected)
+	}
+	if !c.atLeast {
+		if len(got) != len(expected) {
+			return false
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// expected has to be a subsequence of got, which sorting turns into multiset inclusion
+	i := 0
+	for _, value := range got {
+		if i < len(expected) && value == expected[i] {
+			i++
+		}
+	}
+	return i == len(expected)
+}
+
+// queryMismatch is the error of a MatchQuery matcher rejecting a request.
+type queryMismatch struct {
+	problem       string
+	got, expected url.Values
+}
+
+func (e *queryMismatch) Error() string {
+	return "query " + e.problem
+}
+
+// detailed describes the mismatch along with the parsed values of both sides.
+func (e *queryMismatch) detailed() error {
+	return fmt.Errorf("%s (got %s, expected %s)", e.Error(), formatValues(e.got), formatValues(e.expected))
+}
+
+// formatValues formats values with sorted keys, as in {flag: [""], tag: ["a" "b"]}.
+func formatValues(values url.Values) string {
+	parts := make([]string, 0, len(values))
+	for _, key := range sortedKeys(values) {
+		parts = append(parts, fmt.Sprintf("%s: %q", key, values[key]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func copyValues(values url.Values) url.Values {
+	c := make(url.Values, len(values))
+	for key, v := range values {
+		c[key] = append([]string(nil), v...)
+	}
+	return c
+}
+
+// canonicalQuery encodes query with sorted keys and values, so that queries equal as multisets
+// encode the same.
+func canonicalQuery(query url.Values) string {
+	sorted := make(url.Values, len(query))
+	for key, values := range query {
+		sorted[key] = sortedCopy(values)
+	}
+	return sorted.Encode()
+}
+
+// RegisterResponderURL is RegisterResponder taking a *url.URL.  The query of u, if any, is matched
+// as with RegisterResponderWithQuery: whatever the order and escaping of the parameters.
+func (m *MockTransport) RegisterResponderURL(method string, u *url.URL, responder Responder, opts ...RegisterOption) *Registration {
+	if u.RawQuery == "" && !u.ForceQuery {
+		return m.RegisterResponder(method, u.String(), responder, opts...)
+	}
+	base := *u
+	base.RawQuery, base.ForceQuery = "", false
+	opts = append([]RegisterOption{WithMatcher(MatchQuery(u.Query()))}, opts...)
+	return m.RegisterResponder(method, base.String(), responder, opts...)
+}
+
+// RegisterResponderWithQuery registers responder for requests to baseURL whose query string holds
+// exactly the given values, whatever the order of the parameters and how they were escaped, sparing
+// the query-encoding mistakes of building URLs by concatenation.  query is a url.Values, a
+// map[string]string or a map[string][]string.  baseURL must not have a query of its own.
+func (m *MockTransport) RegisterResponderWithQuery(method, baseURL string, query interface{}, responder Responder, opts ...RegisterOption) (*Registration, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.RawQuery != "" || u.ForceQuery {
+		return nil, errors.New("httpmock: base URL already has a query: " + baseURL)
+	}
+
+	values, err := queryValues(query)
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]RegisterOption{WithMatcher(MatchQuery(values))}, opts...)
+	return m.RegisterResponder(method, baseURL, responder, opts...), nil
+}
+
+// queryValues converts the query given to RegisterResponderWithQuery to url.Values.
+func queryValues(query interface{}) (url.Values, error) {
+	switch q := query.(type) {
+	case url.Values:
+		return q, nil
+	case map[string][]string:
+		return url.Values(q), nil
+	case map[string]string:
+		values := make(url.Values, len(q))
+		for key, value := range q {
+			values.Set(key, value)
+		}
+		return values, nil
+	case nil:
+		return url.Values{}, nil
+	default:
+		return nil, fmt.Errorf("httpmock: unsupported query type %T", query)
+	}
+}
+
+// RegisterResponderURL is RegisterResponder taking a *url.URL, on the DefaultTransport.
+func RegisterResponderURL(method string, u *url.URL, responder Responder, opts ...RegisterOption) *Registration {
+	return DefaultTransport.RegisterResponderURL(method, u, responder, opts...)
+}
+
+// RegisterResponderWithQuery registers responder for requests to baseURL with the given query on
+// the DefaultTransport, see MockTransport.RegisterResponderWithQuery.
+func RegisterResponderWithQuery(method, baseURL string, query interface{}, responder Responder, opts ...RegisterOption) (*Registration, error) {
+	return DefaultTransport.RegisterResponderWithQuery(method, baseURL, query, responder, opts...)
+}
@@ -0,0 +1,166 @@
+package httpmock
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRegisterResponderWithQuery(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	_, err := tr.RegisterResponderWithQuery("GET", "http://www.example.com/search", url.Values{
+		"tag": {"b", "a"},
+		"q":   {"one two+three"},
+		"loc": {"Zürich"},
+	}, NewStringResponder(200, "values"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tr.RegisterResponderWithQuery("GET", "http://www.example.com/users", map[string]string{"name": "a&b"},
+		NewStringResponder(200, "map"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"http://www.example.com/search?tag=a&tag=b&q=one+two%2Bthree&loc=Z%C3%BCrich", 200},
+		{"http://www.example.com/search?loc=Zürich&q=one%20two%2bthree&tag=b&tag=a", 200},
+		{"http://www.example.com/search?tag=a&q=one+two%2Bthree&loc=Z%C3%BCrich", 0},
+		{"http://www.example.com/search?tag=a&tag=b&q=one+two+three&loc=Z%C3%BCrich", 0},
+		{"http://www.example.com/search", 0},
+		{"http://www.example.com/users?name=a%26b", 200},
+		{"http://www.example.com/users?name=a&b", 0},
+	}
+	for _, test := range tests {
+		resp, err := client.Get(test.url)
+		if test.status == 0 {
+			if err == nil {
+				t.Errorf("%s: expected no match, got %d", test.url, resp.StatusCode)
+			}
+			continue
+		}
+		if err != nil || resp.StatusCode != test.status {
+			t.Errorf("%s: expected %d, got %v %v", test.url, test.status, resp, err)
+		}
+	}
+
+	_, err = tr.RegisterResponderWithQuery("GET", "http://www.example.com/search?page=1", url.Values{"q": {"x"}},
+		NewStringResponder(200, ""))
+	if err == nil || !strings.Contains(err.Error(), "already has a query") {
+		t.Fatalf("expected a query in both places to be an error, got %v", err)
+	}
+	if _, err := tr.RegisterResponderWithQuery("GET", testUrl, 12, NewStringResponder(200, "")); err == nil {
+		t.Fatal("expected an unsupported query type to be an error")
+	}
+}
+
+func TestRegisterResponderURL(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	u := &url.URL{Scheme: "http", Host: "www.example.com", Path: "/a b"}
+	tr.RegisterResponderURL("GET", u, NewStringResponder(200, ""))
+
+	withQuery := &url.URL{Scheme: "http", Host: "www.example.com", Path: "/items",
+		RawQuery: url.Values{"id": {"1"}, "sort": {"name asc"}}.Encode()}
+	tr.RegisterResponderURL("GET", withQuery, NewStringResponder(201, ""))
+
+	for url, status := range map[string]int{
+		"http://www.example.com/a%20b":                      200,
+		"http://www.example.com/items?sort=name%20asc&id=1": 201,
+	} {
+		resp, err := client.Get(url)
+		if err != nil || resp.StatusCode != status {
+			t.Errorf("%s: expected %d, got %v %v", url, status, resp, err)
+		}
+	}
+}
+
+func TestMatchQuerySemantics(t *testing.T) {
+	tests := []struct {
+		expected url.Values
+		opts     []QueryOption
+		query    string
+		match    bool
+	}{
+		{url.Values{"tag": {"a", "b"}}, nil, "tag=b&tag=a", true},
+		{url.Values{"tag": {"a", "b"}}, nil, "tag=a&tag=b&tag=a", false},
+		{url.Values{"tag": {"a", "a", "b"}}, nil, "tag=a&tag=b&tag=a", true},
+		{url.Values{"tag": {"a", "b"}}, nil, "tag=a", false},
+		{url.Values{"tag": {"a", "b"}}, nil, "tag=a&tag=b&page=1", false},
+		{url.Values{"tag": {"a", "b"}}, []QueryOption{QueryOrdered()}, "tag=a&tag=b", true},
+		{url.Values{"tag": {"a", "b"}}, []QueryOption{QueryOrdered()}, "tag=b&tag=a", false},
+		{url.Values{"tag": {"a", "b"}}, []QueryOption{QueryAtLeast()}, "tag=c&tag=b&page=1&tag=a", true},
+		{url.Values{"tag": {"a", "a"}}, []QueryOption{QueryAtLeast()}, "tag=a&tag=b", false},
+		{url.Values{"tag": {"a", "b"}}, []QueryOption{QueryAtLeast(), QueryOrdered()}, "tag=a&tag=c&tag=b", true},
+		{url.Values{"tag": {"a", "b"}}, []QueryOption{QueryAtLeast(), QueryOrdered()}, "tag=b&tag=c&tag=a", false},
+		{url.Values{"flag": {""}}, nil, "flag", true},
+		{url.Values{"flag": {""}}, nil, "flag=", true},
+		{url.Values{"flag": {""}, "x": {"1"}}, nil, "x=1&flag", true},
+		{url.Values{"flag": {""}}, nil, "flag=on", false},
+		{url.Values{"flag": {"", ""}}, nil, "flag&flag=", true},
+		{url.Values{}, nil, "", true},
+		{url.Values{}, nil, "flag", false},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", testUrl+"?"+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = MatchQuery(test.expected, test.opts...)(req)
+		if (err == nil) != test.match {
+			t.Errorf("%v against %q: expected match %v, got %v", test.expected, test.query, test.match, err)
+		}
+	}
+}
+
+func TestMatchQueryDiagnostics(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""),
+		WithMatcher(MatchQuery(url.Values{"tag": {"a", "b"}, "flag": {""}})))
+	client := &http.Client{Transport: tr}
+
+	_, err := client.Get(testUrl + "?tag=a&flag")
+	expected := `query key "tag" has values ["a"], expected ["a" "b"] (got {flag: [""], tag: ["a"]}, expected {flag: [""], tag: ["a" "b"]})`
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected the parsed queries in the error, got %v", err)
+	}
+
+	// another reason for the mismatch takes over
+	tr.Reset()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""),
+		WithMatcher(MatchQuery(url.Values{"tag": {"a", "b"}}), func(req *http.Request) error {
+			return errors.New("missing X-Test header")
+		}))
+	_, err = client.Get(testUrl + "?tag=a")
+	if err == nil || !strings.Contains(err.Error(), "missing X-Test header") || strings.Contains(err.Error(), "(got {tag") {
+		t.Fatalf("expected the other matcher to explain the mismatch, got %v", err)
+	}
+}
+
+func TestRegisteredQueryAsMultiset(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl+"?tag=a&tag=b&page=1", NewStringResponder(200, ""))
+	client := &http.Client{Transport: tr}
+
+	for _, query := range []string{"tag=a&tag=b&page=1", "page=1&tag=b&tag=a", "tag=a&page=1&tag=b"} {
+		resp, err := client.Get(testUrl + "?" + query)
+		if err != nil || resp.StatusCode != 200 {
+			t.Errorf("%s: expected a match, got %v %v", query, resp, err)
+		}
+	}
+	if _, err := client.Get(testUrl + "?tag=a&page=1"); err == nil {
+		t.Error("expected a missing value not to match")
+	}
+	if counts := tr.CallCounts(); counts["GET "+testUrl+"?tag=a&tag=b&page=1"] != 3 {
+		t.Errorf("expected the calls counted under the registered key, got %v", counts)
+	}
+}
@@ -0,0 +1,79 @@
+package httpmock
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// lockedRand is a source of randomness that is safe for concurrent use.
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand(src rand.Source) *lockedRand {
+	return &lockedRand{r: rand.New(src)}
+}
+
+// Int63n returns a random number in [0, n).
+func (l *lockedRand) Int63n(n int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Int63n(n)
+}
+
+// Float64 returns a random number in [0, 1).
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Float64()
+}
+
+var packageRand = newLockedRand(rand.NewSource(time.Now().UnixNano()))
+
+// SetRandSource replaces the source of randomness used by the randomized responders and options of
+// the package, such as WithJitter.  Seeding it, as in SetRandSource(rand.NewSource(42)), makes
+// their outcomes the same on every run, given the same sequence of requests.  Passing nil restores
+// a source seeded with the current time.  The source is only ever used behind a mutex, so it
+// doesn't have to be safe for concurrent use.  The requests of a MockTransport in chaos mode draw
+// from the source of the transport instead, see MockTransport.EnableChaos.
+func SetRandSource(src rand.Source) {
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+	packageRand.mu.Lock()
+	packageRand.r = rand.New(src)
+	packageRand.mu.Unlock()
+}
+
+// randContextKey is the context key of the source of randomness of a request, see withRand.
+type randContextKey struct{}
+
+// withRand returns a copy of req whose random draws come from r rather than the package source.
+func withRand(req *http.Request, r *lockedRand) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), randContextKey{}, r))
+}
+
+// requestRand returns the source of randomness for the draws made on behalf of req, which may be
+// nil.
+func requestRand(req *http.Request) *lockedRand {
+	if req != nil {
+		if r, ok := req.Context().Value(randContextKey{}).(*lockedRand); ok {
+			return r
+		}
+	}
+	return packageRand
+}
+
+// randInt63n returns a random number in [0, n) from the source of req, which may be nil.
+func randInt63n(req *http.Request, n int64) int64 {
+	return requestRand(req).Int63n(n)
+}
+
+// randFloat64 returns a random number in [0, 1) from the source of req, which may be nil.
+func randFloat64(req *http.Request) float64 {
+	return requestRand(req).Float64()
+}
@@ -0,0 +1,58 @@
+package httpmock
+
+import (
+	"math/rand"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetRandSource(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewMockClock(start)
+	SetClock(clock)
+	defer SetClock(nil)
+	defer SetRandSource(nil)
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// jitters returns the delays picked by WithJitter for five requests
+	jitters := func() []time.Duration {
+		responder := NewResponder(200, "", WithJitter(time.Second, 500*time.Millisecond))
+		var delays []time.Duration
+		for i := 0; i < 5; i++ {
+			done := make(chan struct{})
+			go func() {
+				responder(req)
+				close(done)
+			}()
+			waitForClockWaiters(t, clock, 1)
+
+			clock.mu.Lock()
+			delays = append(delays, clock.waiters[0].deadline.Sub(clock.now))
+			clock.mu.Unlock()
+
+			clock.Advance(time.Hour)
+			<-done
+		}
+		return delays
+	}
+
+	SetRandSource(rand.NewSource(42))
+	first := jitters()
+	SetRandSource(rand.NewSource(42))
+	second := jitters()
+
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("expected the same delays with the same seed, got %v and %v", first, second)
+	}
+	for _, d := range first {
+		if d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("expected delays within the jitter, got %v", first)
+		}
+	}
+}
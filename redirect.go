@@ -0,0 +1,58 @@
+package httpmock
+
+import (
+	"net/http"
+)
+
+// NewRedirectResponder creates a Responder redirecting every request to location with the given
+// status, which should be one of the 3xx codes.
+func NewRedirectResponder(status int, location string) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(status, "")
+		resp.Header.Set("Location", location)
+		return resp, nil
+	}
+}
+
+// NewSelfRedirectResponder creates a Responder redirecting every request to its own URL with the
+// given status, so that a client following redirects loops until it gives up.
+func NewSelfRedirectResponder(status int) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		return NewRedirectResponder(status, req.URL.String())(req)
+	}
+}
+
+// RegisterRedirectLoop registers GET responders on tr making urlA and urlB redirect to each other
+// with a 302, so that a client following redirects bounces between them until it gives up.
+func RegisterRedirectLoop(tr *MockTransport, urlA, urlB string) {
+	tr.RegisterResponder("GET", urlA, NewRedirectResponder(http.StatusFound, urlB))
+	tr.RegisterResponder("GET", urlB, NewRedirectResponder(http.StatusFound, urlA))
+}
+
+// NewRedirectChainResponder creates a Responder walking clients down a chain of 302 redirects: a
+// request for locations[i] is redirected to locations[i+1], a request for the last location is
+// answered by final, and a request for any other URL, the start of the chain, is redirected to
+// locations[0].  Every hop is a separate request, so the responder is meant to be registered for
+// the starting URL and each of the locations; with n locations a client stopping after fewer than
+// n redirects never reaches final.
+func NewRedirectChainResponder(locations []string, final Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if len(locations) == 0 {
+			return final(req)
+		}
+
+		u := req.URL.String()
+		next := locations[0]
+		for i, location := range locations {
+			if location != u {
+				continue
+			}
+			if i == len(locations)-1 {
+				return final(req)
+			}
+			next = locations[i+1]
+			break
+		}
+		return NewRedirectResponder(http.StatusFound, next)(req)
+	}
+}
@@ -0,0 +1,96 @@
+package httpmock
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRegisterRedirectLoop(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	a, b := "http://a.example.com/", "http://b.example.com/"
+	RegisterRedirectLoop(tr, a, b)
+
+	_, err := client.Get(a)
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) || !strings.Contains(err.Error(), "stopped after 10 redirects") {
+		t.Fatalf("expected the client to give up after 10 redirects, got %v", err)
+	}
+
+	counts := tr.CallCounts()
+	if hops := counts["GET "+a] + counts["GET "+b]; hops != 10 {
+		t.Fatalf("expected exactly 10 hops, got %d (%v)", hops, counts)
+	}
+	if counts["GET "+a] != 5 || counts["GET "+b] != 5 {
+		t.Fatalf("expected the hops to alternate, got %v", counts)
+	}
+}
+
+func TestSelfRedirectResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewSelfRedirectResponder(http.StatusMovedPermanently))
+	client := &http.Client{Transport: tr}
+
+	var locations []string
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		locations = append(locations, req.URL.String())
+		if len(via) >= 3 {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != testUrl {
+		t.Fatalf("expected a redirect to the same URL, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if resp.Request == nil || resp.Request.URL.String() != testUrl {
+		t.Fatalf("expected the response to point at its request, got %v", resp.Request)
+	}
+	if len(locations) != 3 {
+		t.Fatalf("expected 3 redirects to be followed, got %v", locations)
+	}
+}
+
+func TestRedirectChainResponder(t *testing.T) {
+	start := testUrl + "start"
+	locations := []string{testUrl + "1", testUrl + "2", testUrl + "3"}
+	chain := NewRedirectChainResponder(locations, NewStringResponder(200, "done"))
+
+	tr := NewMockTransport()
+	for _, u := range append([]string{start}, locations...) {
+		tr.RegisterResponder("GET", u, chain)
+	}
+
+	client := &http.Client{Transport: tr}
+	resp, err := client.Get(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Request.URL.String() != locations[2] {
+		t.Fatalf("expected to reach the end of the chain, got %d at %s", resp.StatusCode, resp.Request.URL)
+	}
+
+	limited := &http.Client{
+		Transport: tr,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 2 {
+				return errors.New("too many redirects")
+			}
+			return nil
+		},
+	}
+	if _, err := limited.Get(start); err == nil || !strings.Contains(err.Error(), "too many redirects") {
+		t.Fatalf("expected the client to stop before the end of the chain, got %v", err)
+	}
+	if n := tr.CallCounts()["GET "+locations[2]]; n != 1 {
+		t.Fatalf("expected the last location to be reached once, got %d", n)
+	}
+}
@@ -0,0 +1,196 @@
+package httpmock
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RegisterOption configures a responder at registration time.
+type RegisterOption func(*registration)
+
+// WithTTL limits a registration to the given duration, measured from registration using the
+// package Clock.  Once it has elapsed the registration expires and whatever was registered
+// underneath it for the same method and URL is revealed.
+func WithTTL(d time.Duration) RegisterOption {
+	return func(r *registration) {
+		r.ttl = d
+	}
+}
+
+// WithMaxCalls limits a registration to n calls.  Once it has served them the registration
+// expires and whatever was registered underneath it for the same method and URL is revealed.
+func WithMaxCalls(n int) RegisterOption {
+	return func(r *registration) {
+		r.maxCalls = n
+	}
+}
+
+// WithMatcher restricts a registration to the requests accepted by every one of matchers.
+// Requests the registration rejects fall through to the other registrations for the same method
+// and URL.  Each matcher counts as a constraint making the registration more specific, see
+// RegisterResponder.
+func WithMatcher(matchers ...Matcher) RegisterOption {
+	return func(r *registration) {
+		r.matchers = append(r.matchers, matchers...)
+	}
+}
+
+// RegistrationState describes whether a registration is able to serve requests.
+type RegistrationState string
+
+const (
+	// RegistrationActive is the registration currently answering for its method and URL.
+	RegistrationActive RegistrationState = "active"
+	// RegistrationShadowed is a registration hidden by one without matchers for the same method and
+	// URL that outranks it, see RegisterResponder.
+	RegistrationShadowed RegistrationState = "shadowed"
+	// RegistrationExpired is a registration that ran out of calls or outlived its TTL.
+	RegistrationExpired RegistrationState = "expired"
+)
+
+// RegistrationInfo describes a registration, as returned by MockTransport.Registrations.
+type RegistrationInfo struct {
+	Key   string
+	State RegistrationState
+	Calls int
+}
+
+// registration is a responder registered for a key, along with the limits on its lifetime.
+// Registrations for the same key are stacked: the most specific live one accepting a request
+// answers it, the most recent one among equally specific ones, see RegisterResponder.
+type registration struct {
+	key       string
+	responder Responder
+	ttl       time.Duration
+	maxCalls  int
+	matchers  []Matcher
+	rawPath   bool
+	priority  int
+	seq       uint64
+
+	continueMode *ContinueMode
+	continueHook func(*http.Request, ContinueMode)
+	earlyHints   http.Header
+
+	registered time.Time
+	calls      int
+	expired    bool
+
+	expectedCalls int
+	hasExpected   bool
+}
+
+func newRegistration(key string, responder Responder, opts []RegisterOption) *registration {
+	r := &registration{
+		key:        key,
+		responder:  responder,
+		registered: now(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.responder = r.interim(responder)
+	return r
+}
+
+// live reports whether the registration can still serve requests, marking it expired when its
+// TTL has passed.
+func (r *registration) live(t time.Time) bool {
+	if !r.expired && r.ttl > 0 && !t.Before(r.registered.Add(r.ttl)) {
+		r.expired = true
+	}
+	return !r.expired
+}
+
+// match returns nil if every matcher of the registration accepts req, or an error describing the
+// first one that didn't.  When only query matchers failed, the error details the parsed queries.
+// Each matcher gets a fresh copy of body.
+func (r *registration) match(req *http.Request, body []byte) error {
+	if r.rawPath {
+		if err := matchRawPath(r.key, req); err != nil {
+			return err
+		}
+	}
+
+	var query *queryMismatch
+	for _, matcher := range r.matchers {
+		setRequestBody(req, body)
+		err := matcher(req)
+		if err == nil {
+			continue
+		}
+		// keep looking after a query mismatch, in case it is not the only problem
+		if mismatch, ok := err.(*queryMismatch); ok {
+			if query == nil {
+				query = mismatch
+			}
+			continue
+		}
+		return err
+	}
+	if query != nil {
+		return query.detailed()
+	}
+	return nil
+}
+
+// use records a call against the registration, expiring it once its calls run out.
+func (r *registration) use() {
+	r.calls++
+	if r.maxCalls > 0 && r.calls >= r.maxCalls {
+		r.expired = true
+	}
+}
+
+// Registration is a handle on a registered responder, returned by RegisterResponder, on which
+// expectations about its calls can be declared and later checked with MockTransport.Verify:
+//
+//	tr.RegisterResponder("POST", "https://api.mybiz.com/orders", responder).ExpectCalls(2)
+//	tr.RegisterResponder("DELETE", "https://api.mybiz.com/orders", responder).ExpectNoCalls()
+//	// ... exercise the code under test
+//	tr.Verify(t)
+type Registration struct {
+	m *MockTransport
+	r *registration
+}
+
+// ExpectCalls declares that the registration must answer exactly n calls.
+func (reg *Registration) ExpectCalls(n int) *Registration {
+	reg.m.mu.Lock()
+	defer reg.m.mu.Unlock()
+	reg.r.expectedCalls = n
+	reg.r.hasExpected = true
+	return reg
+}
+
+// ExpectNoCalls declares that the registration must never answer a call.
+func (reg *Registration) ExpectNoCalls() *Registration {
+	return reg.ExpectCalls(0)
+}
+
+// Verify checks the call expectations declared on the registrations of the MockTransport, see
+// Registration, and reports every one that isn't met in a single t.Errorf, with the expected and
+// actual number of calls of each registration.
+func (m *MockTransport) Verify(t testing.TB) {
+	t.Helper()
+
+	m.mu.Lock()
+	var violations []string
+	for key, stack := range m.responders {
+		for _, r := range stack {
+			if r.hasExpected && r.calls != r.expectedCalls {
+				violations = append(violations, fmt.Sprintf("%s: expected %d calls, got %d", key, r.expectedCalls, r.calls))
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	if len(violations) > 0 {
+		sort.Strings(violations)
+		t.Errorf("httpmock: call expectations not met:\n\t%s", strings.Join(violations, "\n\t"))
+	}
+}
@@ -0,0 +1,153 @@
+package httpmock
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistrationMaxCalls(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "ok"))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(503, "down"), WithMaxCalls(2))
+
+	for i, want := range []int{503, 503, 200, 200} {
+		resp, err := client.Get(testUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("call %d: expected status %d, got %d", i+1, want, resp.StatusCode)
+		}
+	}
+
+	infos := tr.Registrations()
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 registrations, got %d", len(infos))
+	}
+	if infos[0].State != RegistrationExpired || infos[0].Calls != 2 {
+		t.Fatalf("expected the 503 stub to be expired after 2 calls, got %+v", infos[0])
+	}
+	if infos[1].State != RegistrationActive || infos[1].Calls != 2 {
+		t.Fatalf("expected the 200 stub to be active after 2 calls, got %+v", infos[1])
+	}
+}
+
+func TestRegistrationTTL(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "ok"))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(503, "maintenance"), WithTTL(time.Minute))
+
+	if infos := tr.Registrations(); infos[1].State != RegistrationShadowed {
+		t.Fatalf("expected the 200 stub to be shadowed, got %+v", infos[1])
+	}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 503 {
+		t.Fatalf("expected status 503 during the TTL, got %d", resp.StatusCode)
+	}
+
+	clock.Advance(time.Minute)
+
+	resp, err = client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected status 200 after the TTL, got %d", resp.StatusCode)
+	}
+
+	if infos := tr.Registrations(); infos[0].State != RegistrationExpired {
+		t.Fatalf("expected the 503 stub to be expired, got %+v", infos[0])
+	}
+}
+
+func TestMockClockAfter(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	ch := clock.After(time.Second)
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("expected After not to fire before its deadline")
+	default:
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-ch:
+	default:
+		t.Fatal("expected After to fire at its deadline")
+	}
+}
+
+func TestMockClockConcurrentAdvance(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewMockClock(start)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clock.Advance(time.Second)
+		}()
+	}
+	wg.Wait()
+
+	if elapsed := clock.Now().Sub(start); elapsed != 100*time.Second {
+		t.Fatalf("expected every Advance to count, got %s", elapsed)
+	}
+}
+
+func TestRegistrationExpectations(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	tr.RegisterResponder("GET", testUrl+"twice", NewStringResponder(200, "")).ExpectCalls(2)
+	tr.RegisterResponder("GET", testUrl+"never", NewStringResponder(200, "")).ExpectNoCalls()
+	tr.RegisterResponder("GET", testUrl+"free", NewStringResponder(200, ""))
+
+	for _, u := range []string{"twice", "never", "free", "free"} {
+		if _, err := client.Get(testUrl + u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := &recordingTB{}
+	tr.Verify(rec)
+	if len(rec.errors) != 1 {
+		t.Fatalf("expected a single failure, got %q", rec.errors)
+	}
+	for _, want := range []string{
+		"GET " + testUrl + "never: expected 0 calls, got 1",
+		"GET " + testUrl + "twice: expected 2 calls, got 1",
+	} {
+		if !strings.Contains(rec.errors[0], want) {
+			t.Errorf("expected the failure to mention %q, got %q", want, rec.errors[0])
+		}
+	}
+
+	if _, err := client.Get(testUrl + "twice"); err != nil {
+		t.Fatal(err)
+	}
+	tr.Reset()
+	rec = &recordingTB{}
+	tr.Verify(rec)
+	if len(rec.errors) != 0 {
+		t.Fatalf("expected Reset to drop the expectations, got %q", rec.errors)
+	}
+}
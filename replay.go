@@ -0,0 +1,151 @@
+package httpmock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RecordedExchange is a request answered by a RecordingResponder, along with what it was answered.
+type RecordedExchange struct {
+	// Time is when the response was returned, according to the package Clock.
+	Time time.Time
+	// Request is a copy of the request whose body can be read any number of times.
+	Request *http.Request
+	// Response is the response returned, with its body buffered in ResponseBody, or nil if the
+	// exchange failed.
+	Response *http.Response
+	// ResponseBody holds the response body.
+	ResponseBody []byte
+	// Err is the error the exchange failed with, if any.
+	Err error
+}
+
+// RequestLog holds the exchanges recorded by a RecordingResponder, in the order they happened.
+// It is safe for concurrent use.
+type RequestLog struct {
+	mu        sync.Mutex
+	exchanges []*RecordedExchange
+}
+
+// NewRequestLog creates an empty RequestLog.
+func NewRequestLog() *RequestLog {
+	return &RequestLog{}
+}
+
+// Exchanges returns the exchanges recorded so far.
+func (l *RequestLog) Exchanges() []*RecordedExchange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]*RecordedExchange(nil), l.exchanges...)
+}
+
+func (l *RequestLog) add(exchange *RecordedExchange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.exchanges = append(l.exchanges, exchange)
+}
+
+// RecordingResponder creates a Responder passing requests on to inner and recording every exchange
+// to log.  Response bodies are read in full so that they can be recorded, the client gets an
+// identical copy.  A typical inner responder is InitialTransport.RoundTrip, to capture real
+// traffic once and serve it back with ReplayResponder.
+func RecordingResponder(log *RequestLog, inner Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		body, err := ReplayableRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		recorded := req.Clone(req.Context())
+		setRequestBody(recorded, body)
+
+		resp, err := inner(req)
+		exchange := &RecordedExchange{Request: recorded, Err: err}
+		if err == nil && resp != nil {
+			var data []byte
+			if resp.Body != nil {
+				data, err = ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+			}
+			kept := *resp
+			kept.Header = resp.Header.Clone()
+			kept.Body = nil
+			exchange.Response = &kept
+			exchange.ResponseBody = data
+			resp = replayedResponse(exchange)
+		}
+		exchange.Time = now()
+		log.add(exchange)
+		return resp, err
+	}
+}
+
+// ReplayOption configures a ReplayResponder.
+type ReplayOption func(*replayConfig)
+
+type replayConfig struct {
+	instant bool
+}
+
+// ReplayInstantly makes a ReplayResponder serve its responses without waiting, for fast tests.
+func ReplayInstantly() ReplayOption {
+	return func(c *replayConfig) {
+		c.instant = true
+	}
+}
+
+// ReplayResponder creates a Responder serving the exchanges of log in the order they were
+// recorded, one per request, whatever the request.  Each response after the first one is delayed,
+// as measured by the package Clock, by the time that separated it from the previous one during the
+// recording; the wait ends early with the context's error if the request is canceled.  Once every
+// exchange has been served, requests fail with an error.
+//
+// The exchanges are taken from log when ReplayResponder is called.
+func ReplayResponder(log *RequestLog, opts ...ReplayOption) Responder {
+	cfg := &replayConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	exchanges := log.Exchanges()
+	var mu sync.Mutex
+	next := 0
+
+	return func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		i := next
+		if i < len(exchanges) {
+			next++
+		}
+		mu.Unlock()
+
+		if i == len(exchanges) {
+			return nil, fmt.Errorf("httpmock: replay exhausted after %d responses", len(exchanges))
+		}
+
+		if i > 0 && !cfg.instant {
+			if err := sleepContext(req, exchanges[i].Time.Sub(exchanges[i-1].Time)); err != nil {
+				return nil, err
+			}
+		}
+
+		exchange := exchanges[i]
+		if exchange.Err != nil || exchange.Response == nil {
+			return nil, exchange.Err
+		}
+		return replayedResponse(exchange), nil
+	}
+}
+
+// replayedResponse builds a fresh copy of the response of exchange, with its own Header and body.
+func replayedResponse(exchange *RecordedExchange) *http.Response {
+	resp := *exchange.Response
+	resp.Header = exchange.Response.Header.Clone()
+	resp.Body = NewRespBodyFromBytes(exchange.ResponseBody)
+	return &resp
+}
@@ -0,0 +1,172 @@
+package httpmock
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordSequence records three exchanges on log, one, two and five seconds apart on clock.
+func recordSequence(t *testing.T, clock *MockClock, log *RequestLog) {
+	t.Helper()
+
+	n := 0
+	recorder := RecordingResponder(log, func(req *http.Request) (*http.Response, error) {
+		n++
+		if n == 3 {
+			return nil, errors.New("boom")
+		}
+		resp := NewStringResponse(200, strings.Repeat("x", n))
+		resp.Header.Set("X-Call", string(rune('0'+n)))
+		return resp, nil
+	})
+
+	for i, gap := range []time.Duration{0, time.Second, 5 * time.Second} {
+		clock.Advance(gap)
+		req, err := http.NewRequest("POST", testUrl, strings.NewReader("req"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := recorder(req)
+		if i == 2 {
+			if err == nil {
+				t.Fatal("expected the failure to go through the recorder")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := ioutil.ReadAll(resp.Body); string(data) != strings.Repeat("x", i+1) {
+			t.Fatalf("expected the client to get the recorded body, got %q", data)
+		}
+	}
+}
+
+func TestRecordingResponder(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	log := NewRequestLog()
+	recordSequence(t, clock, log)
+
+	exchanges := log.Exchanges()
+	if len(exchanges) != 3 {
+		t.Fatalf("expected 3 exchanges, got %d", len(exchanges))
+	}
+	if string(exchanges[1].ResponseBody) != "xx" || exchanges[1].Response.Header.Get("X-Call") != "2" {
+		t.Fatalf("unexpected second exchange: %+v", exchanges[1])
+	}
+	if body, _ := ioutil.ReadAll(exchanges[0].Request.Body); string(body) != "req" {
+		t.Fatalf("expected the request body to be recorded, got %q", body)
+	}
+	if exchanges[2].Err == nil || exchanges[2].Response != nil {
+		t.Fatalf("expected the third exchange to record the error, got %+v", exchanges[2])
+	}
+	if gap := exchanges[2].Time.Sub(exchanges[1].Time); gap != 5*time.Second {
+		t.Fatalf("expected the exchanges 5s apart, got %s", gap)
+	}
+}
+
+func TestReplayResponderTiming(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	log := NewRequestLog()
+	recordSequence(t, clock, log)
+	replay := ReplayResponder(log)
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := replay(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(resp.Body); string(data) != "x" {
+		t.Fatalf("expected the first response straight away, got %q", data)
+	}
+
+	done := make(chan *http.Response)
+	go func() {
+		resp, _ := replay(req)
+		done <- resp
+	}()
+	waitForClockWaiters(t, clock, 1)
+	clock.Advance(time.Second)
+	if resp := <-done; resp == nil || resp.Header.Get("X-Call") != "2" {
+		t.Fatalf("expected the second response after a second, got %v", resp)
+	}
+
+	errs := make(chan error)
+	go func() {
+		_, err := replay(req)
+		errs <- err
+	}()
+	waitForClockWaiters(t, clock, 1)
+	clock.Advance(5 * time.Second)
+	if err := <-errs; err == nil || err.Error() != "boom" {
+		t.Fatalf("expected the recorded error, got %v", err)
+	}
+
+	if _, err := replay(req); err == nil || !strings.Contains(err.Error(), "exhausted") {
+		t.Fatalf("expected the replay to be exhausted, got %v", err)
+	}
+}
+
+func TestReplayResponderInstantly(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	log := NewRequestLog()
+	recordSequence(t, clock, log)
+	replay := ReplayResponder(log, ReplayInstantly())
+
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := replay(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReplayResponderCanceled(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	log := NewRequestLog()
+	recordSequence(t, clock, log)
+	replay := ReplayResponder(log)
+
+	if _, err := replay(newContextRequest(t, context.Background())); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := replay(newContextRequest(t, ctx)); err != context.Canceled {
+		t.Fatalf("expected context.Canceled while waiting, got %v", err)
+	}
+}
+
+func newContextRequest(t *testing.T, ctx context.Context) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
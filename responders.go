@@ -0,0 +1,576 @@
+package httpmock
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// NewAsyncResponder creates a Responder for APIs supporting asynchronous processing.  When the
+// request carries the respond-async preference (RFC 7240), it returns 202 Accepted with a Location
+// header pointing at loc; otherwise requests are answered synchronously by completed.
+func NewAsyncResponder(loc string, completed Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if !hasPreference(req, "respond-async") {
+			return completed(req)
+		}
+
+		resp := NewStringResponse(http.StatusAccepted, "")
+		resp.Header.Set("Location", loc)
+		resp.Header.Set("Preference-Applied", "respond-async")
+		return resp, nil
+	}
+}
+
+// hasPreference reports whether one of the request's Prefer headers holds the given preference.
+func hasPreference(req *http.Request, preference string) bool {
+	for _, value := range req.Header.Values("Prefer") {
+		for _, pref := range strings.Split(value, ",") {
+			token := pref
+			if i := strings.IndexAny(token, ";="); i >= 0 {
+				token = token[:i]
+			}
+			if strings.EqualFold(strings.TrimSpace(token), preference) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequireQueryParams creates a Responder that checks the request query holds every one of keys
+// before delegating to inner.  When any are absent it returns a 400 with a JSON body naming them:
+// 		{"error": "missing required query parameters: page, limit", "missing": ["page", "limit"]}
+func RequireQueryParams(keys []string, inner Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		query := req.URL.Query()
+
+		missing := []string{}
+		for _, key := range keys {
+			if _, ok := query[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) == 0 {
+			return inner(req)
+		}
+
+		message := "missing required query parameter: "
+		if len(missing) > 1 {
+			message = "missing required query parameters: "
+		}
+		return NewJsonResponse(http.StatusBadRequest, map[string]interface{}{
+			"error":   message + strings.Join(missing, ", "),
+			"missing": missing,
+		})
+	}
+}
+
+// NewMaintenanceResponder creates a Responder that models planned maintenance: until the package
+// Clock reaches until it returns 503 Service Unavailable with a Retry-After header of retryAfter
+// (rounded up to whole seconds), then it delegates to inner.
+func NewMaintenanceResponder(until time.Time, retryAfter time.Duration, inner Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if !now().Before(until) {
+			return inner(req)
+		}
+
+		resp := NewStringResponse(http.StatusServiceUnavailable, "")
+		resp.Header.Set("Retry-After", retryAfterSeconds(retryAfter))
+		return resp, nil
+	}
+}
+
+// retryAfterSeconds formats d as a Retry-After value, in whole seconds rounded up.
+func retryAfterSeconds(d time.Duration) string {
+	seconds := int64(d / time.Second)
+	if d%time.Second > 0 {
+		seconds++
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// NewExpectContinueResponder creates a Responder for requests sent with "Expect: 100-continue".
+// For such requests it plays the part of the interim 100 Continue response, through the Got1xxResponse
+// and Got100Continue callbacks of any httptrace.ClientTrace attached to the request context, and
+// then reads the whole request body before delivering the response of final.  A body that can't be
+// read in full fails the request.  Requests without the header go straight to final.
+//
+// A real http.Transport holds the body back until the interim response arrives.  The mock can't
+// reproduce that part: MockTransport reads the request body for its call history before any
+// responder runs.  What this responder verifies is that the client sent the header, reacts to the
+// interim response and delivers the complete body.
+func NewExpectContinueResponder(final Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if !expectsContinue(req) {
+			return final(req)
+		}
+		if err := sendContinue(req); err != nil {
+			return nil, err
+		}
+		return final(req)
+	}
+}
+
+// NewConditionalGzipResponder creates a Responder returning body with the given status, gzip
+// encoded with a "Content-Encoding: gzip" header when the request's Accept-Encoding allows it, or
+// as is otherwise (including when Accept-Encoding is absent).
+func NewConditionalGzipResponder(status int, body []byte) Responder {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(body)
+	zw.Close()
+	compressed := buf.Bytes()
+
+	return func(req *http.Request) (*http.Response, error) {
+		var resp *http.Response
+		if acceptsEncoding(req, "gzip") {
+			resp = NewBytesResponse(status, compressed)
+			resp.Header.Set("Content-Encoding", "gzip")
+			resp.ContentLength = int64(len(compressed))
+		} else {
+			resp = NewBytesResponse(status, body)
+			resp.ContentLength = int64(len(body))
+		}
+		resp.Header.Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+		resp.Header.Set("Vary", "Accept-Encoding")
+		return resp, nil
+	}
+}
+
+// acceptsEncoding reports whether the request's Accept-Encoding headers allow the given content
+// coding, either by name or through "*", with a non zero quality.
+func acceptsEncoding(req *http.Request, coding string) bool {
+	accepted := false
+	for _, value := range req.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(value, ",") {
+			params := strings.Split(part, ";")
+			name := strings.TrimSpace(params[0])
+			if !strings.EqualFold(name, coding) && name != "*" {
+				continue
+			}
+
+			q := 1.0
+			for _, param := range params[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+						q = v
+					}
+				}
+			}
+
+			// an explicit entry for the coding wins over the wildcard
+			if strings.EqualFold(name, coding) {
+				return q > 0
+			}
+			accepted = q > 0
+		}
+	}
+	return accepted
+}
+
+// NewGatedResponder creates a Responder that parks every request until the returned release func
+// is called, then lets them all through to inner.  Requests arriving after the release go straight
+// through.  A request whose context is canceled while parked fails with the context's error
+// without reaching inner.  Calling release more than once is harmless.
+func NewGatedResponder(inner Responder) (Responder, func()) {
+	gate := make(chan struct{})
+	var once sync.Once
+	release := func() {
+		once.Do(func() { close(gate) })
+	}
+
+	responder := func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-gate:
+			return inner(req)
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	return responder, release
+}
+
+// NewMalformedJSONResponder creates a Responder returning, with the given status and a
+// "Content-Type: application/json" header, a body that looks like JSON but can't be decoded: an
+// object cut off in the middle of a value.  It helps checking that decode errors are surfaced
+// rather than swallowed.
+func NewMalformedJSONResponder(status int) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(status, `{"id": 42, "name": "trunc`)
+		resp.Header.Set("Content-Type", "application/json")
+		return resp, nil
+	}
+}
+
+// NewMismatchedLengthResponder creates a Responder returning body with the given status, but with
+// a Content-Length header (and ContentLength field) of declaredLen, as a buggy server would.  The
+// body is kept intact, so a client reading past the declared length gets the extra bytes and one
+// trusting it blindly gets fewer.
+func NewMismatchedLengthResponder(status int, body string, declaredLen int) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(status, body)
+		resp.ContentLength = int64(declaredLen)
+		resp.Header.Set("Content-Length", strconv.Itoa(declaredLen))
+		return resp, nil
+	}
+}
+
+// NewUpgradeRejectResponder creates a Responder refusing protocol upgrades, such as a WebSocket
+// handshake, with the given non-101 status (typically 400 or 426) and reason as a plain text body.
+// The response carries no Upgrade or Connection header, so the client sees a clean rejection and
+// takes its fallback path.
+func NewUpgradeRejectResponder(status int, reason string) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(status, reason)
+		resp.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		return resp, nil
+	}
+}
+
+// NewUpgradeRequiredResponder creates a Responder demanding that the client switch protocols, as
+// RFC 7231 describes: a 426 naming upgradeTo, such as "TLS/1.2" or "HTTP/2.0", in its Upgrade
+// header, with "Connection: Upgrade" and a short plain text body.  Where NewUpgradeRejectResponder
+// turns an upgrade down, this one requires it.
+func NewUpgradeRequiredResponder(upgradeTo string) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(http.StatusUpgradeRequired, "upgrade to "+upgradeTo+" required")
+		resp.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		resp.Header.Set("Upgrade", upgradeTo)
+		resp.Header.Set("Connection", "Upgrade")
+		return resp, nil
+	}
+}
+
+// NewHangingResponder creates a Responder that never answers: it blocks until the request is
+// canceled, through its context or its Cancel channel, and then fails with the context's error
+// (context.DeadlineExceeded for a timeout) or "net/http: request canceled", as a real transport
+// would.  It helps testing client timeouts end to end.
+func NewHangingResponder() Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-req.Context().Done():
+			return nil, canceledError(req)
+		case <-req.Cancel:
+			return nil, canceledError(req)
+		}
+	}
+}
+
+// NewHangAfterHeadersResponder creates a Responder returning a 200 right away, but whose body never
+// delivers a byte: reads block until the request is canceled, failing with the context's error, or
+// the body is closed.  Along with NewHangingResponder, it tells a client's ResponseHeaderTimeout
+// from its overall timeout.
+func NewHangAfterHeadersResponder() Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			Status:        statusLine(http.StatusOK),
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{},
+			Body:          &hangingBody{req: req, closed: make(chan struct{})},
+			ContentLength: -1,
+		}, nil
+	}
+}
+
+// hangingBody is a body whose reads block until its request is canceled or it is closed.
+type hangingBody struct {
+	req    *http.Request
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (b *hangingBody) Read(p []byte) (int, error) {
+	select {
+	case <-b.req.Context().Done():
+		return 0, canceledError(b.req)
+	case <-b.req.Cancel:
+		return 0, canceledError(b.req)
+	case <-b.closed:
+		return 0, errors.New("httpmock: read on closed body")
+	}
+}
+
+func (b *hangingBody) Close() error {
+	b.once.Do(func() { close(b.closed) })
+	return nil
+}
+
+// NewColdStartResponder creates a Responder simulating the cold start of a serverless function:
+// the very first call waits coldDelay, as measured by the package Clock, before getting the
+// response of inner, and every other call, including the ones made while the first is waiting,
+// gets it right away.  The first call fails with the context's error if it is canceled while
+// waiting; the cold start is spent all the same.
+func NewColdStartResponder(coldDelay time.Duration, inner Responder) Responder {
+	var started int32
+	return func(req *http.Request) (*http.Response, error) {
+		if atomic.CompareAndSwapInt32(&started, 0, 1) {
+			if err := sleepContext(req, coldDelay); err != nil {
+				return nil, err
+			}
+		}
+		return inner(req)
+	}
+}
+
+// TimeRange is a span of time, from Start included to End excluded.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether t falls within the range.
+func (r TimeRange) Contains(t time.Time) bool {
+	return !t.Before(r.Start) && t.Before(r.End)
+}
+
+// NewScheduledFailureResponder creates a Responder answering with fail while the package Clock is
+// within one of failWindows, and with ok the rest of the time.  With a MockClock, it reproduces
+// time driven failure patterns, such as a dependency flapping during an incident, deterministically.
+func NewScheduledFailureResponder(failWindows []TimeRange, ok, fail Responder) Responder {
+	windows := append([]TimeRange(nil), failWindows...)
+	return func(req *http.Request) (*http.Response, error) {
+		t := now()
+		for _, window := range windows {
+			if window.Contains(t) {
+				return fail(req)
+			}
+		}
+		return ok(req)
+	}
+}
+
+// NewTrailerEchoResponder creates a Responder echoing the named request trailers as response
+// trailers, to verify trailer handling in both directions, as gRPC-web uses it.
+//
+// Request trailers are only known once the request body has been read up to io.EOF, so the
+// responder consumes the whole body before looking at req.Trailer; the body is gone for whatever
+// runs after it.  Response trailers follow the rules of WithTrailer: the keys show up in the
+// response's Trailer right away, and the values once its body has been read up to io.EOF.  Keys
+// missing from the request trailers are declared without a value.
+func NewTrailerEchoResponder(status int, body string, keys ...string) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			if _, err := io.Copy(ioutil.Discard, req.Body); err != nil {
+				return nil, err
+			}
+		}
+
+		echo := &trailerBody{
+			ReadCloser: NewRespBodyFromString(body),
+			trailer:    http.Header{},
+			values:     http.Header{},
+		}
+		for _, key := range keys {
+			key = http.CanonicalHeaderKey(key)
+			echo.trailer[key] = nil
+			for _, value := range req.Trailer.Values(key) {
+				echo.values.Add(key, value)
+			}
+		}
+
+		resp := NewStringResponse(status, "")
+		resp.Body = echo
+		resp.Trailer = echo.trailer
+		return resp, nil
+	}
+}
+
+// NewErrorRateResponder creates a Responder answering rate of the calls, from 0 to 1, with an
+// errStatus response and the others with ok.  Rather than drawn at random, the failures are spread
+// evenly: after n calls, exactly floor(n*rate) of them failed, so a rate of 0.25 fails every fourth
+// call.  This makes exact assertions possible when testing SLOs.  It fails if rate is out of range.
+func NewErrorRateResponder(rate float64, ok Responder, errStatus int) (Responder, error) {
+	if !(rate >= 0 && rate <= 1) {
+		return nil, fmt.Errorf("httpmock: error rate %v is not within [0, 1]", rate)
+	}
+
+	var mu sync.Mutex
+	calls := 0
+	return func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		calls++
+		failed := math.Floor(float64(calls)*rate) > math.Floor(float64(calls-1)*rate)
+		mu.Unlock()
+
+		if failed {
+			return NewStringResponse(errStatus, http.StatusText(errStatus)), nil
+		}
+		return ok(req)
+	}, nil
+}
+
+// NewConcurrencyLimitResponder creates a Responder letting at most max calls at a time through to
+// inner, to check that a client respects the concurrency limits of a server and backs off when it
+// hits them.  Calls beyond the limit get an overflowStatus response right away, such as a 503.  A
+// call stops counting against the limit as soon as inner returns, whether or not the body of its
+// response has been read.  A max of zero or less turns every call away.
+func NewConcurrencyLimitResponder(max int, overflowStatus int, inner Responder) Responder {
+	if max < 0 {
+		max = 0
+	}
+	slots := make(chan struct{}, max)
+	return func(req *http.Request) (*http.Response, error) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			return NewStringResponse(overflowStatus, http.StatusText(overflowStatus)), nil
+		}
+		defer func() { <-slots }()
+		return inner(req)
+	}
+}
+
+// NewSequenceTokenResponder creates a Responder modelling an API with optimistic concurrency: the
+// requests must carry an integer in header, strictly greater than the one of the last request
+// accepted, and are answered by inner when they do.  Tokens may skip values, as those of a client
+// sharing its counter with other resources would, but never repeat or go back.  The first request
+// sets the starting value.  A request arriving out of order gets a 409 Conflict naming the last
+// token accepted, and one whose header is missing or isn't an integer a 400 Bad Request; neither
+// moves the sequence forward.
+func NewSequenceTokenResponder(header string, inner Responder) Responder {
+	var mu sync.Mutex
+	var last int64
+	started := false
+	return func(req *http.Request) (*http.Response, error) {
+		token, err := strconv.ParseInt(req.Header.Get(header), 10, 64)
+		if err != nil {
+			return NewStringResponse(http.StatusBadRequest,
+				fmt.Sprintf("invalid sequence token %q in %s", req.Header.Get(header), header)), nil
+		}
+
+		mu.Lock()
+		if started && token <= last {
+			previous := last
+			mu.Unlock()
+			return NewStringResponse(http.StatusConflict,
+				fmt.Sprintf("sequence token %d out of order, expected more than %d", token, previous)), nil
+		}
+		started, last = true, token
+		mu.Unlock()
+
+		return inner(req)
+	}
+}
+
+// NewUTF8ValidatingResponder creates a Responder answering with a 400 Bad Request the requests
+// whose body isn't valid UTF-8, naming the offset of the first invalid byte, and passing the others
+// on to ok, body intact.  It catches the encoding bugs of the code serializing requests.
+func NewUTF8ValidatingResponder(ok Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		body, err := ReplayableRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(body) {
+			return NewStringResponse(http.StatusBadRequest,
+				fmt.Sprintf("request body is not valid UTF-8 at byte %d", invalidUTF8Offset(body))), nil
+		}
+		return ok(req)
+	}
+}
+
+// invalidUTF8Offset returns the offset of the first invalid UTF-8 sequence of data, or len(data).
+func invalidUTF8Offset(data []byte) int {
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return len(data)
+}
+
+// NewEarlyResponseResponder creates a Responder answering with status and body without reading the
+// request body, as servers rejecting an upload early do, and closing the connection as they
+// usually must then, with Close set and a "Connection: close" header.
+//
+// The MockTransport normally reads the whole request body before calling any responder, to record
+// it, so the client still sees its upload consumed.  To test a client against a server that stops
+// reading, send the request with a GetBody, so that the transport records its copy from there, and
+// turn draining off with SetRequestBodyDrain(0): the body of the client is then left unread and
+// open, and Call.BodyRead is 0.
+func NewEarlyResponseResponder(status int, body string) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(status, body)
+		markClose(resp)
+		return resp, nil
+	}
+}
+
+// ByIdempotency creates a Responder routing the requests whose method is idempotent according to
+// RFC 7231 (GET, HEAD, PUT, DELETE, OPTIONS and TRACE) to idempotent, and the others, such as POST
+// and PATCH, to nonIdempotent.  It follows the line clients draw when deciding whether a request
+// is safe to retry.
+func ByIdempotency(idempotent, nonIdempotent Responder) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		switch req.Method {
+		case "GET", "HEAD", "PUT", "DELETE", "OPTIONS", "TRACE":
+			return idempotent(req)
+		}
+		return nonIdempotent(req)
+	}
+}
+
+// NewStatusResponder creates a Responder answering with body and a status line carrying the given
+// reason phrase, as in "200 Everything Fine", for the clients parsing resp.Status rather than only
+// the code.  An empty phrase stands for the standard one, as NewStringResponse sets it.
+func NewStatusResponder(code int, reasonPhrase, body string) Responder {
+	status := statusLine(code)
+	if reasonPhrase != "" {
+		status = fmt.Sprintf("%d %s", code, reasonPhrase)
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(code, body)
+		resp.Status = status
+		return resp, nil
+	}
+}
+
+// DefaultSizedResponderSize is the size of the bodies of a NewSizedResponder when the request
+// doesn't give a valid one.
+const DefaultSizedResponderSize = 1024
+
+// NewSizedResponder creates a Responder answering 200 with a body of as many pattern bytes as the
+// query parameter sizeParam of the request asks for, so that a single registration serves payloads
+// of every size for testing buffering.  The body is generated as it is read rather than held in
+// memory, and its size is set as both the Content-Length header and ContentLength.  A missing,
+// invalid or negative size stands for DefaultSizedResponderSize.
+func NewSizedResponder(sizeParam string, pattern byte) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		size, err := strconv.ParseInt(req.URL.Query().Get(sizeParam), 10, 64)
+		if err != nil || size < 0 {
+			size = DefaultSizedResponderSize
+		}
+		return &http.Response{
+			Status:        statusLine(200),
+			StatusCode:    200,
+			Body:          ioutil.NopCloser(io.LimitReader(patternReader(pattern), size)),
+			Header:        http.Header{"Content-Length": {strconv.FormatInt(size, 10)}},
+			ContentLength: size,
+		}, nil
+	}
+}
+
+// patternReader is an endless stream of the same byte.
+type patternReader byte
+
+func (r patternReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,820 @@
+package httpmock
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAsyncResponder(t *testing.T) {
+	responder := NewAsyncResponder("https://api.example.com/jobs/1", NewStringResponder(200, "done"))
+
+	tests := []struct {
+		prefer string
+		status int
+	}{
+		{"", 200},
+		{"respond-async", 202},
+		{"wait=10, respond-async", 202},
+		{"Respond-Async; foo=bar", 202},
+		{"respond-async-later", 200},
+		{"return=minimal", 200},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("POST", testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.prefer != "" {
+			req.Header.Set("Prefer", test.prefer)
+		}
+
+		resp, err := responder(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.status {
+			t.Errorf("Prefer %q: expected status %d, got %d", test.prefer, test.status, resp.StatusCode)
+		}
+		if test.status == 202 && resp.Header.Get("Location") != "https://api.example.com/jobs/1" {
+			t.Errorf("Prefer %q: expected a Location header, got %q", test.prefer, resp.Header.Get("Location"))
+		}
+	}
+}
+
+func TestRequireQueryParams(t *testing.T) {
+	responder := RequireQueryParams([]string{"page", "limit"}, NewStringResponder(200, "ok"))
+
+	tests := []struct {
+		query   string
+		status  int
+		missing []string
+	}{
+		{"?page=1&limit=10", 200, nil},
+		{"?page=&limit", 200, nil},
+		{"?page=1", 400, []string{"limit"}},
+		{"", 400, []string{"page", "limit"}},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", testUrl+test.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := responder(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.status {
+			t.Errorf("query %q: expected status %d, got %d", test.query, test.status, resp.StatusCode)
+			continue
+		}
+		if test.status != 400 {
+			continue
+		}
+
+		var body struct {
+			Error   string   `json:"error"`
+			Missing []string `json:"missing"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(body.Missing, test.missing) {
+			t.Errorf("query %q: expected missing %v, got %v", test.query, test.missing, body.Missing)
+		}
+		if !strings.Contains(body.Error, strings.Join(test.missing, ", ")) {
+			t.Errorf("query %q: expected the error to name %v, got %q", test.query, test.missing, body.Error)
+		}
+	}
+}
+
+func TestMaintenanceResponder(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewMockClock(start)
+	SetClock(clock)
+	defer SetClock(nil)
+
+	responder := NewMaintenanceResponder(start.Add(time.Hour), 1500*time.Millisecond, NewStringResponder(200, "ok"))
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := responder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 503 || resp.Header.Get("Retry-After") != "2" {
+		t.Fatalf("expected 503 with Retry-After 2 during maintenance, got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	clock.Advance(time.Hour)
+	resp, err = responder(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 after maintenance, got %d", resp.StatusCode)
+	}
+}
+
+func TestExpectContinueResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("PUT", testUrl, NewExpectContinueResponder(func(req *http.Request) (*http.Response, error) {
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		return NewStringResponse(200, string(data)), nil
+	}))
+	client := &http.Client{Transport: tr}
+
+	var events []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			events = append(events, fmt.Sprintf("1xx %d", code))
+			return nil
+		},
+		Got100Continue: func() {
+			events = append(events, "continue")
+		},
+	}
+
+	for _, expect := range []string{"100-continue", ""} {
+		events = nil
+		req, err := http.NewRequest("PUT", testUrl, strings.NewReader("upload"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		if expect != "" {
+			req.Header.Set("Expect", expect)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		if string(data) != "upload" {
+			t.Errorf("Expect %q: expected the body to reach the final responder, got %q", expect, data)
+		}
+
+		want := "1xx 100,continue"
+		if expect == "" {
+			want = ""
+		}
+		if got := strings.Join(events, ","); got != want {
+			t.Errorf("Expect %q: expected trace events %q, got %q", expect, want, got)
+		}
+	}
+
+	// a body shorter than the declared ContentLength never arrived in full
+	req, _ := http.NewRequest("PUT", testUrl, strings.NewReader("upl"))
+	req.ContentLength = 10
+	req.Header.Set("Expect", "100-continue")
+	if resp, err := tr.RoundTrip(req); err == nil {
+		t.Errorf("expected a short body to fail the request, got %v", resp)
+	}
+}
+
+func TestConditionalGzipResponder(t *testing.T) {
+	responder := NewConditionalGzipResponder(200, []byte("hello gzip"))
+
+	tests := []struct {
+		accept  string
+		gzipped bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"*", true},
+		{"gzip;q=0, *", false},
+		{"br", false},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.accept != "" {
+			req.Header.Set("Accept-Encoding", test.accept)
+		}
+
+		resp, err := responder(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body := io.Reader(resp.Body)
+		if gzipped := resp.Header.Get("Content-Encoding") == "gzip"; gzipped != test.gzipped {
+			t.Errorf("Accept-Encoding %q: expected gzip %v, got %v", test.accept, test.gzipped, gzipped)
+			continue
+		} else if gzipped {
+			if body, err = gzip.NewReader(resp.Body); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "hello gzip" {
+			t.Errorf("Accept-Encoding %q: expected the decoded body, got %q", test.accept, data)
+		}
+	}
+}
+
+func TestGatedResponder(t *testing.T) {
+	responder, release := NewGatedResponder(NewStringResponder(200, "released"))
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, responder)
+	client := &http.Client{Transport: tr}
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(testUrl)
+		if err == nil && resp.StatusCode != 200 {
+			err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected the request to be parked, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	release()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGatedResponderCanceled(t *testing.T) {
+	responder, _ := NewGatedResponder(NewStringResponder(200, "never"))
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, responder)
+	client := &http.Client{Transport: tr, Timeout: 20 * time.Millisecond}
+
+	if _, err := client.Get(testUrl); err == nil {
+		t.Fatal("expected the parked request to time out")
+	}
+}
+
+func TestMalformedJSONResponder(t *testing.T) {
+	resp, err := NewMalformedJSONResponder(200)(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a 200 claiming to be JSON, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var v interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err == nil {
+		t.Fatalf("expected the body not to decode, got %v", v)
+	}
+}
+
+func TestMismatchedLengthResponder(t *testing.T) {
+	resp, err := NewMismatchedLengthResponder(200, "hello world", 5)(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContentLength != 5 || resp.Header.Get("Content-Length") != "5" {
+		t.Fatalf("expected a declared length of 5, got %d %q", resp.ContentLength, resp.Header.Get("Content-Length"))
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello world" {
+		t.Fatalf("expected the whole body to be readable, got %q", data)
+	}
+}
+
+func TestUpgradeRejectResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl+"ws", NewUpgradeRejectResponder(http.StatusUpgradeRequired, "websockets are disabled"))
+	client := &http.Client{Transport: tr}
+
+	req, err := http.NewRequest("GET", testUrl+"ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Fatalf("expected a 426, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Upgrade") != "" || resp.Header.Get("Connection") != "" {
+		t.Fatalf("expected no upgrade headers, got %v", resp.Header)
+	}
+	if data, _ := ioutil.ReadAll(resp.Body); string(data) != "websockets are disabled" {
+		t.Fatalf("expected the reason as body, got %q", data)
+	}
+}
+
+func TestUpgradeRequiredResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewUpgradeRequiredResponder("TLS/1.2, HTTP/1.1"))
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired || resp.Status != "426 Upgrade Required" {
+		t.Fatalf("expected a 426, got %q", resp.Status)
+	}
+	if resp.Header.Get("Upgrade") != "TLS/1.2, HTTP/1.1" || resp.Header.Get("Connection") != "Upgrade" {
+		t.Errorf("expected the upgrade headers, got %v", resp.Header)
+	}
+	if string(data) != "upgrade to TLS/1.2, HTTP/1.1 required" {
+		t.Errorf("unexpected body %q", data)
+	}
+}
+
+// waitForGoroutines waits for the number of goroutines to get back to at most n.
+func waitForGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected at most %d goroutines, still got %d", n, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHangingResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewHangingResponder())
+	client := &http.Client{Transport: tr, Timeout: 50 * time.Millisecond}
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 10; i++ {
+		_, err := client.Get(testUrl)
+		if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			t.Fatalf("expected a client timeout, got %v", err)
+		}
+	}
+	waitForGoroutines(t, before)
+}
+
+func TestHangAfterHeadersResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewHangAfterHeadersResponder())
+	client := &http.Client{Transport: tr, Timeout: 50 * time.Millisecond}
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 10; i++ {
+		resp, err := client.Get(testUrl)
+		if err != nil {
+			t.Fatalf("expected the headers to arrive, got %s", err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected a 200, got %d", resp.StatusCode)
+		}
+		if _, err := ioutil.ReadAll(resp.Body); err == nil {
+			t.Fatal("expected reading the body to time out")
+		}
+		resp.Body.Close()
+	}
+	waitForGoroutines(t, before)
+
+	// closing the body unblocks a pending read
+	resp, err := NewHangAfterHeadersResponder()(httptest.NewRequest("GET", testUrl, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := resp.Body.Read(make([]byte, 1))
+		done <- err
+	}()
+	resp.Body.Close()
+	if err := <-done; err == nil {
+		t.Fatal("expected the read on a closed body to fail")
+	}
+}
+
+func TestColdStartResponder(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	responder := NewColdStartResponder(time.Second, NewStringResponder(200, "warm"))
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cold := make(chan error)
+	go func() {
+		_, err := responder(req)
+		cold <- err
+	}()
+	waitForClockWaiters(t, clock, 1)
+
+	// concurrent and later calls don't wait
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp, err := responder(req); err != nil || resp.StatusCode != 200 {
+				t.Errorf("expected an immediate response, got %v %v", resp, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-cold:
+		t.Fatal("expected the first call to wait for the cold delay")
+	default:
+	}
+	clock.Advance(time.Second)
+	if err := <-cold; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestColdStartResponderCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	responder := NewColdStartResponder(time.Hour, NewStringResponder(200, "warm"))
+	if _, err := responder(req); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := responder(httptest.NewRequest("GET", testUrl, nil)); err != nil {
+		t.Fatalf("expected the cold start to be spent, got %s", err)
+	}
+}
+
+func TestScheduledFailureResponder(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewMockClock(start)
+	SetClock(clock)
+	defer SetClock(nil)
+
+	responder := NewScheduledFailureResponder([]TimeRange{
+		{Start: start.Add(time.Minute), End: start.Add(2 * time.Minute)},
+		{Start: start.Add(5 * time.Minute), End: start.Add(6 * time.Minute)},
+	}, NewStringResponder(200, ""), NewStringResponder(500, ""))
+
+	expected := []struct {
+		at     time.Duration
+		status int
+	}{
+		{0, 200},
+		{time.Minute, 500},
+		{90 * time.Second, 500},
+		{2 * time.Minute, 200},
+		{5*time.Minute + time.Second, 500},
+		{6 * time.Minute, 200},
+	}
+	for _, e := range expected {
+		clock.Set(start.Add(e.at))
+		resp, err := responder(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != e.status {
+			t.Errorf("at %s: expected %d, got %d", e.at, e.status, resp.StatusCode)
+		}
+	}
+}
+
+// trailerSettingReader fills in trailer once its reader is exhausted, as a streaming client does.
+type trailerSettingReader struct {
+	r       io.Reader
+	trailer http.Header
+	values  http.Header
+}
+
+func (t *trailerSettingReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err == io.EOF {
+		for key, values := range t.values {
+			t.trailer[key] = values
+		}
+	}
+	return n, err
+}
+
+func TestTrailerEchoResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", testUrl, NewTrailerEchoResponder(200, "done", "Grpc-Status", "grpc-message", "X-Missing"))
+	client := &http.Client{Transport: tr}
+
+	trailer := http.Header{"Grpc-Status": nil, "Grpc-Message": nil}
+	req, err := http.NewRequest("POST", testUrl, &trailerSettingReader{
+		r:       strings.NewReader("payload"),
+		trailer: trailer,
+		values:  http.Header{"Grpc-Status": {"0"}, "Grpc-Message": {"ok"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Trailer = trailer
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp.Trailer["Grpc-Status"]; !ok || resp.Trailer.Get("Grpc-Status") != "" {
+		t.Fatalf("expected the trailer declared but not filled before the body is read, got %v", resp.Trailer)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "done" {
+		t.Fatalf("expected the body, got %q", data)
+	}
+	if resp.Trailer.Get("Grpc-Status") != "0" || resp.Trailer.Get("Grpc-Message") != "ok" {
+		t.Fatalf("expected the request trailers echoed, got %v", resp.Trailer)
+	}
+	if values, ok := resp.Trailer["X-Missing"]; !ok || len(values) != 0 {
+		t.Fatalf("expected a missing trailer to be declared without value, got %v", resp.Trailer)
+	}
+}
+
+func TestErrorRateResponder(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		calls    int
+		failures []int
+	}{
+		{0.25, 12, []int{4, 8, 12}},
+		{0.5, 6, []int{2, 4, 6}},
+		{0, 5, nil},
+		{1, 3, []int{1, 2, 3}},
+		{0.3, 10, []int{4, 7, 10}},
+	}
+
+	for _, test := range tests {
+		responder, err := NewErrorRateResponder(test.rate, NewStringResponder(200, ""), 503)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var failures []int
+		for i := 1; i <= test.calls; i++ {
+			resp, err := responder(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode == 503 {
+				failures = append(failures, i)
+			}
+		}
+		if !reflect.DeepEqual(failures, test.failures) {
+			t.Errorf("rate %v: expected failures on calls %v, got %v", test.rate, test.failures, failures)
+		}
+	}
+
+	for _, rate := range []float64{-0.1, 1.5, math.NaN()} {
+		if _, err := NewErrorRateResponder(rate, NewStringResponder(200, ""), 503); err == nil {
+			t.Errorf("expected rate %v to be rejected", rate)
+		}
+	}
+}
+
+func TestConcurrencyLimitResponder(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	responder := NewConcurrencyLimitResponder(2, 503, func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("X-Block") != "" {
+			entered <- struct{}{}
+			<-release
+		}
+		return NewStringResponse(200, ""), nil
+	})
+
+	call := func(block bool) int {
+		req, err := http.NewRequest("GET", testUrl, nil)
+		if err != nil {
+			t.Error(err)
+			return 0
+		}
+		if block {
+			req.Header.Set("X-Block", "1")
+		}
+		resp, err := responder(req)
+		if err != nil {
+			t.Error(err)
+			return 0
+		}
+		return resp.StatusCode
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if status := call(true); status != 200 {
+				t.Errorf("expected the calls under the limit to go through, got %d", status)
+			}
+		}()
+	}
+	<-entered
+	<-entered
+
+	if status := call(false); status != 503 {
+		t.Errorf("expected the call over the limit to get a 503, got %d", status)
+	}
+
+	close(release)
+	wg.Wait()
+	if status := call(false); status != 200 {
+		t.Errorf("expected the finished calls to free their slots, got %d", status)
+	}
+}
+
+func TestSequenceTokenResponder(t *testing.T) {
+	responder := NewSequenceTokenResponder("X-Seq", NewStringResponder(200, "ok"))
+
+	tests := []struct {
+		token  string
+		status int
+	}{
+		{"5", 200},
+		{"6", 200},
+		{"6", 409},
+		{"4", 409},
+		{"", 400},
+		{"seven", 400},
+		{"9", 200},
+		{"8", 409},
+		{"10", 200},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("POST", testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.token != "" {
+			req.Header.Set("X-Seq", test.token)
+		}
+		resp, err := responder(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.status {
+			t.Errorf("token %q: expected %d, got %d", test.token, test.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestUTF8ValidatingResponder(t *testing.T) {
+	responder := NewUTF8ValidatingResponder(func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		return NewBytesResponse(200, body), nil
+	})
+
+	tests := []struct {
+		body     string
+		status   int
+		expected string
+	}{
+		{"héllo ✓", 200, "héllo ✓"},
+		{"", 200, ""},
+		{"hé\xffllo", 400, "request body is not valid UTF-8 at byte 3"},
+		{"\xe2\x9c", 400, "request body is not valid UTF-8 at byte 0"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("POST", testUrl, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := responder(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != test.status || string(data) != test.expected {
+			t.Errorf("%q: expected %d %q, got %d %q", test.body, test.status, test.expected, resp.StatusCode, data)
+		}
+	}
+}
+
+func TestByIdempotency(t *testing.T) {
+	responder := ByIdempotency(NewStringResponder(503, "retry me"), NewStringResponder(409, "don't"))
+
+	for method, status := range map[string]int{
+		"GET": 503, "HEAD": 503, "PUT": 503, "DELETE": 503, "OPTIONS": 503, "TRACE": 503,
+		"POST": 409, "PATCH": 409, "CONNECT": 409,
+	} {
+		req, err := http.NewRequest(method, testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := responder(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected %d, got %d", method, status, resp.StatusCode)
+		}
+	}
+}
+
+func TestStatusResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStatusResponder(200, "", "ok"))
+	tr.RegisterResponder("POST", testUrl, NewStatusResponder(299, "Everything Fine", ""))
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Status != "200 OK" {
+		t.Errorf("expected the standard reason phrase, got %q", resp.Status)
+	}
+
+	if resp, err = client.Post(testUrl, "text/plain", nil); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Status != "299 Everything Fine" || resp.StatusCode != 299 {
+		t.Errorf("expected a custom reason phrase, got %q (%d)", resp.Status, resp.StatusCode)
+	}
+}
+
+func TestSizedResponder(t *testing.T) {
+	tr := NewMockTransport()
+	tr.SetStrictContentLength(t)
+	tr.RegisterResponder("GET", testUrl, NewSizedResponder("size", 'x'))
+	client := &http.Client{Transport: tr}
+
+	for query, size := range map[string]int{
+		"?size=0":       0,
+		"?size=5":       5,
+		"?size=3000000": 3000000,
+		"":              DefaultSizedResponderSize,
+		"?size=big":     DefaultSizedResponderSize,
+		"?size=-1":      DefaultSizedResponderSize,
+	} {
+		resp, err := client.Get(testUrl + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != size || resp.ContentLength != int64(size) || strings.Trim(string(data), "x") != "" {
+			t.Errorf("%q: expected %d bytes of x, got %d with Content-Length %d", query, size, len(data), resp.ContentLength)
+		}
+		if header := resp.Header.Get("Content-Length"); header != strconv.Itoa(size) {
+			t.Errorf("%q: expected a Content-Length header of %d, got %q", query, size, header)
+		}
+	}
+}
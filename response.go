@@ -2,12 +2,18 @@ package httpmock
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,13 +28,22 @@ func ResponderFromResponse(resp *http.Response) Responder {
 // an http status code.
 func NewStringResponse(status int, body string) *http.Response {
 	return &http.Response{
-		Status:     strconv.Itoa(status),
+		Status:     statusLine(status),
 		StatusCode: status,
 		Body:       NewRespBodyFromString(body),
 		Header:     http.Header{},
 	}
 }
 
+// statusLine returns the Status of a response with the given code as net/http sets it, the code
+// followed by its reason phrase, as in "200 OK", or the bare code if it has none.
+func statusLine(code int) string {
+	if text := http.StatusText(code); text != "" {
+		return strconv.Itoa(code) + " " + text
+	}
+	return strconv.Itoa(code)
+}
+
 // NewStringResponder creates a Responder from a given body (as a string) and status code.
 func NewStringResponder(status int, body string) Responder {
 	return ResponderFromResponse(NewStringResponse(status, body))
@@ -38,7 +53,7 @@ func NewStringResponder(status int, body string) Responder {
 // an http status code.
 func NewBytesResponse(status int, body []byte) *http.Response {
 	return &http.Response{
-		Status:     strconv.Itoa(status),
+		Status:     statusLine(status),
 		StatusCode: status,
 		Body:       NewRespBodyFromBytes(body),
 		Header:     http.Header{},
@@ -94,6 +109,29 @@ func NewXmlResponder(status int, body interface{}) (Responder, error) {
 	return ResponderFromResponse(resp), nil
 }
 
+// NewImageResponse creates an *http.Response with the given image as body and a Content-Type
+// detected from its content with http.DetectContentType (image/png, image/jpeg, image/gif,
+// image/webp...).  Also accepts an http status code.  It fails if data isn't a recognized image.
+func NewImageResponse(status int, data []byte) (*http.Response, error) {
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("httpmock: data is not a recognized image, detected %s", contentType)
+	}
+	response := NewBytesResponse(status, data)
+	response.Header.Set("Content-Type", contentType)
+	return response, nil
+}
+
+// NewImageResponder creates a Responder from a given image (as a byte slice) and status code, see
+// NewImageResponse.
+func NewImageResponder(status int, data []byte) (Responder, error) {
+	resp, err := NewImageResponse(status, data)
+	if err != nil {
+		return nil, err
+	}
+	return ResponderFromResponse(resp), nil
+}
+
 // NewRespBodyFromString creates an io.ReadCloser from a string that is suitable for use as an
 // http response body.
 func NewRespBodyFromString(body string) io.ReadCloser {
@@ -106,25 +144,74 @@ func NewRespBodyFromBytes(body []byte) io.ReadCloser {
 	return &dummyReadCloser{bytes.NewReader(body)}
 }
 
+// NewRespBodyWithEOF creates an io.ReadCloser from a string that returns eof instead of io.EOF once
+// the whole body has been read, for instance io.ErrUnexpectedEOF to make a complete looking body
+// fail.  A nil eof stands for io.EOF.
+func NewRespBodyWithEOF(body string, eof error) io.ReadCloser {
+	if eof == nil {
+		eof = io.EOF
+	}
+	return &eofReadCloser{r: strings.NewReader(body), eof: eof}
+}
+
+// NewByteCountingBody creates an io.ReadCloser serving data that keeps count of the bytes read
+// from it so far, partial reads included.  The count is updated atomically, read it with
+// atomic.LoadInt64 to tell whether the client read the whole body or stopped early.
+func NewByteCountingBody(data []byte) (io.ReadCloser, *int64) {
+	body := &countingReadCloser{r: bytes.NewReader(data), n: new(int64)}
+	return body, body.n
+}
+
+// NewWriterToBody creates an io.ReadCloser serving data whose type also implements io.WriterTo, so
+// that io.Copy and friends hand it the destination writer instead of reading it in chunks.  Plain
+// reads keep working for the consumers that don't take that path.
+func NewWriterToBody(data []byte) io.ReadCloser {
+	return &writerToBody{r: bytes.NewReader(data)}
+}
+
+// CloneResponse returns a deep copy of resp, for helpers that need to keep or hand out a response
+// more than once.  Its body is read in full and replaced, so that resp and the copy each get an
+// independent reader over the same bytes; the Header, Trailer and TransferEncoding are copied too.
+// Request and TLS still point to the same values.  A nil body stays nil.  If reading the body
+// fails, the error is returned and resp is left with whatever was not read.
+func CloneResponse(resp *http.Response) (*http.Response, error) {
+	if resp == nil {
+		return nil, errors.New("httpmock: nil response")
+	}
+
+	clone := *resp
+	if resp.Body != nil && resp.Body != http.NoBody {
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+		clone.Body = ioutil.NopCloser(bytes.NewReader(data))
+	}
+	// the trailer may only be filled in once the body has been read
+	clone.Header = resp.Header.Clone()
+	clone.Trailer = resp.Trailer.Clone()
+	clone.TransferEncoding = append([]string(nil), resp.TransferEncoding...)
+	return &clone, nil
+}
+
 // same as ResponderFromResponse with delay support
 func ResponderFromDelayResponse(delay time.Duration, resp *http.Response) Responder {
-	return func(req *http.Request) (*http.Response, error) {
-		time.Sleep(delay)
-		return resp, nil
-	}
+	return WithFixedDelay(delay)(ResponderFromResponse(resp))
 }
 
 // NewStringResponder creates a Responder from a given body (as a string) and status code.
 // it use delay to test cancellation incoming request
 func NewStringResponderWithDelay(delay time.Duration, status int, body string) Responder {
-	return ResponderFromDelayResponse(delay, NewStringResponse(status, body))
+	return NewResponder(status, body, WithFixedDelay(delay))
 }
 
 // NewStringResponse creates an *http.Response with a body based on the given string.  Also accepts
 // an http status code.
 func NewSlowStringResponse(status int, body string) *http.Response {
 	return &http.Response{
-		Status:     strconv.Itoa(status),
+		Status:     statusLine(status),
 		StatusCode: status,
 		Body:       NewSlowRespBodyFromString(body, 4096),
 		Header:     http.Header{},
@@ -136,7 +223,7 @@ func NewSlowRespBodyFromString(body string, bps int) io.ReadCloser {
 }
 
 func NewSlowStringResponder(delay time.Duration, status int, body string) Responder {
-	return ResponderFromResponse(NewSlowStringResponse(status, body))
+	return NewResponder(status, body, WithThrottle(4096))
 }
 
 type dummyReadCloser struct {
@@ -155,6 +242,54 @@ func (d *dummyReadCloser) Close() error {
 	return nil
 }
 
+type eofReadCloser struct {
+	r   io.Reader
+	eof error
+}
+
+func (e *eofReadCloser) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err == io.EOF {
+		err = e.eof
+	}
+	return n, err
+}
+
+func (e *eofReadCloser) Close() error {
+	return nil
+}
+
+type countingReadCloser struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
+
+func (c *countingReadCloser) Close() error {
+	return nil
+}
+
+type writerToBody struct {
+	r *bytes.Reader
+}
+
+func (w *writerToBody) Read(p []byte) (int, error) {
+	return w.r.Read(p)
+}
+
+func (w *writerToBody) WriteTo(dst io.Writer) (int64, error) {
+	return w.r.WriteTo(dst)
+}
+
+func (w *writerToBody) Close() error {
+	return nil
+}
+
 type SlowReader struct {
 	delay time.Duration
 	r     io.ReadSeeker
@@ -176,3 +311,204 @@ func NewSlowReader(r io.ReadSeeker, bps int) io.ReadSeeker {
 		delay: delay,
 	}
 }
+
+// JitterConfig describes the pacing of the body of NewJitteredSlowResponder.
+type JitterConfig struct {
+	// BaseDelay is the average wait before each chunk, and Jitter how far it may stray from it:
+	// each wait is drawn from [BaseDelay-Jitter, BaseDelay+Jitter], and never less than zero.
+	BaseDelay time.Duration
+	Jitter    time.Duration
+
+	// MinChunk and MaxChunk bound the size of the chunks, drawn from [MinChunk, MaxChunk].  A
+	// MinChunk of zero or less means 1, and a MaxChunk below MinChunk means chunks of MinChunk.
+	MinChunk int
+	MaxChunk int
+
+	// Seed makes every body use the same sequence of waits and chunk sizes.  With a zero Seed,
+	// they are drawn from the package source of randomness, see SetRandSource.
+	Seed int64
+}
+
+// NewJitteredSlowResponder creates a Responder whose body is delivered in chunks of varying size
+// with varying waits in between, as measured by the package Clock, reproducing the bursty reads
+// that trip up bufio based parsers.  Reads fail with the context's error if the request is canceled
+// while waiting.
+func NewJitteredSlowResponder(status int, body []byte, cfg JitterConfig) Responder {
+	if cfg.MinChunk <= 0 {
+		cfg.MinChunk = 1
+	}
+	if cfg.MaxChunk < cfg.MinChunk {
+		cfg.MaxChunk = cfg.MinChunk
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		jittered := &jitteredBody{ctx: context.Background(), data: body, cfg: cfg, int63n: requestRand(req).Int63n}
+		if req != nil {
+			jittered.ctx = req.Context()
+		}
+		if cfg.Seed != 0 {
+			jittered.int63n = rand.New(rand.NewSource(cfg.Seed)).Int63n
+		}
+		return &http.Response{
+			Status:        statusLine(status),
+			StatusCode:    status,
+			Body:          jittered,
+			Header:        http.Header{},
+			ContentLength: int64(len(body)),
+		}, nil
+	}
+}
+
+// jitteredBody hands out data in chunks, following its JitterConfig.
+type jitteredBody struct {
+	ctx    context.Context
+	data   []byte
+	pos    int
+	cfg    JitterConfig
+	int63n func(int64) int64
+}
+
+func (j *jitteredBody) Read(p []byte) (int, error) {
+	if j.pos == len(j.data) {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	delay := j.cfg.BaseDelay
+	if j.cfg.Jitter > 0 {
+		delay += time.Duration(j.int63n(int64(2*j.cfg.Jitter+1))) - j.cfg.Jitter
+	}
+	size := j.cfg.MinChunk
+	if j.cfg.MaxChunk > j.cfg.MinChunk {
+		size += int(j.int63n(int64(j.cfg.MaxChunk - j.cfg.MinChunk + 1)))
+	}
+
+	if delay > 0 {
+		select {
+		case <-getClock().After(delay):
+		case <-j.ctx.Done():
+			return 0, j.ctx.Err()
+		}
+	}
+
+	if size > len(p) {
+		size = len(p)
+	}
+	n := copy(p[:size], j.data[j.pos:])
+	j.pos += n
+	return n, nil
+}
+
+func (j *jitteredBody) Close() error {
+	return nil
+}
+
+// NewBurstyBody creates a body delivering data in bursts of burstSize bytes, pausing for pause, as
+// measured by the package Clock, between two bursts.  Unlike the steady trickle of SlowReader, it
+// exercises read loops assuming steady delivery.  A burstSize of zero or less delivers data in a
+// single burst.  Seeking moves within data and starts a new burst.
+func NewBurstyBody(data []byte, burstSize int, pause time.Duration) io.ReadCloser {
+	if burstSize <= 0 {
+		burstSize = len(data)
+	}
+	return &burstyBody{r: bytes.NewReader(data), size: burstSize, pause: pause}
+}
+
+// burstyBody hands out its bytes in bursts.
+type burstyBody struct {
+	r     *bytes.Reader
+	size  int
+	pause time.Duration
+	left  int
+	began bool
+}
+
+func (b *burstyBody) Read(p []byte) (int, error) {
+	if b.r.Len() == 0 {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if b.left == 0 {
+		// the first burst comes right away, the next ones after a pause
+		if b.began && b.pause > 0 {
+			<-getClock().After(b.pause)
+		}
+		b.began = true
+		b.left = b.size
+	}
+	if len(p) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.r.Read(p)
+	b.left -= n
+	return n, err
+}
+
+// Seek moves within the data, the next read starting a new burst right away.
+func (b *burstyBody) Seek(offset int64, whence int) (int64, error) {
+	pos, err := b.r.Seek(offset, whence)
+	if err == nil {
+		b.left, b.began = 0, false
+	}
+	return pos, err
+}
+
+func (b *burstyBody) Close() error {
+	return nil
+}
+
+// NewPacketizedBody creates a body delivering data in packets of at most packetSize bytes, one per
+// read whatever the size of the buffer, waiting interval, as measured by the package Clock,
+// between two packets.  It models delivery bounded by the MTU of a network, with realistic chunks
+// rather than the single bytes of SlowReader.  A packetSize of zero or less delivers data in a
+// single packet.  Seeking moves within data, the next packet coming right away.
+func NewPacketizedBody(data []byte, packetSize int, interval time.Duration) io.ReadCloser {
+	if packetSize <= 0 {
+		packetSize = len(data)
+	}
+	return &packetizedBody{r: bytes.NewReader(data), size: packetSize, interval: interval}
+}
+
+// packetizedBody hands out its bytes a packet per read.
+type packetizedBody struct {
+	r        *bytes.Reader
+	size     int
+	interval time.Duration
+	began    bool
+}
+
+func (b *packetizedBody) Read(p []byte) (int, error) {
+	if b.r.Len() == 0 {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// the first packet comes right away, the next ones after an interval
+	if b.began && b.interval > 0 {
+		<-getClock().After(b.interval)
+	}
+	b.began = true
+	if len(p) > b.size {
+		p = p[:b.size]
+	}
+	return b.r.Read(p)
+}
+
+// Seek moves within the data, the next packet coming right away.
+func (b *packetizedBody) Seek(offset int64, whence int) (int64, error) {
+	pos, err := b.r.Seek(offset, whence)
+	if err == nil {
+		b.began = false
+	}
+	return pos, err
+}
+
+func (b *packetizedBody) Close() error {
+	return nil
+}
@@ -1,11 +1,21 @@
 package httpmock
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"image"
+	"image/png"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNewStringResponse(t *testing.T) {
@@ -25,6 +35,13 @@ func TestNewStringResponse(t *testing.T) {
 	if response.StatusCode != status {
 		t.FailNow()
 	}
+
+	if response.Status != "200 OK" {
+		t.Fatalf("expected the status to include the reason phrase, got %q", response.Status)
+	}
+	if status := NewStringResponse(299, "").Status; status != "299" {
+		t.Fatalf("expected an unknown code to stand alone, got %q", status)
+	}
 }
 
 func TestNewBytesResponse(t *testing.T) {
@@ -145,3 +162,374 @@ func TestRewindResponse(t *testing.T) {
 		}
 	}
 }
+
+func TestNewRespBodyWithEOF(t *testing.T) {
+	body := NewRespBodyWithEOF("hello", io.ErrUnexpectedEOF)
+
+	data, err := ioutil.ReadAll(body)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF at the end of the body, got %v", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("expected the whole body to be read, got %q", data)
+	}
+
+	if _, err := ioutil.ReadAll(NewRespBodyFromString("hello")); err != nil {
+		t.Fatalf("expected the default body to end with a clean io.EOF, got %v", err)
+	}
+
+	// a nil eof must end the body, not make it read forever
+	data, err = ioutil.ReadAll(NewRespBodyWithEOF("hello", nil))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("expected a nil eof to end the body with io.EOF, got %q, %v", data, err)
+	}
+}
+
+func TestNewByteCountingBody(t *testing.T) {
+	body, count := NewByteCountingBody([]byte("hello world"))
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(body, buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(count); n != 4 {
+		t.Fatalf("expected 4 bytes read after a partial read, got %d", n)
+	}
+
+	if _, err := ioutil.ReadAll(body); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(count); n != 11 {
+		t.Fatalf("expected the whole body to be counted, got %d", n)
+	}
+}
+
+// writeRecorder records the size of every write it gets, hiding any io.ReaderFrom of the writer.
+type writeRecorder struct {
+	w      io.Writer
+	writes []int
+}
+
+func (r *writeRecorder) Write(p []byte) (int, error) {
+	r.writes = append(r.writes, len(p))
+	return r.w.Write(p)
+}
+
+func TestNewWriterToBody(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+
+	body := NewWriterToBody(data)
+	if _, ok := body.(io.WriterTo); !ok {
+		t.Fatal("expected the body to implement io.WriterTo")
+	}
+
+	var copied bytes.Buffer
+	rec := &writeRecorder{w: &copied}
+	if _, err := io.Copy(rec, body); err != nil {
+		t.Fatal(err)
+	}
+	// reading in chunks would have taken several writes of io.Copy's 32KB buffer
+	if !reflect.DeepEqual(rec.writes, []int{len(data)}) {
+		t.Errorf("expected io.Copy to go through WriteTo in a single write, got writes of %v", rec.writes)
+	}
+	if !bytes.Equal(copied.Bytes(), data) {
+		t.Error("expected WriteTo to copy the whole body")
+	}
+
+	// the body the client gets from the transport still takes the fast path, through the checks of
+	// the transport too: those still see the body read to the end
+	for _, declared := range []int{len(data), len(data) + 1} {
+		tr := NewMockTransport()
+		errs := make(chan error, 1)
+		tr.SetStrictContentLengthErrors(errs)
+		tr.TrackResponseBodies(AllowReadToEOF())
+		tr.RegisterResponder("GET", testUrl, func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode:    200,
+				Header:        http.Header{"Content-Length": {strconv.Itoa(declared)}},
+				ContentLength: int64(declared),
+				Body:          NewWriterToBody(data),
+			}, nil
+		})
+		resp, err := (&http.Client{Transport: tr}).Get(testUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copied.Reset()
+		rec = &writeRecorder{w: &copied}
+		if _, err := io.Copy(rec, resp.Body); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(rec.writes, []int{len(data)}) || !bytes.Equal(copied.Bytes(), data) {
+			t.Errorf("expected io.Copy of the client's body to go through WriteTo, got writes of %v", rec.writes)
+		}
+		tr.AssertAllResponseBodiesClosed(t)
+
+		select {
+		case err := <-errs:
+			if declared == len(data) {
+				t.Errorf("expected the body to have its declared length, got %s", err)
+			}
+		default:
+			if declared != len(data) {
+				t.Errorf("expected a body shorter than its declared %d bytes to be reported", declared)
+			}
+		}
+	}
+
+	read, err := ioutil.ReadAll(NewWriterToBody([]byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(read) != "hello" {
+		t.Errorf("expected plain reads to serve the body, got %q", read)
+	}
+}
+
+func TestNewImageResponse(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		data        []byte
+		contentType string
+	}{
+		{encoded.Bytes(), "image/png"},
+		{[]byte("GIF89a\x01\x00\x01\x00"), "image/gif"},
+		{[]byte("\xff\xd8\xff\xe0"), "image/jpeg"},
+	}
+	for _, test := range tests {
+		resp, err := NewImageResponse(200, test.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != test.contentType {
+			t.Errorf("expected %s, got %s", test.contentType, ct)
+		}
+		if data, _ := ioutil.ReadAll(resp.Body); !bytes.Equal(data, test.data) {
+			t.Errorf("expected the image as body, got %q", data)
+		}
+	}
+
+	if _, err := NewImageResponder(200, []byte("not an image")); err == nil {
+		t.Fatal("expected text to be refused")
+	}
+}
+
+// recordingClock is a Clock whose timers fire right away, recording the waits asked for.
+type recordingClock struct {
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func (c *recordingClock) Now() time.Time { return time.Now() }
+
+func (c *recordingClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	c.waits = append(c.waits, d)
+	c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+func TestJitteredSlowResponder(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789"), 100)
+	cfg := JitterConfig{
+		BaseDelay: 10 * time.Millisecond,
+		Jitter:    5 * time.Millisecond,
+		MinChunk:  1,
+		MaxChunk:  64,
+		Seed:      42,
+	}
+
+	read := func() ([]time.Duration, []int) {
+		clock := &recordingClock{}
+		SetClock(clock)
+		defer SetClock(nil)
+
+		resp, err := NewJitteredSlowResponder(200, body, cfg)(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data []byte
+		var chunks []int
+		buf := make([]byte, 1024)
+		for {
+			n, err := resp.Body.Read(buf)
+			data = append(data, buf[:n]...)
+			if n > 0 {
+				chunks = append(chunks, n)
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(data, body) {
+			t.Fatalf("expected the %d bytes of the body, got %d", len(body), len(data))
+		}
+		return clock.waits, chunks
+	}
+
+	waits, chunks := read()
+	gaps := map[time.Duration]bool{}
+	for _, wait := range waits {
+		if wait < 5*time.Millisecond || wait > 15*time.Millisecond {
+			t.Fatalf("expected waits within the jitter, got %s", wait)
+		}
+		gaps[wait] = true
+	}
+	if len(gaps) < 2 {
+		t.Fatalf("expected distinct gaps between chunks, got %v", waits)
+	}
+	sizes := map[int]bool{}
+	for _, chunk := range chunks {
+		sizes[chunk] = true
+	}
+	if len(sizes) < 2 {
+		t.Fatalf("expected chunks of varying sizes, got %v", chunks)
+	}
+
+	waits2, chunks2 := read()
+	if !reflect.DeepEqual(waits, waits2) || !reflect.DeepEqual(chunks, chunks2) {
+		t.Fatal("expected the same seed to pace bodies the same way")
+	}
+}
+
+func TestJitteredSlowResponderCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := NewJitteredSlowResponder(200, []byte("abc"), JitterConfig{BaseDelay: time.Hour})(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := ioutil.ReadAll(resp.Body); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBurstyBody(t *testing.T) {
+	clock := &recordingClock{}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	body := NewBurstyBody([]byte("0123456789"), 4, time.Second)
+	var reads []string
+	buf := make([]byte, 3)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			reads = append(reads, string(buf[:n]))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"012", "3", "456", "7", "89"}
+	if !reflect.DeepEqual(reads, expected) {
+		t.Fatalf("expected reads %q, got %q", expected, reads)
+	}
+	if !reflect.DeepEqual(clock.waits, []time.Duration{time.Second, time.Second}) {
+		t.Fatalf("expected a pause between bursts, got %v", clock.waits)
+	}
+
+	// seeking starts a new burst right away
+	if _, err := body.(io.Seeker).Seek(2, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "23456789" || len(clock.waits) != 3 {
+		t.Fatalf("expected the rest of the body after one more pause, got %q after %v", data, clock.waits)
+	}
+}
+
+func TestPacketizedBody(t *testing.T) {
+	clock := &recordingClock{}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	body := NewPacketizedBody([]byte("0123456789"), 4, 10*time.Millisecond)
+	var reads []string
+	buf := make([]byte, 64)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			reads = append(reads, string(buf[:n]))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"0123", "4567", "89"}
+	if !reflect.DeepEqual(reads, expected) {
+		t.Fatalf("expected packets %q, got %q", expected, reads)
+	}
+	if !reflect.DeepEqual(clock.waits, []time.Duration{10 * time.Millisecond, 10 * time.Millisecond}) {
+		t.Fatalf("expected an interval between packets, got %v", clock.waits)
+	}
+
+	// seeking sends the next packet right away
+	if _, err := body.(io.Seeker).Seek(6, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := body.Read(buf); err != nil || string(buf[:n]) != "6789" || len(clock.waits) != 2 {
+		t.Fatalf("expected the packet after the seek without waiting, got %q %v after %v", buf[:n], err, clock.waits)
+	}
+}
+
+func TestCloneResponse(t *testing.T) {
+	resp := NewStringResponse(200, "hello")
+	resp.Header.Set("X-Test", "1")
+	resp.TransferEncoding = []string{"chunked"}
+	resp.Trailer = http.Header{"X-Checksum": []string{"abc"}}
+
+	clone, err := CloneResponse(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone.Header.Set("X-Test", "2")
+	clone.Trailer.Set("X-Checksum", "def")
+	clone.TransferEncoding[0] = "identity"
+	if resp.Header.Get("X-Test") != "1" || resp.Trailer.Get("X-Checksum") != "abc" || resp.TransferEncoding[0] != "chunked" {
+		t.Error("expected the clone not to share its headers, trailer or transfer encoding")
+	}
+
+	for _, r := range []*http.Response{clone, resp} {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "hello" {
+			t.Errorf("expected both bodies to hold the whole content, got %q", data)
+		}
+	}
+
+	resp.Body = nil
+	if clone, err = CloneResponse(resp); err != nil || clone.Body != nil {
+		t.Errorf("expected a nil body to stay nil, got %v, %v", clone.Body, err)
+	}
+	if _, err := CloneResponse(nil); err == nil {
+		t.Error("expected a nil response to fail")
+	}
+}
@@ -0,0 +1,127 @@
+package httpmock
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// ErrSequenceExhausted is returned by a SequenceResponder called past its last responder, unless
+// its policy is StickLast.
+var ErrSequenceExhausted = errors.New("httpmock: responder sequence exhausted")
+
+// SequenceResponder answers successive requests with successive responders.  What happens once
+// they are all used up is set by its exhaustion policy, StickLast by default.  It is created with
+// NewSequenceResponder and registered through its Respond method:
+//
+//	seq := httpmock.NewSequenceResponder([]httpmock.Responder{
+//		httpmock.NewStringResponder(503, ""),
+//		httpmock.NewStringResponder(200, "ok"),
+//	}, httpmock.FailTest(t))
+//	httpmock.RegisterResponder("GET", "https://api.mybiz.com/status", seq.Respond)
+type SequenceResponder struct {
+	responders  []Responder
+	policy      exhaustionPolicy
+	tb          testing.TB
+	onExhausted func(*http.Request)
+
+	mu        sync.Mutex
+	calls     int
+	exhausted int
+}
+
+type exhaustionPolicy int
+
+const (
+	stickLast exhaustionPolicy = iota
+	errorWhenExhausted
+	failTest
+)
+
+// SequenceOption configures a SequenceResponder.
+type SequenceOption func(*SequenceResponder)
+
+// StickLast makes the last responder of the sequence answer every request once it is reached.  It
+// is the default policy.
+func StickLast() SequenceOption {
+	return func(s *SequenceResponder) {
+		s.policy = stickLast
+	}
+}
+
+// ErrorWhenExhausted makes requests past the end of the sequence fail with ErrSequenceExhausted.
+func ErrorWhenExhausted() SequenceOption {
+	return func(s *SequenceResponder) {
+		s.policy = errorWhenExhausted
+	}
+}
+
+// FailTest makes requests past the end of the sequence fail the test through tb, as well as fail
+// with ErrSequenceExhausted.
+func FailTest(tb testing.TB) SequenceOption {
+	return func(s *SequenceResponder) {
+		s.policy = failTest
+		s.tb = tb
+	}
+}
+
+// OnExhausted calls hook with the first request past the end of the sequence, exactly once
+// whatever the number of concurrent requests.
+func OnExhausted(hook func(req *http.Request)) SequenceOption {
+	return func(s *SequenceResponder) {
+		s.onExhausted = hook
+	}
+}
+
+// NewSequenceResponder creates a SequenceResponder answering the first request with the first of
+// responders, the second with the second one, and so on.
+func NewSequenceResponder(responders []Responder, opts ...SequenceOption) *SequenceResponder {
+	s := &SequenceResponder{responders: append([]Responder(nil), responders...)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Respond is the Responder of the SequenceResponder.
+func (s *SequenceResponder) Respond(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	i := s.calls
+	s.calls++
+	if i >= len(s.responders) {
+		s.exhausted++
+	}
+	s.mu.Unlock()
+
+	if i < len(s.responders) {
+		return s.responders[i](req)
+	}
+
+	if i == len(s.responders) && s.onExhausted != nil {
+		s.onExhausted(req)
+	}
+	switch {
+	case s.policy == stickLast && len(s.responders) > 0:
+		return s.responders[len(s.responders)-1](req)
+	case s.policy == failTest:
+		s.tb.Helper()
+		s.tb.Errorf("httpmock: %s %s is call %d of a sequence of %d responders", req.Method, req.URL, i+1, len(s.responders))
+	}
+	return nil, ErrSequenceExhausted
+}
+
+// Calls returns the number of requests the SequenceResponder received.
+func (s *SequenceResponder) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// Exhausted returns the number of requests received past the end of the sequence, whatever the
+// policy.
+func (s *SequenceResponder) Exhausted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exhausted
+}
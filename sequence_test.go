@@ -0,0 +1,116 @@
+package httpmock
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func sequenceOf(statuses ...int) []Responder {
+	responders := make([]Responder, len(statuses))
+	for i, status := range statuses {
+		responders[i] = NewStringResponder(status, "")
+	}
+	return responders
+}
+
+func TestSequenceResponderPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   func(tb testing.TB) []SequenceOption
+		status int
+		failed bool
+	}{
+		{"default", func(testing.TB) []SequenceOption { return nil }, 200, false},
+		{"StickLast", func(testing.TB) []SequenceOption { return []SequenceOption{StickLast()} }, 200, false},
+		{"ErrorWhenExhausted", func(testing.TB) []SequenceOption { return []SequenceOption{ErrorWhenExhausted()} }, 0, false},
+		{"FailTest", func(tb testing.TB) []SequenceOption { return []SequenceOption{FailTest(tb)} }, 0, true},
+	}
+
+	for _, test := range tests {
+		tb := &recordingTB{TB: t}
+		var hooked []*http.Request
+		opts := append(test.opts(tb), OnExhausted(func(req *http.Request) { hooked = append(hooked, req) }))
+		seq := NewSequenceResponder(sequenceOf(503, 200), opts...)
+
+		tr := NewMockTransport()
+		tr.RegisterResponder("GET", testUrl, seq.Respond)
+		client := &http.Client{Transport: tr}
+
+		for i, expected := range []int{503, 200} {
+			resp, err := client.Get(testUrl)
+			if err != nil || resp.StatusCode != expected {
+				t.Fatalf("%s: call %d: expected %d, got %v %v", test.name, i+1, expected, resp, err)
+			}
+		}
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(testUrl)
+			if test.status == 0 {
+				if err == nil || !strings.Contains(err.Error(), ErrSequenceExhausted.Error()) {
+					t.Errorf("%s: expected the sequence to be exhausted, got %v %v", test.name, resp, err)
+				}
+			} else if err != nil || resp.StatusCode != test.status {
+				t.Errorf("%s: expected the last responder to stick, got %v %v", test.name, resp, err)
+			}
+		}
+
+		if len(hooked) != 1 {
+			t.Errorf("%s: expected the exhaustion hook to be called once, got %d", test.name, len(hooked))
+		}
+		if seq.Calls() != 4 || seq.Exhausted() != 2 {
+			t.Errorf("%s: expected 4 calls, 2 of them exhausted, got %d and %d", test.name, seq.Calls(), seq.Exhausted())
+		}
+		if failed := len(tb.errors) > 0; failed != test.failed {
+			t.Errorf("%s: expected the test to fail: %v, got %q", test.name, test.failed, tb.errors)
+		}
+	}
+}
+
+func TestSequenceResponderEmpty(t *testing.T) {
+	seq := NewSequenceResponder(nil)
+	if _, err := seq.Respond(httptestRequest(t)); err != ErrSequenceExhausted {
+		t.Fatalf("expected an empty sequence to be exhausted right away, got %v", err)
+	}
+}
+
+func TestSequenceResponderConcurrentExhaustion(t *testing.T) {
+	var transitions int32
+	seq := NewSequenceResponder(sequenceOf(200, 200, 200, 200, 200), ErrorWhenExhausted(),
+		OnExhausted(func(*http.Request) { atomic.AddInt32(&transitions, 1) }))
+
+	req := httptestRequest(t)
+	var ok, exhausted int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := seq.Respond(req); err == ErrSequenceExhausted {
+				atomic.AddInt32(&exhausted, 1)
+			} else {
+				atomic.AddInt32(&ok, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if ok != 5 || exhausted != 15 || seq.Exhausted() != 15 {
+		t.Fatalf("expected 5 responses and 15 exhausted calls, got %d and %d", ok, exhausted)
+	}
+	if transitions != 1 {
+		t.Fatalf("expected exactly one call to observe the exhaustion, got %d", transitions)
+	}
+}
+
+func httptestRequest(t *testing.T) *http.Request {
+	req, err := http.NewRequest("GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
@@ -0,0 +1,91 @@
+package httpmock
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// ServerOption configures the server started by MockTransport.Server.
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	baseURL string
+	tls     bool
+}
+
+// ServerBaseURL makes the server map every request it receives onto base before routing it, so
+// that registrations made against the original URLs still match: with a base of
+// "https://api.mybiz.com/v1", a request for "/articles?page=2" is routed as
+// "https://api.mybiz.com/v1/articles?page=2".  Without it, requests are routed with the URL of the
+// server itself.
+func ServerBaseURL(base string) ServerOption {
+	return func(c *serverConfig) {
+		c.baseURL = base
+	}
+}
+
+// ServerTLS makes the server speak HTTPS, see httptest.NewTLSServer.  Clients must trust its
+// certificate, which the *http.Client returned by the Client method of the server does.
+func ServerTLS() ServerOption {
+	return func(c *serverConfig) {
+		c.tls = true
+	}
+}
+
+// Server starts an httptest.Server answering with the registrations of the MockTransport, for code
+// under test that can't be given a custom http.RoundTripper, such as a subprocess or a non-Go HTTP
+// stack.  Requests received by the server go through RoundTrip, so they share the matching, the
+// call history and the call counts of the transport.  Requests no responder matched get a 404, and
+// requests failing with another error a 502, with the error as body.  The server is closed when
+// the test completes.
+func (m *MockTransport) Server(t testing.TB, opts ...ServerOption) (*httptest.Server, error) {
+	cfg := &serverConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var base *url.URL
+	if cfg.baseURL != "" {
+		var err error
+		if base, err = url.Parse(cfg.baseURL); err != nil {
+			return nil, err
+		}
+		if base.Scheme == "" || base.Host == "" {
+			return nil, errors.New("httpmock: server base URL must be absolute: " + cfg.baseURL)
+		}
+	}
+
+	var server *httptest.Server
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := *r.URL
+		if base != nil {
+			target.Scheme = base.Scheme
+			target.Host = base.Host
+			target.Path = strings.TrimSuffix(base.Path, "/") + r.URL.Path
+			target.RawPath = ""
+		} else {
+			serverURL, _ := url.Parse(server.URL)
+			target.Scheme = serverURL.Scheme
+			target.Host = serverURL.Host
+		}
+
+		req := r.Clone(r.Context())
+		req.URL = &target
+		req.Host = target.Host
+		req.RequestURI = ""
+
+		ResponderHandler(m.RoundTrip).ServeHTTP(w, req)
+	})
+
+	if cfg.tls {
+		server = httptest.NewTLSServer(handler)
+	} else {
+		server = httptest.NewServer(handler)
+	}
+	t.Cleanup(server.Close)
+	return server, nil
+}
@@ -0,0 +1,82 @@
+package httpmock
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMockTransportServer(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", "https://api.mybiz.com/v1/articles", func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(201, "created")
+		resp.Header.Add("Set-Cookie", "a=1")
+		resp.Header.Add("Set-Cookie", "b=2")
+		return resp, nil
+	})
+
+	server, err := tr.Server(t, ServerBaseURL("https://api.mybiz.com/v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := server.Client().Post(server.URL+"/articles?draft=1", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected the registration to answer, got %d", resp.StatusCode)
+	}
+	if data, _ := ioutil.ReadAll(resp.Body); string(data) != "created" {
+		t.Fatalf("unexpected body %q", data)
+	}
+	if cookies := resp.Header.Values("Set-Cookie"); len(cookies) != 2 {
+		t.Fatalf("expected both cookies, got %q", cookies)
+	}
+
+	call := tr.LastCall()
+	if call == nil || call.Key != "POST https://api.mybiz.com/v1/articles" || string(call.Body) != "hello" {
+		t.Fatalf("expected the call to be recorded by the transport, got %+v", call)
+	}
+	if call.Request.URL.String() != "https://api.mybiz.com/v1/articles?draft=1" {
+		t.Fatalf("expected the request to be mapped onto the base URL, got %s", call.Request.URL)
+	}
+
+	resp, err = server.Client().Get(server.URL + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 404 {
+		t.Fatalf("expected a 404 for an unmatched request, got %d", resp.StatusCode)
+	}
+}
+
+func TestMockTransportServerTLS(t *testing.T) {
+	tr := NewMockTransport()
+	server, err := tr.Server(t, ServerTLS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(server.URL, "https://") {
+		t.Fatalf("expected an https server, got %s", server.URL)
+	}
+	tr.RegisterResponder("GET", server.URL+"/ping", NewStringResponder(200, "pong"))
+
+	resp, err := server.Client().Get(server.URL + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if data, _ := ioutil.ReadAll(resp.Body); string(data) != "pong" {
+		t.Fatalf("expected the registration to answer, got %d %q", resp.StatusCode, data)
+	}
+}
+
+func TestMockTransportServerBadBaseURL(t *testing.T) {
+	if _, err := NewMockTransport().Server(t, ServerBaseURL("/relative")); err == nil {
+		t.Fatal("expected a relative base URL to be refused")
+	}
+}
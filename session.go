@@ -0,0 +1,64 @@
+package httpmock
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// SessionScenario simulates a cookie based session: a login endpoint handing out a session cookie,
+// and protected endpoints answering with a 401 Unauthorized the requests that don't carry it.  It
+// is created with NewSessionScenario, which registers the login endpoint:
+//
+//	session := httpmock.NewSessionScenario(tr, "session", "POST", "https://api.mybiz.com/login")
+//	session.Protect("GET", "https://api.mybiz.com/me", httpmock.NewStringResponder(200, `{"name": "bob"}`))
+//
+// The client needs a cookie jar to send the cookie back.  As with MatchCookie, only the name and
+// value of the cookie are checked: its Path and Secure attributes are only honored as far as the
+// jar of the client honors them when picking the cookies to send.
+type SessionScenario struct {
+	tr     *MockTransport
+	cookie *http.Cookie
+}
+
+// NewSessionScenario creates a SessionScenario and registers its login endpoint on tr for method
+// and loginURL, answering with a 200 setting the cookie name to a random value, drawn from the
+// package source of randomness (see SetRandSource).  The cookie applies to every path, and is
+// Secure if loginURL is https.
+func NewSessionScenario(tr *MockTransport, name, method, loginURL string) *SessionScenario {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    fmt.Sprintf("%016x%016x", randInt63n(nil, 1<<62), randInt63n(nil, 1<<62)),
+		Path:     "/",
+		HttpOnly: true,
+	}
+	if u, err := url.Parse(loginURL); err == nil && u.Scheme == "https" {
+		cookie.Secure = true
+	}
+
+	s := &SessionScenario{tr: tr, cookie: cookie}
+	tr.RegisterResponder(method, loginURL, func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(http.StatusOK, "")
+		resp.Header.Add("Set-Cookie", cookie.String())
+		return resp, nil
+	})
+	return s
+}
+
+// Protect registers responder on the transport of the SessionScenario for method and url, the
+// requests without the session cookie getting a 401 Unauthorized instead.
+func (s *SessionScenario) Protect(method, url string, responder Responder, opts ...RegisterOption) *Registration {
+	requireCookie := MatchCookie(s.cookie.Name, s.cookie.Value)
+	return s.tr.RegisterResponder(method, url, func(req *http.Request) (*http.Response, error) {
+		if err := requireCookie(req); err != nil {
+			return NewStringResponse(http.StatusUnauthorized, err.Error()), nil
+		}
+		return responder(req)
+	}, opts...)
+}
+
+// Cookie returns a copy of the session cookie handed out by the login endpoint.
+func (s *SessionScenario) Cookie() *http.Cookie {
+	cookie := *s.cookie
+	return &cookie
+}
@@ -0,0 +1,54 @@
+package httpmock
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+)
+
+func TestSessionScenario(t *testing.T) {
+	tr := NewMockTransport()
+	session := NewSessionScenario(tr, "sid", "POST", "https://api.example.com/login")
+	session.Protect("GET", "https://api.example.com/me", NewStringResponder(200, "bob"))
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr, Jar: jar}
+
+	get := func() (int, string) {
+		resp, err := client.Get("https://api.example.com/me")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	if status, _ := get(); status != 401 {
+		t.Errorf("expected a 401 before logging in, got %d", status)
+	}
+
+	resp, err := client.Post("https://api.example.com/login", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	cookie := session.Cookie()
+	if cookie.Name != "sid" || len(cookie.Value) != 32 || !cookie.Secure || cookie.Path != "/" {
+		t.Errorf("unexpected session cookie %v", cookie)
+	}
+	if cookies := resp.Cookies(); len(cookies) != 1 || cookies[0].Value != cookie.Value {
+		t.Errorf("expected the login to set the session cookie, got %v", cookies)
+	}
+
+	if status, body := get(); status != 200 || body != "bob" {
+		t.Errorf("expected the protected endpoint once logged in, got %d %q", status, body)
+	}
+	if err := MatchCookie("sid", cookie.Value)(tr.LastCall().Request); err != nil {
+		t.Errorf("expected the call to carry the session cookie: %v", err)
+	}
+}
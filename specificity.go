@@ -0,0 +1,311 @@
+package httpmock
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// WithPriority sets the priority of a registration, 0 by default.  When several registrations
+// accept a request, the one with the highest priority answers, whatever its other merits, see
+// RegisterResponder.
+func WithPriority(priority int) RegisterOption {
+	return func(r *registration) {
+		r.priority = priority
+	}
+}
+
+// URLMatch tells how closely the URL of a registration matches the one of a request.  Closer
+// matches are greater.
+type URLMatch int
+
+const (
+	// URLWithoutQuery is a registration for the URL of the request stripped of its query.
+	URLWithoutQuery URLMatch = iota + 1
+	// URLEquivalent is a registration for the URL of the request spelled differently, see
+	// WithRawPath.
+	URLEquivalent
+	// URLExact is a registration for the URL of the request, as spelled by the request.
+	URLExact
+)
+
+func (u URLMatch) String() string {
+	switch u {
+	case URLWithoutQuery:
+		return "URL without query"
+	case URLEquivalent:
+		return "equivalent URL"
+	case URLExact:
+		return "exact URL"
+	}
+	return fmt.Sprintf("URLMatch(%d)", int(u))
+}
+
+// constraints returns how many constraints the registration puts on requests beyond their method
+// and URL.
+func (r *registration) constraints() int {
+	n := len(r.matchers)
+	if r.rawPath {
+		n++
+	}
+	return n
+}
+
+// candidate is a registration that may answer a request, along with how its URL matched.
+type candidate struct {
+	r     *registration
+	match URLMatch
+}
+
+// outranks reports whether c answers rather than o when both accept a request.
+func (c candidate) outranks(o candidate) bool {
+	if c.r.priority != o.r.priority {
+		return c.r.priority > o.r.priority
+	}
+	if c.match != o.match {
+		return c.match > o.match
+	}
+	if n, other := c.r.constraints(), o.r.constraints(); n != other {
+		return n > other
+	}
+	return c.r.seq > o.r.seq
+}
+
+// ties reports whether only the order of registration tells c and o apart.
+func (c candidate) ties(o candidate) bool {
+	return c.r.priority == o.r.priority && c.match == o.match && c.r.constraints() == o.r.constraints()
+}
+
+// candidates returns the registrations that may answer req, whose URL is url, best first.
+func (m *MockTransport) candidates(req *http.Request, url string) []candidate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var all []candidate
+	add := func(key string, match URLMatch) {
+		for _, r := range m.responders[key] {
+			all = append(all, candidate{r: r, match: match})
+		}
+	}
+	key := req.Method + " " + url
+	add(key, URLExact)
+	for _, equivalent := range m.equivalentKeys(key) {
+		add(equivalent, URLEquivalent)
+	}
+	if strings.Contains(url, "?") {
+		key = req.Method + " " + strings.Split(url, "?")[0]
+		add(key, URLWithoutQuery)
+		for _, equivalent := range m.equivalentKeys(key) {
+			add(equivalent, URLWithoutQuery)
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].outranks(all[j]) })
+	return all
+}
+
+// selectResponder returns the responder and key of the registration answering req, whose URL is
+// url, and counts the call against that registration.  When none does, it returns why each live
+// candidate didn't match.
+func (m *MockTransport) selectResponder(req *http.Request, url string, body []byte) (Responder, string, []string) {
+	for {
+		// matchers are user code, so they run without holding the lock
+		candidates := m.candidates(req, url)
+		var misses []string
+		selected := -1
+		for i, c := range candidates {
+			m.mu.Lock()
+			live := c.r.live(now())
+			m.mu.Unlock()
+			if !live {
+				continue
+			}
+			if err := c.r.match(req, body); err != nil {
+				misses = append(misses, fmt.Sprintf("%q did not match: %s", c.r.key, err))
+				continue
+			}
+			selected = i
+			break
+		}
+
+		if selected < 0 {
+			setRequestBody(req, body)
+			return nil, "", misses
+		}
+		winner := candidates[selected]
+		m.reportAmbiguity(req, body, winner, candidates[selected+1:])
+		setRequestBody(req, body)
+
+		m.mu.Lock()
+		live := winner.r.live(now())
+		if live {
+			winner.r.use()
+		}
+		m.mu.Unlock()
+
+		// the registration may have expired while its matchers ran, in which case look again
+		if live {
+			return winner.r.responder, winner.r.key, nil
+		}
+	}
+}
+
+// reportAmbiguity logs, if a logger is set, that a registration with matchers that lost to winner
+// only because it is older also accepts req.  Stacking a registration without matchers on another
+// is the documented way to override it, and isn't reported.
+func (m *MockTransport) reportAmbiguity(req *http.Request, body []byte, winner candidate, rest []candidate) {
+	m.mu.Lock()
+	logger := m.logger
+	m.mu.Unlock()
+	if logger == nil || winner.r.constraints() == 0 {
+		return
+	}
+
+	for _, c := range rest {
+		if !c.ties(winner) {
+			return
+		}
+		m.mu.Lock()
+		live := c.r.live(now())
+		m.mu.Unlock()
+		if live && c.r.match(req, body) == nil {
+			logger.Logf("httpmock: %s %s is accepted by %q and %q with the same specificity, using the most recent",
+				req.Method, req.URL, winner.r.key, c.r.key)
+			return
+		}
+	}
+}
+
+// MatchCandidate is a registration considered for a request, as reported by ExplainMatch.  Its
+// Priority, URLMatch and Constraints, compared in this order, make its specificity.
+type MatchCandidate struct {
+	Key         string
+	Priority    int
+	URLMatch    URLMatch
+	Constraints int
+
+	// Selected is set on the registration answering the request.
+	Selected bool
+	// Reason tells why a candidate that isn't selected lost.
+	Reason string
+}
+
+// MatchExplanation describes how a MockTransport chooses the registration answering a request, as
+// returned by ExplainMatch.
+type MatchExplanation struct {
+	// Request is the method and URL of the request.
+	Request string
+	// Candidates are the registrations considered, from the most to the least specific.
+	Candidates []MatchCandidate
+	// Default is the pattern of the default responder answering the request when no candidate
+	// does, see SetDefaultResponder.
+	Default string
+}
+
+// Selected returns the candidate answering the request, or nil if none does.
+func (e *MatchExplanation) Selected() *MatchCandidate {
+	for i := range e.Candidates {
+		if e.Candidates[i].Selected {
+			return &e.Candidates[i]
+		}
+	}
+	return nil
+}
+
+// String formats the explanation one candidate per line.
+func (e *MatchExplanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d candidates", e.Request, len(e.Candidates))
+	for _, c := range e.Candidates {
+		fmt.Fprintf(&b, "\n\t%q (priority %d, %s, %d constraints): ", c.Key, c.Priority, c.URLMatch, c.Constraints)
+		if c.Selected {
+			b.WriteString("selected")
+		} else {
+			b.WriteString(c.Reason)
+		}
+	}
+	if e.Default != "" {
+		fmt.Fprintf(&b, "\n\tdefault responder %q", e.Default)
+	}
+	return b.String()
+}
+
+// ExplainMatch tells which registration would answer req, and why each of the other candidates
+// wouldn't, without calling any responder nor counting a call: it is meant for tests where a
+// request is answered by an unexpected registration.  The matchers of every live candidate run.
+// Simulated host and proxy failures, and pass-through hosts, are not taken into account.  The
+// matchers get a copy of req; its body is read through GetBody when req has one, and otherwise
+// read and put back as an identical one.
+func (m *MockTransport) ExplainMatch(req *http.Request) *MatchExplanation {
+	clientReq := req
+	req, err := prepareRequest(req)
+	if err != nil {
+		return &MatchExplanation{}
+	}
+	url := req.URL.String()
+	explanation := &MatchExplanation{Request: req.Method + " " + url}
+	body, err := ReplayableRequestBody(req)
+	if clientReq.GetBody == nil && body != nil {
+		// the body of the caller was consumed, leave it readable
+		clientReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	if err != nil {
+		return explanation
+	}
+
+	var winner *candidate
+	for _, c := range m.candidates(req, url) {
+		c := c
+		mc := MatchCandidate{
+			Key:         c.r.key,
+			Priority:    c.r.priority,
+			URLMatch:    c.match,
+			Constraints: c.r.constraints(),
+		}
+		m.mu.Lock()
+		live := c.r.live(now())
+		m.mu.Unlock()
+
+		switch {
+		case !live:
+			mc.Reason = "expired"
+		case winner != nil:
+			// candidates come best first, so the winner outranks this one
+			if err := c.r.match(req, body); err != nil {
+				mc.Reason = "did not match: " + err.Error()
+			} else {
+				mc.Reason = lostTo(c, *winner)
+			}
+		default:
+			if err := c.r.match(req, body); err != nil {
+				mc.Reason = "did not match: " + err.Error()
+			} else {
+				mc.Selected = true
+				winner = &c
+			}
+		}
+		explanation.Candidates = append(explanation.Candidates, mc)
+	}
+	setRequestBody(req, body)
+
+	if winner == nil {
+		_, explanation.Default = m.defaultResponder(req)
+	}
+	return explanation
+}
+
+// lostTo tells why c, which accepts the request, doesn't answer it rather than winner.
+func lostTo(c, winner candidate) string {
+	switch {
+	case c.r.priority != winner.r.priority:
+		return fmt.Sprintf("lower priority than %q (%d < %d)", winner.r.key, c.r.priority, winner.r.priority)
+	case c.match != winner.match:
+		return fmt.Sprintf("less exact URL than %q (%s < %s)", winner.r.key, c.match, winner.match)
+	case c.r.constraints() != winner.r.constraints():
+		return fmt.Sprintf("fewer constraints than %q (%d < %d)", winner.r.key, c.r.constraints(), winner.r.constraints())
+	}
+	return fmt.Sprintf("registered before %q, with the same specificity", winner.r.key)
+}
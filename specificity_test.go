@@ -0,0 +1,257 @@
+package httpmock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// hasHeader is a matcher requiring the request to have header key.
+func hasHeader(key string) Matcher {
+	return HeaderMatchesRegexp(key, regexp.MustCompile(""))
+}
+
+func TestSpecificityOrdering(t *testing.T) {
+	type stub struct {
+		url  string
+		opts []RegisterOption
+	}
+	authorized := WithMatcher(hasHeader("Authorization"))
+
+	for _, test := range []struct {
+		name     string
+		stubs    []stub // answering with their index
+		url      string
+		auth     bool
+		expected int
+	}{
+		{
+			name:     "exact URL beats URL without query",
+			stubs:    []stub{{testUrl + "?a=1", nil}, {testUrl, []RegisterOption{authorized}}},
+			url:      testUrl + "?a=1",
+			auth:     true,
+			expected: 0,
+		},
+		{
+			name:     "exact URL beats equivalent URL",
+			stubs:    []stub{{testUrl + "?a=1&b=2", nil}, {testUrl + "?b=2&a=1", []RegisterOption{authorized}}},
+			url:      testUrl + "?a=1&b=2",
+			auth:     true,
+			expected: 0,
+		},
+		{
+			name:     "equivalent URL beats URL without query",
+			stubs:    []stub{{testUrl + "?b=2&a=1", nil}, {testUrl, []RegisterOption{authorized}}},
+			url:      testUrl + "?a=1&b=2",
+			auth:     true,
+			expected: 0,
+		},
+		{
+			name:     "more constraints beat fewer, registered first",
+			stubs:    []stub{{testUrl, []RegisterOption{authorized}}, {testUrl, nil}},
+			url:      testUrl,
+			auth:     true,
+			expected: 0,
+		},
+		{
+			name:     "more constraints beat fewer, registered last",
+			stubs:    []stub{{testUrl, nil}, {testUrl, []RegisterOption{authorized}}},
+			url:      testUrl,
+			auth:     true,
+			expected: 1,
+		},
+		{
+			name:     "each matcher is a constraint",
+			stubs:    []stub{{testUrl, []RegisterOption{WithMatcher(hasHeader("Authorization"), hasHeader("Accept"))}}, {testUrl, []RegisterOption{authorized}}},
+			url:      testUrl,
+			auth:     true,
+			expected: 0,
+		},
+		{
+			name:     "a rejecting registration falls through to less specific ones",
+			stubs:    []stub{{testUrl, nil}, {testUrl, []RegisterOption{authorized}}},
+			url:      testUrl,
+			expected: 0,
+		},
+		{
+			name:     "priority beats constraints",
+			stubs:    []stub{{testUrl, []RegisterOption{authorized}}, {testUrl, []RegisterOption{WithPriority(1)}}},
+			url:      testUrl,
+			auth:     true,
+			expected: 1,
+		},
+		{
+			name:     "priority beats exact URL",
+			stubs:    []stub{{testUrl, []RegisterOption{WithPriority(1)}}, {testUrl + "?a=1", nil}},
+			url:      testUrl + "?a=1",
+			expected: 0,
+		},
+		{
+			name:     "negative priority loses to the default",
+			stubs:    []stub{{testUrl + "?a=1", []RegisterOption{WithPriority(-1), authorized}}, {testUrl, nil}},
+			url:      testUrl + "?a=1",
+			auth:     true,
+			expected: 1,
+		},
+		{
+			name:     "ties go to the most recent registration",
+			stubs:    []stub{{testUrl, []RegisterOption{authorized}}, {testUrl, []RegisterOption{authorized}}},
+			url:      testUrl,
+			auth:     true,
+			expected: 1,
+		},
+		{
+			name:     "expired registrations are skipped",
+			stubs:    []stub{{testUrl, nil}, {testUrl, []RegisterOption{WithPriority(1), WithMaxCalls(1)}}},
+			url:      testUrl,
+			expected: 0, // after a first call, see below
+		},
+	} {
+		tr := NewMockTransport()
+		for i, s := range test.stubs {
+			tr.RegisterResponder("GET", s.url, NewStringResponder(200, fmt.Sprint(i)), s.opts...)
+		}
+
+		get := func() string {
+			req, _ := http.NewRequest("GET", test.url, nil)
+			req.Header.Set("Accept", "*/*")
+			if test.auth {
+				req.Header.Set("Authorization", "Bearer token")
+			}
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			data, _ := ioutil.ReadAll(resp.Body)
+			return string(data)
+		}
+		if strings.HasPrefix(test.name, "expired") {
+			get()
+		}
+		if got := get(); got != fmt.Sprint(test.expected) {
+			t.Errorf("%s: expected registration %d to answer, got %s", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestExplainMatch(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "anonymous"))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "authorized"),
+		WithMatcher(hasHeader("Authorization")))
+	tr.RegisterResponder("GET", testUrl+"?page=1", NewStringResponder(200, "admin"),
+		WithMatcher(hasHeader("X-Admin")))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "gone"), WithMaxCalls(1), WithPriority(2))
+	if _, err := (&http.Client{Transport: tr}).Get(testUrl); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", testUrl+"?page=1", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	explanation := tr.ExplainMatch(req)
+
+	if explanation.Request != "GET "+testUrl+"?page=1" || len(explanation.Candidates) != 4 {
+		t.Fatalf("expected 4 candidates, got %s", explanation)
+	}
+	selected := explanation.Selected()
+	if selected == nil || selected.Key != "GET "+testUrl || selected.Constraints != 1 || selected.URLMatch != URLWithoutQuery {
+		t.Fatalf("expected the authorized registration to be selected, got %s", explanation)
+	}
+
+	reasons := []string{
+		"expired",
+		"did not match: header X-Admin missing",
+		"selected",
+		`fewer constraints than "GET ` + testUrl + `" (0 < 1)`,
+	}
+	for i, reason := range reasons {
+		c := explanation.Candidates[i]
+		if got := c.Reason; c.Selected && reason != "selected" || !c.Selected && got != reason {
+			t.Errorf("candidate %d: expected %q, got %+v", i, reason, c)
+		}
+	}
+	if s := explanation.String(); !strings.Contains(s, `"GET `+testUrl+`" (priority 0, URL without query, 1 constraints): selected`) {
+		t.Errorf("unexpected explanation:\n%s", s)
+	}
+
+	// explaining counts no call
+	if calls := tr.Registrations(); calls[1].Calls != 0 {
+		t.Errorf("expected no call counted, got %+v", calls)
+	}
+}
+
+func TestExplainMatchDefault(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""), WithMatcher(hasHeader("Authorization")))
+	tr.SetDefaultResponder("www.example.com", NewStringResponder(404, ""))
+
+	req, _ := http.NewRequest("GET", testUrl, nil)
+	explanation := tr.ExplainMatch(req)
+	if explanation.Selected() != nil || explanation.Default != "www.example.com" {
+		t.Fatalf("expected the default responder to answer, got %s", explanation)
+	}
+}
+
+func TestSpecificityAmbiguityLogged(t *testing.T) {
+	var logged []string
+	tr := NewMockTransport()
+	tr.SetLogger(LoggerFunc(func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "first"), WithMatcher(hasHeader("Accept")))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, "second"), WithMatcher(hasHeader("Authorization")))
+	tr.RegisterResponder("GET", testUrl+"?a=1", NewStringResponder(200, "plain"))
+	tr.RegisterResponder("GET", testUrl+"?a=1", NewStringResponder(200, "override"))
+
+	req, _ := http.NewRequest("GET", testUrl, nil)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Authorization", "Bearer token")
+	if _, err := tr.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) == 0 || !strings.Contains(logged[0], "with the same specificity") {
+		t.Fatalf("expected the ambiguity to be logged, got %q", logged)
+	}
+
+	// overriding a registration without matchers is not ambiguous
+	logged = nil
+	if _, err := (&http.Client{Transport: tr}).Get(testUrl + "?a=1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range logged {
+		if strings.Contains(line, "same specificity") {
+			t.Errorf("expected no ambiguity logged, got %q", line)
+		}
+	}
+}
+
+func TestRegistrationsShadowingWithPriority(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""), WithPriority(1))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""), WithMatcher(hasHeader("Accept")))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""))
+
+	// newest first: the prioritized one accepts every request and outranks the others
+	infos := tr.Registrations()
+	for i, state := range []RegistrationState{RegistrationShadowed, RegistrationShadowed, RegistrationActive} {
+		if infos[i].State != state {
+			t.Errorf("registration %d: expected %s, got %s", i, state, infos[i].State)
+		}
+	}
+
+	tr = NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""), WithMatcher(hasHeader("Accept")))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""))
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""))
+
+	// the newest one without matchers outranks the older one, but not the one with a matcher
+	infos = tr.Registrations()
+	for i, state := range []RegistrationState{RegistrationActive, RegistrationShadowed, RegistrationActive} {
+		if infos[i].State != state {
+			t.Errorf("registration %d: expected %s, got %s", i, state, infos[i].State)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package httpmock
+
+import (
+	"io"
+	"net/http"
+)
+
+// NewPipeResponder creates a Responder whose response body is the read end of a pipe, and returns
+// the write end for the test to feed at its own pace.  Every Write blocks until the client has read
+// the bytes, which makes it suitable for backpressure tests, and closing the writer ends the body
+// with io.EOF (CloseWithError on the *io.PipeWriter ends it with another error).  If the client
+// closes the body early, later writes fail with io.ErrClosedPipe.
+//
+// There is a single pipe, so the responder is meant to answer a single request.
+func NewPipeResponder(status int) (Responder, io.WriteCloser) {
+	pr, pw := io.Pipe()
+	responder := func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			Status:        statusLine(status),
+			StatusCode:    status,
+			Body:          pr,
+			Header:        http.Header{},
+			ContentLength: -1,
+		}, nil
+	}
+	return responder, pw
+}
@@ -0,0 +1,56 @@
+package httpmock
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestPipeResponder(t *testing.T) {
+	tr := NewMockTransport()
+	responder, w := NewPipeResponder(200)
+	tr.RegisterResponder("GET", testUrl, responder)
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		for _, chunk := range []string{"hello", " ", "world"} {
+			if _, err := io.WriteString(w, chunk); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- w.Close()
+	}()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello world" {
+		t.Fatalf("expected the written bytes, got %q", data)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipeResponderClosedByClient(t *testing.T) {
+	responder, w := NewPipeResponder(200)
+	resp, err := responder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if _, err := w.Write([]byte("late")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("expected writes after the client closed the body to fail, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package httpmock
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewThrottledBody creates a body reading r at bytesPerSec, with token bucket semantics: up to
+// burst bytes can be read right away, after which reads are paced by the refill of the bucket, as
+// measured by the package Clock.  Reads wait for enough tokens to fill the buffer, up to burst, so
+// a burst of zero or less, which means 1, makes reads byte by byte.  A bytesPerSec of zero or less
+// leaves r alone.  Closing the body closes r if it is an io.Closer.
+func NewThrottledBody(r io.Reader, bytesPerSec, burst int) io.ReadCloser {
+	return newBucketBody(context.Background(), r, bytesPerSec, burst)
+}
+
+// NewThrottledResponder creates a Responder whose body is delivered at bytesPerSec with bursts of
+// up to burst bytes, as with NewThrottledBody, to test adaptive bitrate logic with settings such
+// as "256 KB/s with 64 KB burst".  Reads fail with the context's error if the request is canceled
+// while waiting for tokens.
+func NewThrottledResponder(status int, body []byte, bytesPerSec, burst int) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		ctx := context.Background()
+		if req != nil {
+			ctx = req.Context()
+		}
+		return &http.Response{
+			Status:        statusLine(status),
+			StatusCode:    status,
+			Body:          newBucketBody(ctx, bytes.NewReader(body), bytesPerSec, burst),
+			Header:        http.Header{},
+			ContentLength: int64(len(body)),
+		}, nil
+	}
+}
+
+func newBucketBody(ctx context.Context, r io.Reader, bytesPerSec, burst int) io.ReadCloser {
+	if bytesPerSec <= 0 {
+		if rc, ok := r.(io.ReadCloser); ok {
+			return rc
+		}
+		return ioutil.NopCloser(r)
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &bucketBody{
+		ctx:    ctx,
+		r:      r,
+		rate:   float64(bytesPerSec),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now(),
+	}
+}
+
+// bucketBody paces the reads of r with a token bucket refilled at rate bytes per second.
+type bucketBody struct {
+	ctx    context.Context
+	r      io.Reader
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last refill.
+func (b *bucketBody) refill() {
+	t := now()
+	b.tokens += t.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = t
+}
+
+func (b *bucketBody) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	want := float64(len(p))
+	if want > b.burst {
+		want = b.burst
+	}
+	b.refill()
+	for b.tokens < want {
+		wait := time.Duration((want - b.tokens) / b.rate * float64(time.Second))
+		if wait <= 0 {
+			wait = 1
+		}
+		select {
+		case <-getClock().After(wait):
+		case <-b.ctx.Done():
+			return 0, b.ctx.Err()
+		}
+		b.refill()
+	}
+
+	n, err := b.r.Read(p[:int(want)])
+	b.tokens -= float64(n)
+	return n, err
+}
+
+func (b *bucketBody) Close() error {
+	if closer, ok := b.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// NewBufferThenFlushResponder creates a Responder modelling a server computing the whole response
+// before sending it: headers come right away, then the first read of the body waits bufferDelay,
+// as measured by the package Clock, after which the whole body is available at once.  Unlike the
+// steady pacing of NewThrottledResponder, the latency is all before the first byte.  The first read
+// fails with the context's error if the request is canceled while waiting.
+func NewBufferThenFlushResponder(status int, body string, bufferDelay time.Duration) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		ctx := context.Background()
+		if req != nil {
+			ctx = req.Context()
+		}
+		return &http.Response{
+			Status:        statusLine(status),
+			StatusCode:    status,
+			Body:          &flushBody{ctx: ctx, r: strings.NewReader(body), delay: bufferDelay},
+			Header:        http.Header{},
+			ContentLength: int64(len(body)),
+		}, nil
+	}
+}
+
+// flushBody waits delay on its first read, then reads r without waiting.
+type flushBody struct {
+	ctx     context.Context
+	r       io.Reader
+	delay   time.Duration
+	flushed bool
+}
+
+func (b *flushBody) Read(p []byte) (int, error) {
+	if !b.flushed {
+		if b.delay > 0 {
+			select {
+			case <-getClock().After(b.delay):
+			case <-b.ctx.Done():
+				return 0, b.ctx.Err()
+			}
+		}
+		b.flushed = true
+	}
+	return b.r.Read(p)
+}
+
+func (b *flushBody) Close() error {
+	return nil
+}
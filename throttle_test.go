@@ -0,0 +1,182 @@
+package httpmock
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThrottledResponderRate(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewMockClock(start)
+	SetClock(clock)
+	defer SetClock(nil)
+
+	// the burst is free, the remaining 100 KB take a second at 100 KB/s
+	body := bytes.Repeat([]byte("x"), 110000)
+	resp, err := NewThrottledResponder(200, body, 100000, 10000)(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(resp.Body)
+		done <- data
+	}()
+
+	// move the clock to each deadline the reads wait for, until the body is read
+	var data []byte
+	for data == nil {
+		select {
+		case data = <-done:
+		default:
+			clock.mu.Lock()
+			if len(clock.waiters) > 0 {
+				clock.set(clock.waiters[0].deadline)
+			}
+			clock.mu.Unlock()
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if len(data) != len(body) {
+		t.Fatalf("expected %d bytes, got %d", len(body), len(data))
+	}
+	// the reads past the end of the body wait for their tokens too
+	if elapsed := clock.Now().Sub(start); elapsed < time.Second || elapsed > 1100*time.Millisecond {
+		t.Fatalf("expected about a second, took %s", elapsed)
+	}
+}
+
+func TestThrottledBodyBurst(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	body := NewThrottledBody(bytes.NewReader(bytes.Repeat([]byte("x"), 300)), 100, 100)
+
+	// the bucket starts full
+	buf := make([]byte, 1000)
+	if n, err := body.Read(buf); n != 100 || err != nil {
+		t.Fatalf("expected the burst right away, got %d %v", n, err)
+	}
+
+	done := make(chan int)
+	go func() {
+		n, _ := body.Read(buf)
+		done <- n
+	}()
+	waitForClockWaiters(t, clock, 1)
+	clock.Advance(time.Second)
+	if n := <-done; n != 100 {
+		t.Fatalf("expected a refilled bucket after a second, got %d", n)
+	}
+
+	// an idle second refills the bucket, but not beyond the burst
+	clock.Advance(5 * time.Second)
+	if n, _ := body.Read(buf); n != 100 {
+		t.Fatalf("expected the burst after being idle, got %d", n)
+	}
+}
+
+func TestThrottledResponderCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := NewThrottledResponder(200, []byte("abc"), 1, 1)(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := ioutil.ReadAll(resp.Body); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+// countingClock is the real clock, counting the timers it hands out.
+type countingClock struct {
+	realClock
+	timers int64
+}
+
+func (c *countingClock) After(d time.Duration) <-chan time.Time {
+	atomic.AddInt64(&c.timers, 1)
+	return c.realClock.After(d)
+}
+
+func BenchmarkThrottledBody(b *testing.B) {
+	clock := &countingClock{}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	// 1 MB at 256 MB/s with 64 KB bursts: one timer per 32 KB read past the burst, never a spin
+	data := bytes.Repeat([]byte("x"), 1<<20)
+	buf := make([]byte, 32<<10)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		body := NewThrottledBody(bytes.NewReader(data), 256<<20, 64<<10)
+		if _, err := io.CopyBuffer(ioutil.Discard, body, buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&clock.timers))/float64(b.N), "timers/op")
+}
+
+func TestBufferThenFlushResponder(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	resp, err := NewBufferThenFlushResponder(200, "the whole report", 3*time.Second)(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.ContentLength != 16 {
+		t.Fatalf("expected the headers right away, got %d with length %d", resp.StatusCode, resp.ContentLength)
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	buf := make([]byte, 100)
+	done := make(chan result)
+	go func() {
+		n, err := resp.Body.Read(buf)
+		done <- result{n, err}
+	}()
+	waitForClockWaiters(t, clock, 1)
+	clock.Advance(3 * time.Second)
+	if r := <-done; r.n != 16 || r.err != nil || string(buf[:r.n]) != "the whole report" {
+		t.Fatalf("expected the whole body at once, got %q %v", buf[:r.n], r.err)
+	}
+	if n, err := resp.Body.Read(buf); n != 0 || err != io.EOF {
+		t.Fatalf("expected EOF without waiting, got %d %v", n, err)
+	}
+}
+
+func TestBufferThenFlushResponderCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := NewBufferThenFlushResponder(200, "abc", time.Hour)(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := ioutil.ReadAll(resp.Body); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
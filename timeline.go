@@ -0,0 +1,315 @@
+package httpmock
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultTimelineExcerpt is the number of bytes of each body ExportTimeline shows by default.
+const DefaultTimelineExcerpt = 512
+
+// DefaultResponseCapture is the number of bytes of each response body a MockTransport keeps in the
+// call history by default, see SetResponseCapture.
+const DefaultResponseCapture = 64 << 10
+
+// SetResponseCapture sets how many bytes of each response body the MockTransport keeps in
+// Call.ResponseBody, as the client reads them, for ExportTimeline to show.  Whatever the limit, the
+// size and SHA-256 of the whole body are recorded, so that large or streamed bodies are summarized
+// without being kept in memory.  A limit of zero or less keeps no byte.  Reset leaves the setting in
+// place.
+func (m *MockTransport) SetResponseCapture(limit int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if limit <= 0 {
+		limit = -1
+	}
+	m.captureLimit = limit
+}
+
+// TimelineOption configures ExportTimeline and DumpOnFailure.
+type TimelineOption func(*timelineConfig)
+
+type timelineConfig struct {
+	excerpt int
+}
+
+// TimelineExcerpt sets the number of bytes of each text body shown in a timeline, beyond which it
+// is truncated.  The default is DefaultTimelineExcerpt.
+func TimelineExcerpt(n int) TimelineOption {
+	return func(c *timelineConfig) {
+		c.excerpt = n
+	}
+}
+
+// TimelineEntry is a call as exported by ExportTimeline in JSON.  Offset is the time elapsed
+// between the first call of the timeline and this one, Latency the time the responder took, and
+// Total the time until the client read the body up to io.EOF, in milliseconds, the latter two
+// being left out when unknown or zero.
+type TimelineEntry struct {
+	Index     int               `json:"index"`
+	Offset    float64           `json:"offset_ms"`
+	Latency   float64           `json:"latency_ms,omitempty"`
+	Total     float64           `json:"total_ms,omitempty"`
+	Key       string            `json:"key,omitempty"`
+	Unmatched bool              `json:"unmatched,omitempty"`
+	Default   bool              `json:"default,omitempty"`
+	Bypassed  bool              `json:"bypassed,omitempty"`
+	Canceled  bool              `json:"canceled,omitempty"`
+	Request   TimelineRequest   `json:"request"`
+	Response  *TimelineResponse `json:"response,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// TimelineRequest is the request of a TimelineEntry.
+type TimelineRequest struct {
+	Method string        `json:"method"`
+	URL    string        `json:"url"`
+	Header http.Header   `json:"header,omitempty"`
+	Body   *TimelineBody `json:"body,omitempty"`
+}
+
+// TimelineResponse is the response of a TimelineEntry.  Its body is what the client read of it.
+type TimelineResponse struct {
+	Status int           `json:"status"`
+	Header http.Header   `json:"header,omitempty"`
+	Body   *TimelineBody `json:"body,omitempty"`
+}
+
+// TimelineBody is an excerpt of a body in a TimelineEntry.  Text bodies are shown up to the excerpt
+// size, or for responses up to what the call history kept of them (see SetResponseCapture), binary
+// ones are summarized by their SHA-256.
+type TimelineBody struct {
+	Size      int    `json:"size"`
+	Text      string `json:"text,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	SHA256    string `json:"sha256,omitempty"`
+}
+
+// ExportTimeline writes every call in the history of the MockTransport to w, in order, for failure
+// triage: its timing, the registration that answered it, excerpts of the request and the response,
+// and the requests no responder matched highlighted.  format is "text", for people, or "json", an
+// array of TimelineEntry meant to be diffed between runs: object keys and headers are sorted, and
+// no absolute time is written, so with a MockClock (see SetClock) the output of a test is the same
+// from one run to the next.
+func (m *MockTransport) ExportTimeline(w io.Writer, format string, opts ...TimelineOption) error {
+	cfg := timelineConfig{excerpt: DefaultTimelineExcerpt}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	entries := m.timeline(cfg)
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "text":
+		for _, entry := range entries {
+			if _, err := io.WriteString(w, entry.text()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("httpmock: unknown timeline format %q, expected \"text\" or \"json\"", format)
+}
+
+// DumpOnFailure makes the MockTransport write its timeline to the file at path once t completes,
+// if it failed, see ExportTimeline.  The format is "json" if path ends with ".json", "text"
+// otherwise.
+func (m *MockTransport) DumpOnFailure(t testing.TB, path string, opts ...TimelineOption) {
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
+		}
+		format := "text"
+		if strings.HasSuffix(path, ".json") {
+			format = "json"
+		}
+
+		var buf bytes.Buffer
+		err := m.ExportTimeline(&buf, format, opts...)
+		if err == nil {
+			err = ioutil.WriteFile(path, buf.Bytes(), 0644)
+		}
+		if err != nil {
+			t.Errorf("httpmock: could not write the timeline: %s", err)
+			return
+		}
+		t.Logf("httpmock: timeline written to %s", path)
+	})
+}
+
+// timeline builds the entries of the calls in the history.
+func (m *MockTransport) timeline(cfg timelineConfig) []TimelineEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	unmatched := make(map[*Call]bool, len(m.unmatched))
+	for _, call := range m.unmatched {
+		unmatched[call] = true
+	}
+
+	entries := []TimelineEntry{}
+	for _, call := range m.history {
+		entry := TimelineEntry{
+			Index:     call.Index,
+			Key:       call.Key,
+			Unmatched: unmatched[call],
+			Default:   call.Default,
+			Bypassed:  call.Bypassed,
+			Canceled:  call.Canceled,
+			Request: TimelineRequest{
+				Method: call.Request.Method,
+				URL:    call.Request.URL.String(),
+				Header: call.Request.Header,
+				Body:   timelineBody(call.Body, int64(len(call.Body)), nil, cfg.excerpt),
+			},
+		}
+		entry.Offset = milliseconds(call.Time.Sub(m.history[0].Time))
+		if !call.Responded.IsZero() {
+			entry.Latency = milliseconds(call.Responded.Sub(call.Time))
+		}
+		if !call.BodyDone.IsZero() {
+			entry.Total = milliseconds(call.BodyDone.Sub(call.Time))
+		}
+		if call.Err != nil {
+			entry.Error = call.Err.Error()
+		} else if call.StatusCode != 0 {
+			entry.Response = &TimelineResponse{
+				Status: call.StatusCode,
+				Header: call.ResponseHeader,
+				Body:   timelineBody(call.ResponseBody, call.ResponseSize, call.responseSum, cfg.excerpt),
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// milliseconds converts d to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// timelineBody summarizes a body of size bytes, of which data holds the beginning, showing up to
+// excerpt bytes of it if it is text.  sum is the running SHA-256 of the whole body, or nil when
+// data holds all of it.
+func timelineBody(data []byte, size int64, sum hash.Hash, excerpt int) *TimelineBody {
+	if size == 0 {
+		return nil
+	}
+	b := &TimelineBody{Size: int(size)}
+	if int64(len(data)) < size {
+		data = trimPartialRune(data)
+	}
+	if !isText(data) || len(data) == 0 {
+		if sum == nil {
+			sum = sha256.New()
+			sum.Write(data)
+		}
+		b.SHA256 = hex.EncodeToString(sum.Sum(nil))
+		return b
+	}
+	if excerpt >= 0 && len(data) > excerpt {
+		data = data[:excerpt]
+	}
+	b.Text, b.Truncated = string(data), int64(len(data)) < size
+	return b
+}
+
+// trimPartialRune drops the UTF-8 sequence cut short at the end of data, if any.
+func trimPartialRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			break
+		}
+	}
+	return data
+}
+
+// text formats the entry for ExportTimeline.
+func (e TimelineEntry) text() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "=== #%d +%s %s %s", e.Index, formatMilliseconds(e.Offset), e.Request.Method, e.Request.URL)
+	switch {
+	case e.Unmatched:
+		b.WriteString(" [UNMATCHED]")
+	case e.Bypassed:
+		b.WriteString(" [bypassed]")
+	case e.Default:
+		fmt.Fprintf(&b, " [default responder %s]", e.Key)
+	case e.Key != "":
+		fmt.Fprintf(&b, " [%s]", e.Key)
+	}
+	b.WriteString("\n")
+
+	writeTimelineMessage(&b, ">", e.Request.Header, e.Request.Body)
+	if e.Error != "" {
+		fmt.Fprintf(&b, "error: %s\n", e.Error)
+	}
+	if e.Response != nil {
+		fmt.Fprintf(&b, "%s after %s", statusLine(e.Response.Status), formatMilliseconds(e.Latency))
+		if e.Total > 0 {
+			fmt.Fprintf(&b, ", body read after %s", formatMilliseconds(e.Total))
+		}
+		b.WriteString("\n")
+		writeTimelineMessage(&b, "<", e.Response.Header, e.Response.Body)
+	}
+	if e.Canceled {
+		b.WriteString("canceled\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// writeTimelineMessage writes the sorted headers and the body excerpt of a message, each line
+// prefixed by prefix.
+func writeTimelineMessage(b *bytes.Buffer, prefix string, header http.Header, body *TimelineBody) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range header[key] {
+			fmt.Fprintf(b, "%s %s: %s\n", prefix, key, value)
+		}
+	}
+
+	switch {
+	case body == nil:
+	case body.SHA256 != "":
+		fmt.Fprintf(b, "%s (%d bytes of binary data, sha256 %s)\n", prefix, body.Size, body.SHA256)
+	case body.Truncated:
+		fmt.Fprintf(b, "%s %q... (%d bytes)\n", prefix, body.Text, body.Size)
+	default:
+		fmt.Fprintf(b, "%s %q\n", prefix, body.Text)
+	}
+}
+
+// formatMilliseconds formats a number of milliseconds as a duration.
+func formatMilliseconds(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).String()
+}
+
+// DumpOnFailure makes DefaultTransport write its timeline to the file at path once t completes,
+// if it failed, see MockTransport.DumpOnFailure.
+func DumpOnFailure(t testing.TB, path string, opts ...TimelineOption) {
+	DefaultTransport.DumpOnFailure(t, path, opts...)
+}
@@ -0,0 +1,193 @@
+package httpmock
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timelineTransport makes a matched call with a text body, one with a binary body and an unmatched
+// one, a second apart on a MockClock.
+func timelineTransport(t *testing.T) *MockTransport {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	t.Cleanup(func() { SetClock(nil) })
+
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", testUrl+"orders", NewStringResponder(201, strings.Repeat("created ", 10)))
+	tr.RegisterResponder("GET", testUrl+"logo.png", NewBytesResponder(200, []byte{0x89, 'P', 'N', 'G', 0, 1}))
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Post(testUrl+"orders", "text/plain", strings.NewReader("one widget"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	clock.Advance(time.Second)
+
+	resp, err = client.Get(testUrl + "logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	clock.Advance(time.Second)
+
+	client.Get(testUrl + "missing")
+	return tr
+}
+
+func TestExportTimelineText(t *testing.T) {
+	tr := timelineTransport(t)
+
+	var buf bytes.Buffer
+	if err := tr.ExportTimeline(&buf, "text", TimelineExcerpt(16)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, expected := range []string{
+		"=== #0 +0s POST " + testUrl + "orders [POST " + testUrl + "orders]\n",
+		"> Content-Type: text/plain\n",
+		"> \"one widget\"\n",
+		"201 Created after 0s\n",
+		"< \"created created \"... (80 bytes)\n",
+		"=== #1 +1s GET " + testUrl + "logo.png",
+		"< (6 bytes of binary data, sha256 ",
+		"=== #2 +2s GET " + testUrl + "missing [UNMATCHED]\n",
+		"error: " + NoResponderFound.Error(),
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in the timeline:\n%s", expected, out)
+		}
+	}
+}
+
+func TestExportTimelineJSON(t *testing.T) {
+	var outputs []string
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		if err := timelineTransport(t).ExportTimeline(&buf, "json"); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, buf.String())
+	}
+	if outputs[0] != outputs[1] {
+		t.Fatalf("expected the same output from one run to the next:\n%s\n%s", outputs[0], outputs[1])
+	}
+
+	var entries []TimelineEntry
+	if err := json.Unmarshal([]byte(outputs[0]), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if e := entries[0]; e.Key != "POST "+testUrl+"orders" || e.Response == nil || e.Response.Status != 201 ||
+		e.Response.Body.Text != strings.Repeat("created ", 10) || e.Request.Body.Text != "one widget" {
+		t.Errorf("unexpected first entry %+v", e)
+	}
+	if e := entries[1]; e.Offset != 1000 || e.Response.Body.SHA256 == "" || e.Response.Body.Text != "" {
+		t.Errorf("expected the binary body summarized, got %+v", e.Response.Body)
+	}
+	if e := entries[2]; !e.Unmatched || e.Response != nil || e.Error == "" {
+		t.Errorf("expected the unmatched request highlighted, got %+v", e)
+	}
+
+	if err := NewMockTransport().ExportTimeline(&bytes.Buffer{}, "yaml"); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
+
+func TestTimelineLargeBody(t *testing.T) {
+	const size = 8 << 20
+	tr := NewMockTransport()
+	tr.SetResponseCapture(1024)
+	tr.RegisterResponder("GET", testUrl, NewSizedResponder("size", 0))
+	tr.RegisterResponder("GET", testUrl+"text", NewSizedResponder("size", 'x'))
+	client := &http.Client{Transport: tr}
+
+	for _, url := range []string{fmt.Sprintf("%s?size=%d", testUrl, size), testUrl + "text?size=2000"} {
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// only the beginning of the body is kept, its size and digest cover all of it
+	call := tr.History()[0]
+	if len(call.ResponseBody) != 1024 || call.ResponseSize != size {
+		t.Fatalf("expected 1024 bytes kept out of %d, got %d out of %d", size, len(call.ResponseBody), call.ResponseSize)
+	}
+
+	var buf bytes.Buffer
+	if err := tr.ExportTimeline(&buf, "json", TimelineExcerpt(16)); err != nil {
+		t.Fatal(err)
+	}
+	var entries []TimelineEntry
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(make([]byte, size))
+	if body := entries[0].Response.Body; body.Size != size || body.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected the binary body summarized as a whole, got %+v", body)
+	}
+	if body := entries[1].Response.Body; body.Size != 2000 || body.Text != strings.Repeat("x", 16) || !body.Truncated {
+		t.Errorf("expected an excerpt of the text body, got %+v", body)
+	}
+
+	// a capture ending in the middle of a character is still text
+	if body := timelineBody([]byte("caf\xc3"), 5, nil, 16); body.Text != "caf" || !body.Truncated {
+		t.Errorf("expected the partial character dropped, got %+v", body)
+	}
+}
+
+// cleanupTB runs its cleanups on demand, with a settable failure state.
+type cleanupTB struct {
+	testing.TB
+	failed   bool
+	cleanups []func()
+}
+
+func (c *cleanupTB) Cleanup(f func())                        { c.cleanups = append(c.cleanups, f) }
+func (c *cleanupTB) Failed() bool                            { return c.failed }
+func (c *cleanupTB) Logf(format string, args ...interface{}) {}
+
+func (c *cleanupTB) finish() {
+	for _, f := range c.cleanups {
+		f()
+	}
+}
+
+func TestDumpOnFailure(t *testing.T) {
+	tr := timelineTransport(t)
+	dir := t.TempDir()
+
+	passed := &cleanupTB{TB: t}
+	tr.DumpOnFailure(passed, filepath.Join(dir, "passed.txt"))
+	passed.finish()
+	if _, err := ioutil.ReadFile(filepath.Join(dir, "passed.txt")); err == nil {
+		t.Error("expected no timeline written for a passing test")
+	}
+
+	failed := &cleanupTB{TB: t, failed: true}
+	tr.DumpOnFailure(failed, filepath.Join(dir, "failed.json"))
+	failed.finish()
+	data, err := ioutil.ReadFile(filepath.Join(dir, "failed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []TimelineEntry
+	if err := json.Unmarshal(data, &entries); err != nil || len(entries) != 3 {
+		t.Errorf("expected a JSON timeline of 3 entries, got %d entries, %v", len(entries), err)
+	}
+}
@@ -0,0 +1,201 @@
+package httpmock
+
+import (
+	"crypto/sha256"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Latency returns how long the call waited for its response, from Time to Responded, or zero if
+// no response came back.
+func (c *Call) Latency() time.Duration {
+	if c.Responded.IsZero() {
+		return 0
+	}
+	return c.Responded.Sub(c.Time)
+}
+
+// TotalLatency returns how long the call took until its response body was read up to io.EOF, from
+// Time to BodyDone, or zero if it wasn't.
+func (c *Call) TotalLatency() time.Duration {
+	if c.BodyDone.IsZero() {
+		return 0
+	}
+	return c.BodyDone.Sub(c.Time)
+}
+
+// LatencyStats summarizes the latencies of the calls answered for a key, see
+// MockTransport.LatencyStats.
+type LatencyStats struct {
+	// Count is the number of calls that got a response.
+	Count int
+	// P50 and P95 are the median and 95th percentile of their Latency.
+	P50 time.Duration
+	P95 time.Duration
+}
+
+// LatencyStats returns the latency statistics of the calls each registration answered since the
+// MockTransport was created or last reset, keyed like CallCounts.  Calls canceled before their
+// response came back aren't counted.  Percentiles are nearest rank: P95 is the latency 95% of the
+// calls took at most.
+func (m *MockTransport) LatencyStats() map[string]LatencyStats {
+	m.mu.Lock()
+	latencies := make(map[string][]time.Duration)
+	for _, call := range m.history {
+		if call.Key != "" && !call.Responded.IsZero() && !call.Canceled {
+			latencies[call.Key] = append(latencies[call.Key], call.Latency())
+		}
+	}
+	m.mu.Unlock()
+
+	stats := make(map[string]LatencyStats, len(latencies))
+	for key, durations := range latencies {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		stats[key] = LatencyStats{
+			Count: len(durations),
+			P50:   percentile(durations, 0.50),
+			P95:   percentile(durations, 0.95),
+		}
+	}
+	return stats
+}
+
+// percentile returns the nearest rank p percentile of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// recordResponded records in call that the responder came back with resp and err.
+func (m *MockTransport) recordResponded(req *http.Request, call *Call, resp *http.Response, err error) {
+	t := now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	call.Responded = t
+	call.Err = err
+	if err != nil && requestCanceled(req) {
+		call.Canceled = true
+	}
+	if err == nil && resp != nil {
+		call.StatusCode = resp.StatusCode
+		call.ResponseHeader = resp.Header.Clone()
+	}
+}
+
+// recordErr records in call the error err it failed with, when no responder ran.
+func (m *MockTransport) recordErr(call *Call, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	call.Err = err
+}
+
+// timeBody makes the body of resp record in call what the client reads of it, and when it's read
+// up to io.EOF, or fails because req is canceled.
+func (m *MockTransport) timeBody(req *http.Request, resp *http.Response, call *Call) {
+	if resp.Body == nil || resp.Body == http.NoBody || resp.StatusCode == http.StatusSwitchingProtocols {
+		return
+	}
+
+	m.mu.Lock()
+	limit := m.captureLimit
+	if limit == 0 {
+		limit = DefaultResponseCapture
+	}
+	call.responseSum = sha256.New()
+	m.mu.Unlock()
+	body := &timedBody{ReadCloser: resp.Body, m: m, req: req, call: call, limit: limit}
+	if _, ok := resp.Body.(io.WriterTo); ok {
+		resp.Body = timedWriterToBody{body}
+		return
+	}
+	resp.Body = body
+}
+
+type timedBody struct {
+	io.ReadCloser
+	m     *MockTransport
+	req   *http.Request
+	call  *Call
+	limit int
+	done  bool
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.record(p[:n])
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+// record records in the call that the client read p.
+func (b *timedBody) record(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	b.m.mu.Lock()
+	defer b.m.mu.Unlock()
+	if keep := b.limit - len(b.call.ResponseBody); keep > 0 {
+		if keep > len(p) {
+			keep = len(p)
+		}
+		b.call.ResponseBody = append(b.call.ResponseBody, p[:keep]...)
+	}
+	b.call.ResponseSize += int64(len(p))
+	b.call.responseSum.Write(p)
+}
+
+// finish records in the call that reading the body ended with err, io.EOF if it was read in full.
+func (b *timedBody) finish(err error) {
+	if b.done {
+		return
+	}
+	b.done = true
+	t := now()
+	b.m.mu.Lock()
+	defer b.m.mu.Unlock()
+	if err == io.EOF {
+		b.call.BodyDone = t
+	} else if requestCanceled(b.req) {
+		b.call.Canceled = true
+	}
+}
+
+func (b *timedBody) unwrapBody() io.ReadCloser {
+	return b.ReadCloser
+}
+
+// timedWriterToBody is a timedBody keeping the io.WriterTo of the body it wraps, for io.Copy to
+// still take the fast path.
+type timedWriterToBody struct {
+	*timedBody
+}
+
+func (b timedWriterToBody) WriteTo(w io.Writer) (int64, error) {
+	n, err := b.ReadCloser.(io.WriterTo).WriteTo(timedWriter{w: w, body: b.timedBody})
+	if err == nil {
+		b.finish(io.EOF)
+	} else {
+		b.finish(err)
+	}
+	return n, err
+}
+
+// timedWriter records in the call of body what is written to w, as read by the client.
+type timedWriter struct {
+	w    io.Writer
+	body *timedBody
+}
+
+func (t timedWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	t.body.record(p[:n])
+	return n, err
+}
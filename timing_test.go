@@ -0,0 +1,172 @@
+package httpmock
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCallTimings(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, func(req *http.Request) (*http.Response, error) {
+		clock.Advance(2 * time.Second)
+		return NewStringResponse(200, "hello"), nil
+	})
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(testUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	call := tr.LastCall()
+	if call.Latency() != 2*time.Second || call.TotalLatency() != 3*time.Second || call.Canceled {
+		t.Errorf("expected a 2s latency, 3s in total, not canceled, got %s, %s, %v", call.Latency(), call.TotalLatency(), call.Canceled)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err = client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := ioutil.ReadAll(resp.Body); err == nil {
+		t.Fatal("expected the canceled body to fail")
+	}
+	resp.Body.Close()
+	if call := tr.LastCall(); !call.Canceled || call.TotalLatency() != 0 {
+		t.Errorf("expected the call to be canceled before its body was read, got %+v", call)
+	}
+}
+
+func TestCallTimingsWriterTo(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Header: http.Header{}, Body: NewWriterToBody(data)}, nil
+	})
+
+	// a cancelable request gets its body wrapped twice, for timing and for cancellation
+	get := func() (*http.Response, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := resp.Body.(io.WriterTo); !ok {
+			t.Fatal("expected the body to still implement io.WriterTo")
+		}
+		return resp, cancel
+	}
+
+	resp, cancel := get()
+	defer cancel()
+	clock.Advance(time.Second)
+	var copied bytes.Buffer
+	rec := &writeRecorder{w: &copied}
+	if _, err := io.Copy(rec, resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rec.writes, []int{len(data)}) || !bytes.Equal(copied.Bytes(), data) {
+		t.Fatalf("expected io.Copy to go through WriteTo in a single write, got writes of %v", rec.writes)
+	}
+	if call := tr.LastCall(); call.TotalLatency() != time.Second || call.ResponseSize != int64(len(data)) {
+		t.Errorf("expected the copied body timed and recorded, got %s and %d bytes", call.TotalLatency(), call.ResponseSize)
+	}
+
+	resp, cancel = get()
+	cancel()
+	if _, err := io.Copy(ioutil.Discard, resp.Body); err != context.Canceled {
+		t.Fatalf("expected the copy of a canceled body to fail, got %v", err)
+	}
+	if call := tr.LastCall(); !call.Canceled || call.TotalLatency() != 0 {
+		t.Errorf("expected the call to be canceled before its body was read, got %+v", call)
+	}
+}
+
+func TestCallTimingsCanceledWhileWaiting(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewHangingResponder())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", testUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.RoundTrip(req); err == nil {
+		t.Fatal("expected the request to time out")
+	}
+	if call := tr.LastCall(); !call.Canceled {
+		t.Error("expected the call to be canceled")
+	}
+	if stats := tr.LatencyStats(); len(stats) != 0 {
+		t.Errorf("expected canceled calls not to be counted, got %v", stats)
+	}
+}
+
+func TestLatencyStats(t *testing.T) {
+	clock := NewMockClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(clock)
+	defer SetClock(nil)
+
+	// the nth call takes n seconds
+	tr := NewMockTransport()
+	calls := 0
+	tr.RegisterResponder("GET", testUrl, func(req *http.Request) (*http.Response, error) {
+		calls++
+		clock.Advance(time.Duration(calls) * time.Second)
+		return NewStringResponse(200, ""), nil
+	})
+	tr.RegisterResponder("POST", testUrl, NewStringResponder(201, ""))
+	client := &http.Client{Transport: tr}
+
+	for i := 0; i < 20; i++ {
+		resp, err := client.Get(testUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	resp, err := client.Post(testUrl, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	expected := map[string]LatencyStats{
+		"GET " + testUrl:  {Count: 20, P50: 10 * time.Second, P95: 19 * time.Second},
+		"POST " + testUrl: {Count: 1},
+	}
+	stats := tr.LatencyStats()
+	if len(stats) != len(expected) {
+		t.Fatalf("expected stats for %d keys, got %v", len(expected), stats)
+	}
+	for key, s := range expected {
+		if stats[key] != s {
+			t.Errorf("%s: expected %+v, got %+v", key, s, stats[key])
+		}
+	}
+}
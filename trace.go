@@ -0,0 +1,130 @@
+package httpmock
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// traceRequest synthesizes, for the httptrace.ClientTrace attached to the context of req if any,
+// the events a real transport emits up to the request being written: obtaining a connection, new
+// or reused, then writing the headers and the request.  It returns the trace so that traceResponse
+// can finish the sequence.
+func traceRequest(req *http.Request, reused bool) *httptrace.ClientTrace {
+	trace := httptrace.ContextClientTrace(req.Context())
+	if trace == nil {
+		return nil
+	}
+
+	addr := requestAddr(req)
+	if trace.GetConn != nil {
+		trace.GetConn(addr.String())
+	}
+	if !reused {
+		traceDial(trace, req, addr)
+	}
+	if trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{
+			Conn:    traceConn{remote: addr},
+			Reused:  reused,
+			WasIdle: reused,
+		})
+	}
+
+	if trace.WroteHeaderField != nil {
+		for key, values := range req.Header {
+			trace.WroteHeaderField(key, values)
+		}
+	}
+	if trace.WroteHeaders != nil {
+		trace.WroteHeaders()
+	}
+	if trace.Wait100Continue != nil && expectsContinue(req) {
+		trace.Wait100Continue()
+	}
+	if trace.WroteRequest != nil {
+		trace.WroteRequest(httptrace.WroteRequestInfo{})
+	}
+	return trace
+}
+
+// traceDial synthesizes the events of a new connection to addr: resolving the host unless it is an
+// IP address, connecting, and the TLS handshake for https.
+func traceDial(trace *httptrace.ClientTrace, req *http.Request, addr net.Addr) {
+	host := req.URL.Hostname()
+	if net.ParseIP(host) == nil {
+		if trace.DNSStart != nil {
+			trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+		}
+		if trace.DNSDone != nil {
+			trace.DNSDone(httptrace.DNSDoneInfo{Addrs: []net.IPAddr{{IP: net.IPv4(127, 0, 0, 1)}}})
+		}
+	}
+	if trace.ConnectStart != nil {
+		trace.ConnectStart("tcp", addr.String())
+	}
+	if trace.ConnectDone != nil {
+		trace.ConnectDone("tcp", addr.String(), nil)
+	}
+	if req.URL.Scheme == "https" {
+		if trace.TLSHandshakeStart != nil {
+			trace.TLSHandshakeStart()
+		}
+		if trace.TLSHandshakeDone != nil {
+			trace.TLSHandshakeDone(tls.ConnectionState{HandshakeComplete: true, ServerName: host}, nil)
+		}
+	}
+}
+
+// traceResponse delivers GotFirstResponseByte for resp.  In-memory bodies are complete when the
+// responder returns, so it fires immediately; for streaming bodies it fires on the first read.  The
+// body of a 101 is a connection the client writes to as well, so it is left alone and the event
+// fires immediately too.
+func traceResponse(trace *httptrace.ClientTrace, resp *http.Response) {
+	if trace == nil || trace.GotFirstResponseByte == nil {
+		return
+	}
+	if _, ok := responderBody(resp.Body).(*dummyReadCloser); ok || resp.Body == nil || resp.Body == http.NoBody ||
+		resp.StatusCode == http.StatusSwitchingProtocols {
+		trace.GotFirstResponseByte()
+		return
+	}
+	resp.Body = &firstByteBody{ReadCloser: resp.Body, fire: trace.GotFirstResponseByte}
+}
+
+// firstByteBody calls fire when the first byte of the body is read.
+type firstByteBody struct {
+	io.ReadCloser
+	once sync.Once
+	fire func()
+}
+
+func (b *firstByteBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.once.Do(b.fire)
+	}
+	return n, err
+}
+
+// traceConn is the connection reported to httptrace callbacks.  No bytes go through it.
+type traceConn struct {
+	remote net.Addr
+}
+
+func (traceConn) Read([]byte) (int, error)         { return 0, io.EOF }
+func (traceConn) Write(p []byte) (int, error)      { return len(p), nil }
+func (traceConn) Close() error                     { return nil }
+func (traceConn) LocalAddr() net.Addr              { return tcpAddr("127.0.0.1:0") }
+func (c traceConn) RemoteAddr() net.Addr           { return c.remote }
+func (traceConn) SetDeadline(time.Time) error      { return nil }
+func (traceConn) SetReadDeadline(time.Time) error  { return nil }
+func (traceConn) SetWriteDeadline(time.Time) error { return nil }
+
+func (b *firstByteBody) unwrapBody() io.ReadCloser {
+	return b.ReadCloser
+}
@@ -0,0 +1,106 @@
+package httpmock
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// traceRecorder records the httptrace events it receives, with when they happened.
+type traceRecorder struct {
+	mu     sync.Mutex
+	events []string
+	times  map[string]time.Time
+	reused []bool
+}
+
+func (r *traceRecorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	if r.times == nil {
+		r.times = make(map[string]time.Time)
+	}
+	r.times[event] = time.Now()
+}
+
+func (r *traceRecorder) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn:      func(string) { r.add("GetConn") },
+		DNSStart:     func(httptrace.DNSStartInfo) { r.add("DNSStart") },
+		DNSDone:      func(httptrace.DNSDoneInfo) { r.add("DNSDone") },
+		ConnectStart: func(string, string) { r.add("ConnectStart") },
+		ConnectDone:  func(string, string, error) { r.add("ConnectDone") },
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.add("GotConn")
+			r.reused = append(r.reused, info.Reused)
+		},
+		WroteHeaders:         func() { r.add("WroteHeaders") },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { r.add("WroteRequest") },
+		GotFirstResponseByte: func() { r.add("GotFirstResponseByte") },
+	}
+}
+
+func TestMockTransportTrace(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewResponder(200, "slow", WithFixedDelay(30*time.Millisecond)))
+	client := &http.Client{Transport: tr}
+
+	rec := &traceRecorder{}
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", testUrl, nil)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), rec.trace()))
+		if _, err := client.Do(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{
+		"GetConn", "DNSStart", "DNSDone", "ConnectStart", "ConnectDone", "GotConn",
+		"WroteHeaders", "WroteRequest", "GotFirstResponseByte",
+		"GetConn", "GotConn", "WroteHeaders", "WroteRequest", "GotFirstResponseByte",
+	}
+	if !reflect.DeepEqual(rec.events, expected) {
+		t.Fatalf("expected events %v, got %v", expected, rec.events)
+	}
+	if !reflect.DeepEqual(rec.reused, []bool{false, true}) {
+		t.Errorf("expected the second request to reuse the connection, got %v", rec.reused)
+	}
+	if wait := rec.times["GotFirstResponseByte"].Sub(rec.times["WroteRequest"]); wait < 30*time.Millisecond {
+		t.Errorf("expected the delay to show between WroteRequest and GotFirstResponseByte, got %s", wait)
+	}
+}
+
+func TestMockTransportTraceStreaming(t *testing.T) {
+	tr := NewMockTransport()
+	responder, w := NewPipeResponder(200)
+	tr.RegisterResponder("GET", testUrl, responder)
+	client := &http.Client{Transport: tr}
+
+	rec := &traceRecorder{}
+	req, _ := http.NewRequest("GET", testUrl, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), rec.trace()))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := rec.events[len(rec.events)-1]; last != "WroteRequest" {
+		t.Fatalf("expected no first byte before the body is read, got %v", rec.events)
+	}
+
+	go func() {
+		io.WriteString(w, "chunk")
+		w.Close()
+	}()
+	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	if last := rec.events[len(rec.events)-1]; last != "GotFirstResponseByte" {
+		t.Fatalf("expected the first byte once the body is read, got %v", rec.events)
+	}
+}
@@ -0,0 +1,119 @@
+package httpmock
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+)
+
+// TransformOption configures WrapBodyTransform.
+type TransformOption func(*transformConfig)
+
+type transformConfig struct {
+	preserveLength bool
+}
+
+// PreserveContentLength makes WrapBodyTransform declare the length of the original body rather
+// than the one of the transformed body, so that the client gets a body shorter or longer than
+// announced.
+func PreserveContentLength() TransformOption {
+	return func(c *transformConfig) {
+		c.preserveLength = true
+	}
+}
+
+// WrapBodyTransform creates a Responder passing the bodies of the responses of inner through
+// transform, such as CorruptBytes, TruncateAt or AppendJunk, to test how a client copes with
+// damaged content, for instance that it verifies checksums.  transform is given a copy of the
+// whole body.  The ContentLength of the responses, and their Content-Length header if set, are
+// updated to the length of the transformed body, or to the length of the original one with
+// PreserveContentLength; a length that was unknown (-1) stays so.
+func WrapBodyTransform(inner Responder, transform func([]byte) []byte, opts ...TransformOption) Responder {
+	var cfg transformConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := inner(req)
+		if err != nil || resp == nil {
+			return resp, err
+		}
+
+		var data []byte
+		if resp.Body != nil {
+			data, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		out := transform(append([]byte(nil), data...))
+
+		transformed := *resp
+		transformed.Header = resp.Header.Clone()
+		if transformed.Header == nil {
+			transformed.Header = http.Header{}
+		}
+		transformed.Body = NewRespBodyFromBytes(out)
+		if resp.ContentLength >= 0 {
+			length := len(out)
+			if cfg.preserveLength {
+				length = len(data)
+			}
+			transformed.ContentLength = int64(length)
+			if transformed.Header.Get("Content-Length") != "" {
+				transformed.Header.Set("Content-Length", strconv.Itoa(length))
+			}
+		}
+		return &transformed, nil
+	}
+}
+
+// CorruptBytes is a transform for WrapBodyTransform flipping the bits of count bytes of the body,
+// picked at random from seed so that every call damages the body the same way.  Every byte is
+// corrupted if the body has count bytes or less, and none if count is negative.
+func CorruptBytes(seed int64, count int) func([]byte) []byte {
+	if count < 0 {
+		count = 0
+	}
+	return func(data []byte) []byte {
+		r := rand.New(rand.NewSource(seed))
+		if count >= len(data) {
+			for i := range data {
+				data[i] ^= 0xff
+			}
+			return data
+		}
+		for _, i := range r.Perm(len(data))[:count] {
+			data[i] ^= 0xff
+		}
+		return data
+	}
+}
+
+// TruncateAt is a transform for WrapBodyTransform keeping the first fraction of the body, from 0
+// to 1.
+func TruncateAt(fraction float64) func([]byte) []byte {
+	return func(data []byte) []byte {
+		switch {
+		case !(fraction > 0):
+			return data[:0]
+		case fraction >= 1:
+			return data
+		}
+		return data[:int(fraction*float64(len(data)))]
+	}
+}
+
+// AppendJunk is a transform for WrapBodyTransform appending n random bytes to the body, drawn from
+// the package source of randomness (see SetRandSource).
+func AppendJunk(n int) func([]byte) []byte {
+	return func(data []byte) []byte {
+		for i := 0; i < n; i++ {
+			data = append(data, byte(randInt63n(nil, 256)))
+		}
+		return data
+	}
+}
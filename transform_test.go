@@ -0,0 +1,92 @@
+package httpmock
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestWrapBodyTransform(t *testing.T) {
+	content := bytes.Repeat([]byte("payload "), 64)
+	sum := sha256.Sum256(content)
+	original := func(req *http.Request) (*http.Response, error) {
+		resp := NewBytesResponse(200, content)
+		resp.Header.Set("X-Checksum", hex.EncodeToString(sum[:]))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(content)))
+		resp.ContentLength = int64(len(content))
+		return resp, nil
+	}
+
+	// download is a client verifying the checksum of what it gets
+	download := func(responder Responder) (int64, error) {
+		tr := NewMockTransport()
+		tr.RegisterResponder("GET", testUrl, responder)
+		resp, err := (&http.Client{Transport: tr}).Get(testUrl)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return 0, err
+		}
+		got := sha256.Sum256(data)
+		if hex.EncodeToString(got[:]) != resp.Header.Get("X-Checksum") {
+			return resp.ContentLength, fmt.Errorf("checksum mismatch")
+		}
+		return resp.ContentLength, nil
+	}
+
+	if _, err := download(original); err != nil {
+		t.Fatalf("expected the intact body to check out, got %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		transform func([]byte) []byte
+		opts      []TransformOption
+		length    int64
+	}{
+		{"corrupted", CorruptBytes(42, 3), nil, 512},
+		{"truncated", TruncateAt(0.5), nil, 256},
+		{"truncated preserving length", TruncateAt(0.5), []TransformOption{PreserveContentLength()}, 512},
+		{"junk", AppendJunk(10), nil, 522},
+	}
+	for _, test := range tests {
+		length, err := download(WrapBodyTransform(original, test.transform, test.opts...))
+		if err == nil {
+			t.Errorf("%s: expected the client to detect the damage", test.name)
+		}
+		if length != test.length {
+			t.Errorf("%s: expected a declared length of %d, got %d", test.name, test.length, length)
+		}
+	}
+}
+
+func TestCorruptBytes(t *testing.T) {
+	data := []byte("0123456789")
+	first := CorruptBytes(7, 3)(append([]byte(nil), data...))
+	second := CorruptBytes(7, 3)(append([]byte(nil), data...))
+	if !bytes.Equal(first, second) {
+		t.Error("expected the same seed to corrupt the same bytes")
+	}
+
+	diffs := 0
+	for i := range data {
+		if first[i] != data[i] {
+			diffs++
+		}
+	}
+	if diffs != 3 {
+		t.Errorf("expected 3 corrupted bytes, got %d", diffs)
+	}
+
+	if out := CorruptBytes(7, -1)(append([]byte(nil), data...)); !bytes.Equal(out, data) {
+		t.Errorf("expected a negative count to corrupt nothing, got %q", out)
+	}
+}
@@ -2,8 +2,15 @@ package httpmock
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"testing"
+	"time"
 )
 
 // Responders are callbacks that receive and http request and return a mocked response.
@@ -20,75 +27,433 @@ func ConnectionFailure(*http.Request) (*http.Response, error) {
 
 // NewMockTransport creates a new *MockTransport with no responders.
 func NewMockTransport() *MockTransport {
-	return &MockTransport{make(map[string]Responder), nil}
+	return &MockTransport{responders: make(map[string][]*registration)}
 }
 
 // MockTransport implements http.RoundTripper, which fulfills single http requests issued by
 // an http.Client.  This implementation doesn't actually make the call, instead deferring to
 // the registered list of responders.
 type MockTransport struct {
-	responders  map[string]Responder
+	mu          sync.Mutex
+	responders  map[string][]*registration
+	equivalents map[string][]string
+	registered  uint64
 	noResponder Responder
+	history     []*Call
+
+	unmatched     []*Call
+	unmatchedDump io.Writer
+	unmatchedSeq  int
+	dumpMu        sync.Mutex
+
+	logger     Logger
+	logVerbose bool
+	metrics    Metrics
+
+	hostFailures map[string]Responder
+	closingHosts map[string]bool
+	connected    map[string]bool
+	connStats    ConnectionStats
+
+	closeIdleCalls     int
+	onCloseIdle        func()
+	cancelRequestCalls int
+
+	proxy           func(*http.Request) (*url.URL, error)
+	proxyChallenges map[string]string
+
+	passThrough []string
+
+	profiles  map[string]Profile
+	chaos     *ChaosConfig
+	chaosRand *lockedRand
+
+	noDrain    bool
+	drainLimit int64
+
+	captureLimit int
+
+	bodyTracker *bodyTracker
+
+	defaults map[string]Responder
+
+	contentLengthReport func(error)
+
+	callsChanged chan struct{}
+	resets       int
 }
 
 // RoundTrip receives HTTP requests and routes them to the appropriate responder.  It is required to
 // implement the http.RoundTripper interface.  You will not interact with this directly, instead
-// the *http.Client you are using will call it for you.
+// the *http.Client you are using will call it for you.  As the interface requires, req is left
+// alone: responders and matchers get a copy of it, whose body can be read again, see
+// ReplayableRequestBody.
 func (m *MockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := m.roundTrip(req)
+	if resp != nil {
+		// the response points back at the request of the caller, not at the copy
+		resp.Request = req
+	}
+	return resp, err
+}
+
+// roundTrip routes a copy of req to the appropriate responder.
+func (m *MockTransport) roundTrip(req *http.Request) (resp *http.Response, err error) {
+	// every outcome is logged and measured, the requests that never reach a responder included
+	start := now()
+	var key string
+	var body []byte
+	defer func() { m.observe(req, key, len(body), resp, err, now().Sub(start)) }()
+
+	prepared, err := prepareRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	req = prepared
 	url := req.URL.String()
 
-	// try and get a responder that matches the method and URL
-	responder := m.responderForKey(req.Method + " " + url)
+	// the body of the client is drained and closed once the request is over, see SetRequestBodyDrain
+	var call *Call
+	clientBody := trackRequestBody(req)
+	defer func() { m.finishRequestBody(clientBody, call) }()
 
-	// if we weren't able to find a responder and the URL contains a querystring
-	// then we strip off the querystring and try again.
-	if responder == nil && strings.Contains(url, "?") {
-		responder = m.responderForKey(req.Method + " " + strings.Split(url, "?")[0])
+	// buffer the body so the call history keeps a copy the responder can't consume
+	declared := req.ContentLength
+	body, err = ReplayableRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.bypasses(req) {
+		call = m.record(req, "", body)
+		return m.bypass(req, call)
+	}
+
+	if req, err = m.routeThroughProxy(req); err != nil {
+		return nil, err
+	}
+
+	failure := m.proxyFailure(req)
+	if failure == nil {
+		failure = m.hostFailure(req)
+	}
+	if failure != nil {
+		// requests to a failing proxy or host never reach the registrations
+		call = m.record(req, "", body)
+		resp, err = respond(failure, req)
+		m.recordErr(call, err)
+		resp = ownResponse(req, resp, err)
+		if resp != nil {
+			m.trackResponseBody(req, resp)
+		}
+		return resp, err
+	}
+
+	// try and get the most specific registration for the method and URL, see RegisterResponder
+	responder, key, misses := m.selectResponder(req, url, body)
+	if declared > 0 {
+		// buffering set ContentLength to the length of the body; the responder gets the length the
+		// client declared instead, so it can tell a short body from a complete one
+		req.ContentLength = declared
 	}
 
-	// if we found a responder, call it
 	if responder != nil {
-		return runCancelable(responder, req)
+		// if we found a responder, call it
+		call = m.record(req, key, body)
+		resp, err = m.serve(responder, req, call)
+	} else if responder, key = m.defaultResponder(req); responder != nil {
+		// no registration matched, but the host has a default responder
+		call = m.record(req, key, body)
+		m.mu.Lock()
+		call.Default = true
+		m.mu.Unlock()
+		resp, err = m.serve(responder, req, call)
+	} else {
+		// we didn't find a responder, so fire the 'no responder' responder
+		key = ""
+		call = m.record(req, key, body)
+		m.recordUnmatched(call)
+
+		m.mu.Lock()
+		noResponder := m.noResponder
+		m.mu.Unlock()
+		if noResponder == nil {
+			resp, err = ConnectionFailure(req)
+			if len(misses) > 0 {
+				// explain why the registrations for this URL didn't match
+				err = fmt.Errorf("%w: %s", err, strings.Join(misses, "; "))
+			}
+			m.recordErr(call, err)
+		} else {
+			resp, err = m.serve(noResponder, req, call)
+		}
 	}
 
-	// we didn't find a responder, so fire the 'no responder' responder
-	if m.noResponder == nil {
-		return ConnectionFailure(req)
+	return resp, err
+}
+
+// serve runs responder for req over a simulated connection, opened or reused for the occasion and
+// closed again when the exchange calls for it, under the host profile of req if any.
+func (m *MockTransport) serve(responder Responder, req *http.Request, call *Call) (*http.Response, error) {
+	req = m.chaosRequest(req)
+	responder = m.applyProfile(responder, req, call)
+	responder = m.applyChaos(responder, req, call)
+	trace := traceRequest(req, m.connect(req))
+	resp, err := respond(responder, req)
+	m.recordResponded(req, call, resp, err)
+	resp = ownResponse(req, resp, err)
+	if resp != nil {
+		cancelOnDone(req, resp)
+		m.timeBody(req, resp, call)
+		m.closeIfRequested(req, resp)
+		traceResponse(trace, resp)
+		m.checkContentLength(req, resp, call.Key)
+		m.trackResponseBody(req, resp)
 	}
-	return runCancelable(m.noResponder, req)
+	m.release(req, resp, err)
+	return resp, err
 }
 
+// prepareRequest checks that req can be handled, instead of letting a malformed request panic deep
+// inside matching, and returns a copy of it the transport can modify, such as to make its body
+// replayable.  As net/http does, an empty method means GET and a nil Header an empty one, and
+// http.NoBody is the same as no body.
+func prepareRequest(req *http.Request) (*http.Request, error) {
+	if req == nil {
+		return nil, errors.New("httpmock: nil request")
+	}
+	if req.URL == nil {
+		return nil, errors.New("httpmock: request has nil URL")
+	}
+
+	clone := req.Clone(req.Context())
+	// the body of the caller may only fill in its trailer as it is read
+	clone.Trailer = req.Trailer
+	req = clone
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	if req.Body == http.NoBody {
+		req.Body = nil
+	}
+	return req, nil
+}
 
-// do nothing with timeout
-func (m *MockTransport) CancelRequest(req *http.Request) {}
+// respond runs responder for req, turning a nil response without error, which would crash the
+// http.Client far from the cause, into an error.
+func respond(responder Responder, req *http.Request) (*http.Response, error) {
+	resp, err := runCancelable(responder, req)
+	if resp == nil && err == nil {
+		err = fmt.Errorf("httpmock: responder for %s %s returned no response and no error", req.Method, req.URL)
+	}
+	return resp, err
+}
 
-// responderForKey returns a responder for a given key
-func (m *MockTransport) responderForKey(key string) Responder {
-	for k, r := range m.responders {
-		if k != key {
-			continue
+// ownResponse returns the response a call gets out of resp.  Responders often hand out the same
+// response to every call, so each call gets its own copy pointing back at its request like a real
+// transport would, with its own Trailer.
+func ownResponse(req *http.Request, resp *http.Response, err error) *http.Response {
+	if err != nil || resp == nil {
+		return resp
+	}
+	r := *resp
+	r.Request = req
+	if _, ok := resp.Body.(*trailerBody); ok {
+		// a trailerBody is made for the call and fills in the Trailer it was given
+		return &r
+	}
+	r.Trailer = resp.Trailer.Clone()
+	for _, values := range resp.Trailer {
+		if len(values) == 0 && r.Body != nil {
+			// a trailerBody under the body fills in resp.Trailer once read, see WithTrailer
+			r.Body = &trailerCopyBody{ReadCloser: r.Body, from: resp.Trailer, to: r.Trailer}
+			break
+		}
+	}
+	return &r
+}
+
+// trailerCopyBody copies the values of from to the trailers declared in to once the body has been
+// read up to io.EOF.
+type trailerCopyBody struct {
+	io.ReadCloser
+	from, to http.Header
+	once     sync.Once
+}
+
+func (b *trailerCopyBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(func() {
+			for key := range b.to {
+				if values := b.from[key]; len(values) > 0 {
+					b.to[key] = values
+				}
+			}
+		})
+	}
+	return n, err
+}
+
+func (b *trailerCopyBody) unwrapBody() io.ReadCloser { return b.ReadCloser }
+
+// wrappedBody is implemented by the bodies the transport wraps around response bodies, so that
+// what inspects a body can still get to the one the responder returned.
+type wrappedBody interface {
+	unwrapBody() io.ReadCloser
+}
+
+// responderBody returns the body the responder returned, from under the wrappers of the transport.
+func responderBody(body io.ReadCloser) io.ReadCloser {
+	for {
+		w, ok := body.(wrappedBody)
+		if !ok {
+			return body
+		}
+		body = w.unwrapBody()
+	}
+}
+
+// observe reports the outcome of a request to the logger and metrics, if any.
+func (m *MockTransport) observe(req *http.Request, key string, reqSize int, resp *http.Response, err error, elapsed time.Duration) {
+	m.mu.Lock()
+	logger, verbose, metrics := m.logger, m.logVerbose, m.metrics
+	m.mu.Unlock()
+
+	if logger != nil {
+		logExchange(logger, verbose, req, key, reqSize, resp, err, elapsed)
+	}
+	if metrics != nil {
+		status := 0
+		if err == nil && resp != nil {
+			status = resp.StatusCode
 		}
-		return r
+		metrics.ObserveRequest(key, status, err, elapsed)
 	}
-	return nil
 }
 
 // RegisterResponder adds a new responder, associated with a given HTTP method and URL.  When a
 // request comes in that matches, the responder will be called and the response returned to the client.
-func (m *MockTransport) RegisterResponder(method, url string, responder Responder) {
-	m.responders[method+" "+url] = responder
+//
+// Registering a responder for a method and URL that already has one stacks the new responder on
+// top: it answers until it expires (see WithTTL and WithMaxCalls), after which the one
+// underneath answers again.
+//
+// Requests whose URL only differs from url by its encoding, such as a punycode host or a path
+// escaped differently, are answered too, see WithRawPath, and so are requests whose URL only
+// differs from url by a query string.
+//
+// When several live registrations accept a request, the most specific one answers.  Registrations
+// are compared on, in this order:
+//   - their priority, see WithPriority;
+//   - how closely their URL matches the one of the request: exactly, then spelled differently,
+//     then without the query string, see URLMatch;
+//   - how many constraints they put on requests: each matcher counts as one, see WithMatcher, as
+//     does WithRawPath.
+//
+// Among equally specific registrations the most recent one answers, which makes a new
+// registration without matchers override the previous ones; a logger set with SetLogger is told
+// when registrations with matchers are only told apart this way.  ExplainMatch tells which
+// registration answers a request and why the others don't.
+//
+// The returned Registration can be ignored, or used to declare how many calls the responder
+// expects, see MockTransport.Verify.
+func (m *MockTransport) RegisterResponder(method, url string, responder Responder, opts ...RegisterOption) *Registration {
+	key := method + " " + url
+	r := newRegistration(key, responder, opts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.registered++
+	r.seq = m.registered
+	if _, ok := m.responders[key]; !ok {
+		m.indexKey(key)
+	}
+	m.responders[key] = append(m.responders[key], r)
+	return &Registration{m: m, r: r}
 }
 
 // RegisterNoResponder is used to register a responder that will be called if no other responder is
-// found.  The default is ConnectionFailure.
+// found, nor any default responder (see SetDefaultResponder).  The default is ConnectionFailure.
 func (m *MockTransport) RegisterNoResponder(responder Responder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.noResponder = responder
 }
 
-// Reset removes all registered responders (including the no responder) from the MockTransport
+// Registrations describes every registration on the MockTransport, including the ones that are
+// shadowed by a newer registration or have expired.  Registrations for the same method and URL
+// are listed from newest to oldest.
+func (m *MockTransport) Registrations() []RegistrationInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0, len(m.responders))
+	for key := range m.responders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	t := now()
+	infos := []RegistrationInfo{}
+	for _, key := range keys {
+		// the best live registration without constraints accepts every request, and shadows the
+		// registrations it outranks
+		stack := m.responders[key]
+		var shadow *candidate
+		for _, r := range stack {
+			if c := (candidate{r: r}); r.constraints() == 0 && r.live(t) && (shadow == nil || c.outranks(*shadow)) {
+				shadow = &c
+			}
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			r := stack[i]
+			info := RegistrationInfo{Key: key, Calls: r.calls}
+			switch {
+			case !r.live(t):
+				info.State = RegistrationExpired
+			case shadow != nil && shadow.outranks(candidate{r: r}):
+				info.State = RegistrationShadowed
+			default:
+				info.State = RegistrationActive
+			}
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// Reset removes all registered responders (including the no responder), simulated host and proxy
+// failures, closing and pass-through hosts, the call history and the unmatched requests from the
+// MockTransport, and forgets about the connections it opened and the calls to CloseIdleConnections
+// and CancelRequest.  Calls to WaitForCallCount in progress fail with ErrTransportReset.  The proxy
+// func set with SetProxy is kept.
 func (m *MockTransport) Reset() {
-	m.responders = make(map[string]Responder)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responders = make(map[string][]*registration)
+	m.equivalents = nil
 	m.noResponder = nil
+	m.defaults = nil
+	m.history = nil
+	m.resets++
+	m.notifyCalls()
+	m.unmatched = nil
+	m.hostFailures = nil
+	m.proxyChallenges = nil
+	m.passThrough = nil
+	m.profiles = nil
+	m.closingHosts = nil
+	m.connected = nil
+	m.connStats = ConnectionStats{}
+	m.closeIdleCalls = 0
+	m.cancelRequestCalls = 0
+	if m.bodyTracker != nil {
+		m.bodyTracker.reset()
+	}
 }
 
 // DefaultTransport is the default mock transport used by Activate, Deactivate, Reset,
@@ -177,7 +542,21 @@ func Reset() {
 
 // DeactivateAndReset is just a convenience method for calling Deactivate() and then Reset()
 // Happy deferring!
-func DeactivateAndReset() {
+//
+// When given a testing.TB and response bodies are tracked (see MockTransport.TrackResponseBodies),
+// it first reports the response bodies of the DefaultTransport that were never closed.
+func DeactivateAndReset(t ...testing.TB) {
+	m := DefaultTransport
+	m.mu.Lock()
+	tracker := m.bodyTracker
+	m.mu.Unlock()
+	if tracker != nil {
+		for _, tb := range t {
+			tb.Helper()
+			tracker.assertClosed(tb)
+		}
+	}
+
 	Deactivate()
 	Reset()
 }
@@ -195,8 +574,8 @@ func DeactivateAndReset() {
 //
 //			// requests to http://example.com/ will now return 'hello world'
 // 		}
-func RegisterResponder(method, url string, responder Responder) {
-	DefaultTransport.RegisterResponder(method, url, responder)
+func RegisterResponder(method, url string, responder Responder, opts ...RegisterOption) *Registration {
+	return DefaultTransport.RegisterResponder(method, url, responder, opts...)
 }
 
 // RegisterNoResponder adds a mock that will be called whenever a request for an unregistered URL
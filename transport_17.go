@@ -4,13 +4,13 @@ package httpmock
 
 import (
 	"net/http"
-	"errors"
 	"fmt"
 )
 
 func runCancelable(responder Responder, req *http.Request) (*http.Response, error) {
-	// Set up a goroutine that translates a close(req.Cancel) into a
-	// "request canceled" error, and another one that runs the
+	// Set up a goroutine that translates a close(req.Cancel) or a done
+	// context into the error a real transport would give, see
+	// canceledError, and another one that runs the
 	// responder. Then race them: first to the result channel wins.
 
 	type result struct {
@@ -25,12 +25,12 @@ func runCancelable(responder Responder, req *http.Request) (*http.Response, erro
 		case <-req.Cancel:
 			resultch <- result{
 				response: nil,
-				err:      errors.New("request canceled"),
+				err:      canceledError(req),
 			}
 		case <-req.Context().Done():
 			resultch <- result{
 				response: nil,
-				err: req.Context().Err(),
+				err:      canceledError(req),
 			}
 		case <-done:
 		}
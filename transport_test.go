@@ -1,9 +1,11 @@
 package httpmock
 
 import (
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -234,3 +236,101 @@ func TestMockTransportRespectsTimeout(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestMockTransportMalformedRequests(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""))
+
+	if _, err := tr.RoundTrip(nil); err == nil || err.Error() != "httpmock: nil request" {
+		t.Errorf("expected an error for a nil request, got %v", err)
+	}
+	if _, err := tr.RoundTrip(&http.Request{Method: "GET"}); err == nil || err.Error() != "httpmock: request has nil URL" {
+		t.Errorf("expected an error for a nil URL, got %v", err)
+	}
+
+	// an empty method means GET, and a nil Header an empty one
+	req, _ := http.NewRequest("GET", testUrl, nil)
+	req.Method = ""
+	req.Header = nil
+	resp, err := tr.RoundTrip(req)
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("expected an empty method to default to GET, got %v %v", resp, err)
+	}
+	if req.Method != "" {
+		t.Errorf("expected the request of the caller to be left alone, got method %q", req.Method)
+	}
+	if call := tr.LastCall(); call.Key != "GET "+testUrl || call.Request.Header == nil {
+		t.Errorf("expected the call recorded as a GET with headers, got %q %v", call.Key, call.Request.Header)
+	}
+}
+
+func TestMockTransportLeavesRequestAlone(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("POST", testUrl, NewStringResponder(204, ""), WithMatcher(BodyEmpty()))
+	tr.RegisterResponder("POST", testUrl, func(req *http.Request) (*http.Response, error) {
+		ioutil.ReadAll(req.Body)
+		req.Header.Set("X-Seen", "1")
+		return NewStringResponse(201, ""), nil
+	})
+
+	// whatever the transport, its matchers and its responders do, the request of the caller is
+	// unchanged
+	req, _ := http.NewRequest("POST", testUrl, strings.NewReader("payload"))
+	body, getBody := req.Body, req.GetBody
+	if explanation := tr.ExplainMatch(req); explanation.Selected() == nil {
+		t.Fatalf("expected a registration to be selected, got %s", explanation)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 201 || resp.Request != req {
+		t.Errorf("expected a response to the request of the caller, got %d for %p", resp.StatusCode, resp.Request)
+	}
+	if req.Body != body || req.Header.Get("X-Seen") != "" || req.ContentLength != 7 ||
+		reflect.ValueOf(req.GetBody).Pointer() != reflect.ValueOf(getBody).Pointer() {
+		t.Errorf("expected the request of the caller to be left alone, got %+v", req)
+	}
+	if data, _ := ioutil.ReadAll(tr.LastCall().Request.Body); string(data) != "payload" {
+		t.Errorf("expected the call history to keep the body, got %q", data)
+	}
+}
+
+func TestMockTransportNoBody(t *testing.T) {
+	tr := NewMockTransport()
+	var seen []interface{}
+	tr.RegisterResponder("POST", testUrl, func(req *http.Request) (*http.Response, error) {
+		seen = append(seen, req.Body)
+		return NewStringResponse(200, ""), nil
+	})
+
+	for _, body := range []io.ReadCloser{nil, http.NoBody} {
+		req, _ := http.NewRequest("POST", testUrl, nil)
+		req.Body = body
+		if _, err := tr.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if seen[0] != nil || seen[1] != nil {
+		t.Fatalf("expected http.NoBody to be treated as no body, got %v", seen)
+	}
+}
+
+func TestMockTransportNilResponse(t *testing.T) {
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, func(*http.Request) (*http.Response, error) {
+		return nil, nil
+	})
+
+	_, err := (&http.Client{Transport: tr}).Get(testUrl)
+	if err == nil || !strings.Contains(err.Error(), "httpmock: responder for GET "+testUrl+" returned no response and no error") {
+		t.Fatalf("expected an error explaining the nil response, got %v", err)
+	}
+
+	tr.SimulateHostError("www.example.com", func(*http.Request) (*http.Response, error) {
+		return nil, nil
+	})
+	if _, err := tr.RoundTrip(httptestRequest(t)); err == nil {
+		t.Fatal("expected a host failure without response to be an error too")
+	}
+}
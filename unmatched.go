@@ -0,0 +1,90 @@
+package httpmock
+
+import (
+	"fmt"
+	"io"
+	"net/http/httputil"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SetUnmatchedDump makes the MockTransport write every request no responder matched to w, in
+// httputil.DumpRequestOut format, each preceded by a marker line with its index and time.  Pass
+// nil to stop dumping.  Reset leaves the writer in place, and the indexes keep counting from where
+// they were.
+func (m *MockTransport) SetUnmatchedDump(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unmatchedDump = w
+}
+
+// SetUnmatchedDumpFile is like SetUnmatchedDump, but appends the dumps to the file at path,
+// creating it if needed.
+func (m *MockTransport) SetUnmatchedDumpFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	m.SetUnmatchedDump(appendFileWriter(path))
+	return nil
+}
+
+// appendFileWriter appends each write to a file, opening and closing it every time so that
+// nothing is left open when the transport is discarded.
+type appendFileWriter string
+
+func (a appendFileWriter) Write(p []byte) (int, error) {
+	f, err := os.OpenFile(string(a), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return f.Write(p)
+}
+
+// UnmatchedRequests returns the requests no responder matched since the MockTransport was created
+// or last reset.  Their bodies are buffered, so they can be read any number of times.
+func (m *MockTransport) UnmatchedRequests() []*Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Call(nil), m.unmatched...)
+}
+
+// recordUnmatched keeps track of a call that no responder matched, dumping it if a writer is set.
+// The dump is written after the transport is unlocked, so that a slow writer doesn't hold up other
+// requests and one calling back into the transport doesn't deadlock.
+func (m *MockTransport) recordUnmatched(call *Call) {
+	m.mu.Lock()
+	m.unmatched = append(m.unmatched, call)
+	w := m.unmatchedDump
+	if w == nil {
+		m.mu.Unlock()
+		return
+	}
+	// the index keeps growing across resets, since the writer survives them
+	m.unmatchedSeq++
+	seq := m.unmatchedSeq
+	m.mu.Unlock()
+
+	req := call.Request.Clone(call.Request.Context())
+	setRequestBody(req, call.Body)
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		dump = []byte(fmt.Sprintf("(could not dump request: %s)\n", err))
+	}
+
+	m.dumpMu.Lock()
+	defer m.dumpMu.Unlock()
+	fmt.Fprintf(w, "=== httpmock unmatched request #%d at %s ===\n%s\n",
+		seq, call.Time.Format(time.RFC3339Nano), dump)
+}
+
+// ResponderSnippet returns Go source registering a responder for the call, ready to be pasted
+// into a test and filled in.
+func (c *Call) ResponderSnippet() string {
+	return fmt.Sprintf("httpmock.RegisterResponder(%s, %s,\n\thttpmock.NewStringResponder(200, \"\"))",
+		strconv.Quote(c.Request.Method), strconv.Quote(c.Request.URL.String()))
+}
@@ -0,0 +1,120 @@
+package httpmock
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMockTransportUnmatchedDump(t *testing.T) {
+	tr := NewMockTransport()
+	client := &http.Client{Transport: tr}
+
+	var dump bytes.Buffer
+	tr.SetUnmatchedDump(&dump)
+	tr.RegisterResponder("GET", testUrl, NewStringResponder(200, ""))
+
+	client.Get(testUrl)
+	client.Post(testUrl+"articles", "application/json", strings.NewReader(`{"id":1}`))
+	client.Get(testUrl + "missing")
+
+	out := dump.String()
+	if strings.Count(out, "=== httpmock unmatched request #") != 2 {
+		t.Fatalf("expected two dumped requests, got:\n%s", out)
+	}
+	if !strings.Contains(out, "#1 at ") || !strings.Contains(out, "#2 at ") {
+		t.Fatalf("expected increasing indexes, got:\n%s", out)
+	}
+	if !strings.Contains(out, "POST /articles HTTP/1.1") || !strings.Contains(out, `{"id":1}`) {
+		t.Fatalf("expected the POST to be dumped with its body, got:\n%s", out)
+	}
+
+	unmatched := tr.UnmatchedRequests()
+	if len(unmatched) != 2 {
+		t.Fatalf("expected 2 unmatched requests, got %d", len(unmatched))
+	}
+	body, err := ioutil.ReadAll(unmatched[0].Request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"id":1}` {
+		t.Fatalf("expected the body to be buffered, got %q", body)
+	}
+
+	want := "httpmock.RegisterResponder(\"POST\", \"http://www.example.com/articles\",\n\thttpmock.NewStringResponder(200, \"\"))"
+	if got := unmatched[0].ResponderSnippet(); got != want {
+		t.Fatalf("expected snippet:\n%s\ngot:\n%s", want, got)
+	}
+
+	// reset clears the collection but keeps dumping
+	tr.Reset()
+	if len(tr.UnmatchedRequests()) != 0 {
+		t.Fatal("expected Reset to clear the unmatched requests")
+	}
+	dump.Reset()
+	client.Get(testUrl)
+	if !strings.Contains(dump.String(), "GET / HTTP/1.1") {
+		t.Fatalf("expected dumping to continue after Reset, got:\n%s", dump.String())
+	}
+	if !strings.Contains(dump.String(), "#3 at ") {
+		t.Fatalf("expected the index to keep growing after Reset, got:\n%s", dump.String())
+	}
+}
+
+// historyWriter calls back into the transport on every write.
+type historyWriter struct {
+	tr    *MockTransport
+	calls int
+}
+
+func (w *historyWriter) Write(p []byte) (int, error) {
+	w.calls = len(w.tr.History())
+	return len(p), nil
+}
+
+func TestMockTransportUnmatchedDumpReentrant(t *testing.T) {
+	tr := NewMockTransport()
+	w := &historyWriter{tr: tr}
+	tr.SetUnmatchedDump(w)
+	client := &http.Client{Transport: tr}
+
+	done := make(chan struct{})
+	go func() {
+		client.Get(testUrl)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a writer calling back into the transport not to deadlock")
+	}
+	if w.calls != 1 {
+		t.Fatalf("expected the writer to see the call in the history, got %d", w.calls)
+	}
+}
+
+func TestMockTransportUnmatchedDumpFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unmatched.txt")
+
+	tr := NewMockTransport()
+	if err := tr.SetUnmatchedDumpFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: tr}
+	client.Get(testUrl)
+	client.Get(testUrl)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "GET / HTTP/1.1") != 2 {
+		t.Fatalf("expected both requests in the dump file, got:\n%s", data)
+	}
+}
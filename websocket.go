@@ -0,0 +1,91 @@
+package httpmock
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// websocketGUID is the key suffix RFC 6455 uses to compute Sec-WebSocket-Accept.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// NewWebsocketUpgradeResponder creates a Responder accepting WebSocket handshakes.  A valid
+// handshake (a GET with "Upgrade: websocket", a Connection header listing "Upgrade", version 13 and
+// a Sec-WebSocket-Key of 16 base64 encoded bytes) gets a 101 Switching Protocols with the matching
+// Sec-WebSocket-Accept.  Its body is one end of a net.Pipe, which can be written to as well as
+// read from, as the body of a 101 from http.Transport can, and handler is run in its own goroutine
+// with the other end to exchange raw bytes; frame encoding is left to the test.  Any other request
+// gets a 400 explaining what is wrong with the handshake.
+//
+// Closing the response body closes the pipe, so a handler returning on the first read or write
+// error ends with the client's connection.  The handler's end is closed when it returns.
+func NewWebsocketUpgradeResponder(handler func(conn net.Conn)) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		key, problem := websocketKey(req)
+		if problem != "" {
+			return NewStringResponse(http.StatusBadRequest, "websocket handshake failed: "+problem), nil
+		}
+
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			handler(server)
+		}()
+
+		resp := &http.Response{
+			Status:        statusLine(http.StatusSwitchingProtocols),
+			StatusCode:    http.StatusSwitchingProtocols,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{},
+			Body:          client,
+			ContentLength: -1,
+		}
+		resp.Header.Set("Upgrade", "websocket")
+		resp.Header.Set("Connection", "Upgrade")
+		resp.Header.Set("Sec-WebSocket-Accept", websocketAccept(key))
+		return resp, nil
+	}
+}
+
+// websocketKey returns the Sec-WebSocket-Key of a handshake request, or what is wrong with it.
+func websocketKey(req *http.Request) (string, string) {
+	if req.Method != http.MethodGet {
+		return "", "method must be GET"
+	}
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		return "", `missing "Upgrade: websocket" header`
+	}
+	if !headerHasToken(req.Header, "Connection", "upgrade") {
+		return "", `missing "Connection: Upgrade" header`
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		return "", "unsupported Sec-WebSocket-Version"
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return "", "invalid Sec-WebSocket-Key"
+	}
+	return key, ""
+}
+
+// headerHasToken reports whether one of the comma separated values of the header holds token.
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// websocketAccept computes the Sec-WebSocket-Accept answering key.
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,90 @@
+package httpmock
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWebsocketUpgradeResponder(t *testing.T) {
+	done := make(chan struct{})
+	echo := func(conn net.Conn) {
+		defer close(done)
+		io.Copy(conn, conn)
+	}
+
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", "http://ws.example.com/chat", NewWebsocketUpgradeResponder(echo))
+	client := &http.Client{Transport: tr}
+
+	req, err := http.NewRequest("GET", "http://ws.example.com/chat", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	// the sample key of RFC 6455
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected a 101, got %d", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected Sec-WebSocket-Accept %q", accept)
+	}
+
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatalf("expected a writable body, got %T", resp.Body)
+	}
+	go conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("expected the handler to echo, got %q %v", buf, err)
+	}
+
+	conn.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the handler to end once the connection closed")
+	}
+}
+
+func TestWebsocketUpgradeResponderRejects(t *testing.T) {
+	responder := NewWebsocketUpgradeResponder(func(net.Conn) {
+		t.Error("expected the handler not to run")
+	})
+
+	tests := map[string]http.Header{
+		"not an upgrade": {},
+		"bad key": {
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-Websocket-Version": {"13"},
+			"Sec-Websocket-Key":     {"short"},
+		},
+	}
+	for name, header := range tests {
+		req, err := http.NewRequest("GET", testUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = header
+
+		resp, err := responder(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected a 400, got %d", name, resp.StatusCode)
+		}
+	}
+}
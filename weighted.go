@@ -0,0 +1,104 @@
+package httpmock
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+)
+
+// WeightedChoice is a responder of a WeightedResponder, picked with a probability proportional to
+// its weight.
+type WeightedChoice struct {
+	Responder Responder
+	Weight    float64
+}
+
+// WeightedResponder answers each request with one of its choices, picked at random according to
+// their weights, to emulate a backend whose calls mostly succeed.  It is created with
+// NewWeightedResponder and registered through its Respond method:
+//
+//	weighted, err := httpmock.NewWeightedResponder([]httpmock.WeightedChoice{
+//		{Responder: httpmock.NewStringResponder(200, "ok"), Weight: 90},
+//		{Responder: httpmock.NewStringResponder(429, ""), Weight: 7},
+//		{Responder: httpmock.NewHangingResponder(), Weight: 3},
+//	})
+//	httpmock.RegisterResponder("GET", "https://api.mybiz.com/status", weighted.Respond)
+//
+// A choice can be any Responder, such as the Respond method of a SequenceResponder, which then
+// moves on each time it is picked.
+type WeightedResponder struct {
+	choices []WeightedChoice
+	total   float64
+	rand    *rand.Rand
+
+	mu     sync.Mutex
+	counts []int
+}
+
+// WeightedOption configures a WeightedResponder.
+type WeightedOption func(*WeightedResponder)
+
+// WeightedSeed makes a WeightedResponder draw its picks from its own source of randomness, seeded
+// with seed, rather than from the package one, see SetRandSource.
+func WeightedSeed(seed int64) WeightedOption {
+	return func(w *WeightedResponder) {
+		w.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// NewWeightedResponder creates a WeightedResponder picking among choices.  It fails if there is no
+// choice, or if one of them has no responder or a weight that isn't positive and finite.
+func NewWeightedResponder(choices []WeightedChoice, opts ...WeightedOption) (*WeightedResponder, error) {
+	if len(choices) == 0 {
+		return nil, errors.New("httpmock: no weighted choice")
+	}
+	w := &WeightedResponder{
+		choices: append([]WeightedChoice(nil), choices...),
+		counts:  make([]int, len(choices)),
+	}
+	for i, choice := range choices {
+		if choice.Responder == nil {
+			return nil, fmt.Errorf("httpmock: weighted choice %d has no responder", i)
+		}
+		if !(choice.Weight > 0) || math.IsInf(choice.Weight, 1) {
+			return nil, fmt.Errorf("httpmock: weighted choice %d has weight %v, expected a positive finite one", i, choice.Weight)
+		}
+		w.total += choice.Weight
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w, nil
+}
+
+// Respond is the Responder of the WeightedResponder.
+func (w *WeightedResponder) Respond(req *http.Request) (*http.Response, error) {
+	w.mu.Lock()
+	var draw float64
+	if w.rand != nil {
+		draw = w.rand.Float64() * w.total
+	} else {
+		draw = randFloat64(req) * w.total
+	}
+	i := 0
+	for ; i < len(w.choices)-1; i++ {
+		if draw < w.choices[i].Weight {
+			break
+		}
+		draw -= w.choices[i].Weight
+	}
+	w.counts[i]++
+	w.mu.Unlock()
+
+	return w.choices[i].Responder(req)
+}
+
+// Counts returns how many times each choice was picked, in the order they were given.
+func (w *WeightedResponder) Counts() []int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]int(nil), w.counts...)
+}
@@ -0,0 +1,121 @@
+package httpmock
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestWeightedResponderCounts(t *testing.T) {
+	weighted, err := NewWeightedResponder([]WeightedChoice{
+		{Responder: NewStringResponder(200, "ok"), Weight: 90},
+		{Responder: NewStringResponder(429, ""), Weight: 7},
+		{Responder: NewConnRefusedResponder(), Weight: 3},
+	}, WeightedSeed(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := NewMockTransport()
+	tr.RegisterResponder("GET", testUrl, weighted.Respond)
+	client := &http.Client{Transport: tr}
+
+	outcomes := map[int]int{}
+	for i := 0; i < 1000; i++ {
+		resp, err := client.Get(testUrl)
+		if err != nil {
+			outcomes[0]++
+			continue
+		}
+		resp.Body.Close()
+		outcomes[resp.StatusCode]++
+	}
+
+	// with a fixed seed, the picks are the same on every run
+	if counts := weighted.Counts(); !reflect.DeepEqual(counts, []int{889, 76, 35}) {
+		t.Errorf("expected counts [889 76 35], got %v", counts)
+	}
+	if !reflect.DeepEqual(outcomes, map[int]int{200: 889, 429: 76, 0: 35}) {
+		t.Errorf("expected the outcomes to follow the counts, got %v", outcomes)
+	}
+}
+
+func TestWeightedResponderPackageSource(t *testing.T) {
+	defer SetRandSource(nil)
+	req, _ := http.NewRequest("GET", testUrl, nil)
+
+	counts := func() []int {
+		SetRandSource(rand.NewSource(7))
+		weighted, err := NewWeightedResponder([]WeightedChoice{
+			{Responder: NewStringResponder(200, ""), Weight: 1},
+			{Responder: NewStringResponder(500, ""), Weight: 1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 100; i++ {
+			weighted.Respond(req)
+		}
+		return weighted.Counts()
+	}
+	if first, second := counts(), counts(); !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same counts from the same package seed, got %v and %v", first, second)
+	}
+}
+
+func TestWeightedResponderSequence(t *testing.T) {
+	seq := NewSequenceResponder([]Responder{
+		NewStringResponder(200, "first"),
+		NewStringResponder(200, "second"),
+	}, ErrorWhenExhausted())
+	weighted, err := NewWeightedResponder([]WeightedChoice{
+		{Responder: seq.Respond, Weight: 1},
+		{Responder: NewStringResponder(503, ""), Weight: 1},
+	}, WeightedSeed(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", testUrl, nil)
+
+	// the sequence moves on each time it is picked, and only then
+	var bodies []string
+	for i := 0; i < 10; i++ {
+		resp, err := weighted.Respond(req)
+		if err == ErrSequenceExhausted {
+			bodies = append(bodies, "exhausted")
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode == 200 {
+			data, _ := ioutil.ReadAll(resp.Body)
+			bodies = append(bodies, string(data))
+		}
+	}
+	if counts := weighted.Counts(); !reflect.DeepEqual(counts, []int{6, 4}) || seq.Calls() != 6 {
+		t.Fatalf("expected the sequence called each of the 6 times it was picked, got %d calls for %v", seq.Calls(), counts)
+	}
+	expected := []string{"first", "second", "exhausted", "exhausted", "exhausted", "exhausted"}
+	if !reflect.DeepEqual(bodies, expected) {
+		t.Errorf("expected %q, got %q", expected, bodies)
+	}
+}
+
+func TestWeightedResponderRejectsBadChoices(t *testing.T) {
+	ok := NewStringResponder(200, "")
+	for name, choices := range map[string][]WeightedChoice{
+		"no choice":       nil,
+		"zero weight":     {{Responder: ok, Weight: 1}, {Responder: ok, Weight: 0}},
+		"negative weight": {{Responder: ok, Weight: -1}},
+		"NaN weight":      {{Responder: ok, Weight: math.NaN()}},
+		"infinite weight": {{Responder: ok, Weight: 1}, {Responder: ok, Weight: math.Inf(1)}},
+		"no responder":    {{Weight: 1}},
+	} {
+		if _, err := NewWeightedResponder(choices); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}